@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/steipete/camsnap/internal/camerr"
 	"github.com/steipete/camsnap/internal/cli"
 )
 
@@ -14,6 +15,6 @@ func main() {
 	root := cli.NewRootCommand(version)
 	if err := root.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		os.Exit(camerr.ExitCode(err))
 	}
 }
@@ -9,6 +9,8 @@ require (
 	github.com/muesli/termenv v0.16.0
 	github.com/pion/rtp v1.8.25
 	github.com/spf13/cobra v1.10.1
+	golang.org/x/net v0.47.0
+	golang.org/x/sys v0.38.0
 	gopkg.in/yaml.v3 v3.0.1
 )
 
@@ -25,6 +27,4 @@ require (
 	github.com/pion/transport/v3 v3.1.1 // indirect
 	github.com/rivo/uniseg v0.4.7 // indirect
 	github.com/spf13/pflag v1.0.10 // indirect
-	golang.org/x/net v0.47.0 // indirect
-	golang.org/x/sys v0.38.0 // indirect
 )
@@ -0,0 +1,140 @@
+// Package camerr defines the kinds of camera failures camsnap reports and the exit code for each.
+// Backends (ffmpeg, gortsplib, HTTP) tag their errors with a kind; callers test with errors.Is.
+package camerr
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"syscall"
+)
+
+// Error kinds.
+var (
+	ErrAuth             = errors.New("authentication failed")
+	ErrUnreachable      = errors.New("camera unreachable")
+	ErrTimeout          = errors.New("timed out")
+	ErrNotFound         = errors.New("stream not found")
+	ErrUnsupportedCodec = errors.New("unsupported codec")
+	ErrSessionLimit     = errors.New("camera session limit reached")
+)
+
+// Exit codes; 1 is any other failure, 2 is left for usage errors.
+const (
+	ExitFailure          = 1
+	ExitAuth             = 3
+	ExitUnreachable      = 4
+	ExitTimeout          = 5
+	ExitNotFound         = 6
+	ExitUnsupportedCodec = 7
+	ExitSessionLimit     = 8
+)
+
+var kinds = []struct {
+	err   error
+	class string
+	exit  int
+}{
+	{ErrAuth, "auth", ExitAuth},
+	{ErrSessionLimit, "session-limit", ExitSessionLimit},
+	{ErrNotFound, "not-found", ExitNotFound},
+	{ErrUnsupportedCodec, "unsupported-codec", ExitUnsupportedCodec},
+	{ErrUnreachable, "unreachable", ExitUnreachable},
+	{ErrTimeout, "timeout", ExitTimeout},
+}
+
+// kindError tags err with a kind without changing its message.
+type kindError struct {
+	kind error
+	err  error
+}
+
+func (e *kindError) Error() string   { return e.err.Error() }
+func (e *kindError) Unwrap() []error { return []error{e.err, e.kind} }
+
+// Wrap tags err with kind. A nil kind returns err unchanged, as does an err that already has a kind.
+func Wrap(kind, err error) error {
+	if err == nil || kind == nil || Kind(err) != nil {
+		return err
+	}
+	return &kindError{kind: kind, err: err}
+}
+
+// Kind returns the kind err was tagged with, or nil.
+func Kind(err error) error {
+	for _, k := range kinds {
+		if errors.Is(err, k.err) {
+			return k.err
+		}
+	}
+	return nil
+}
+
+// Class names the kind of err for logs and JSON: auth, unreachable, timeout, not-found,
+// unsupported-codec, session-limit, or unknown.
+func Class(err error) string {
+	for _, k := range kinds {
+		if errors.Is(err, k.err) {
+			return k.class
+		}
+	}
+	return "unknown"
+}
+
+// ExitCode maps err to the process exit code.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	for _, k := range kinds {
+		if errors.Is(err, k.err) {
+			return k.exit
+		}
+	}
+	return ExitFailure
+}
+
+// FromStatus maps an RTSP or HTTP status code to a kind, or nil.
+func FromStatus(code int) error {
+	switch code {
+	case 401, 403:
+		return ErrAuth
+	case 404:
+		return ErrNotFound
+	case 453, 503: // Not Enough Bandwidth / Service Unavailable: cameras out of stream slots
+		return ErrSessionLimit
+	case 415: // Unsupported Media Type
+		return ErrUnsupportedCodec
+	}
+	return nil
+}
+
+// FromNet maps dial and I/O errors to ErrTimeout or ErrUnreachable, or nil.
+func FromNet(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, os.ErrDeadlineExceeded):
+		return ErrTimeout
+	case errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.EHOSTUNREACH),
+		errors.Is(err, syscall.ENETUNREACH), errors.Is(err, syscall.ECONNRESET):
+		return ErrUnreachable
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		if dnsErr.IsTimeout {
+			return ErrTimeout
+		}
+		return ErrUnreachable
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ErrTimeout
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return ErrUnreachable
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package camerr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"testing"
+)
+
+func TestWrapKeepsMessageAndKind(t *testing.T) {
+	base := errors.New("describe: bad status code: 401 (Unauthorized)")
+	err := fmt.Errorf("snap kitchen: %w", Wrap(ErrAuth, base))
+	if err.Error() != "snap kitchen: describe: bad status code: 401 (Unauthorized)" {
+		t.Fatalf("message changed: %q", err)
+	}
+	if !errors.Is(err, ErrAuth) || !errors.Is(err, base) {
+		t.Fatal("wrapped error lost its kind or cause")
+	}
+	if Class(err) != "auth" || ExitCode(err) != ExitAuth {
+		t.Fatalf("class %s exit %d", Class(err), ExitCode(err))
+	}
+	// the first kind sticks
+	if again := Wrap(ErrTimeout, err); Class(again) != "auth" {
+		t.Fatalf("rewrapped as %s", Class(again))
+	}
+	if Wrap(nil, base) != base || Wrap(ErrAuth, nil) != nil {
+		t.Fatal("nil kind or error should pass through")
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	cases := []struct {
+		err  error
+		want int
+	}{
+		{nil, 0},
+		{errors.New("boom"), ExitFailure},
+		{ErrAuth, 3},
+		{ErrUnreachable, 4},
+		{ErrTimeout, 5},
+		{ErrNotFound, 6},
+		{ErrUnsupportedCodec, 7},
+		{ErrSessionLimit, 8},
+	}
+	for _, c := range cases {
+		if got := ExitCode(c.err); got != c.want {
+			t.Fatalf("ExitCode(%v) = %d want %d", c.err, got, c.want)
+		}
+	}
+}
+
+func TestFromStatus(t *testing.T) {
+	cases := map[int]error{401: ErrAuth, 403: ErrAuth, 404: ErrNotFound, 453: ErrSessionLimit, 503: ErrSessionLimit, 415: ErrUnsupportedCodec, 461: nil, 500: nil}
+	for code, want := range cases {
+		if got := FromStatus(code); got != want {
+			t.Fatalf("FromStatus(%d) = %v want %v", code, got, want)
+		}
+	}
+}
+
+func TestFromNet(t *testing.T) {
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+	if got := FromNet(refused); got != ErrUnreachable {
+		t.Fatalf("refused: %v", got)
+	}
+	if got := FromNet(&net.DNSError{Name: "cam.invalid", IsNotFound: true}); got != ErrUnreachable {
+		t.Fatalf("dns: %v", got)
+	}
+	if got := FromNet(fmt.Errorf("read: %w", context.DeadlineExceeded)); got != ErrTimeout {
+		t.Fatalf("deadline: %v", got)
+	}
+	if got := FromNet(errors.New("EOF")); got != nil {
+		t.Fatalf("plain error: %v", got)
+	}
+}
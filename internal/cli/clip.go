@@ -3,12 +3,11 @@ package cli
 import (
 	"context"
 	"fmt"
-	"os"
 	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/steipete/camsnap/internal/exec"
-	"github.com/steipete/camsnap/internal/rtsp"
+	"github.com/steipete/camsnap/internal/httpcam"
 )
 
 func newClipCmd() *cobra.Command {
@@ -22,6 +21,9 @@ func newClipCmd() *cobra.Command {
 	var noAudio bool
 	var audioCodec string
 	var path string
+	var ioTimeout time.Duration
+	var progressMode string
+	var container string
 
 	cmd := &cobra.Command{
 		Use:   "clip",
@@ -36,20 +38,32 @@ func newClipCmd() *cobra.Command {
 			if duration <= 0 {
 				return fmt.Errorf("--dur must be > 0")
 			}
-			if !exec.HasBinary("ffmpeg") {
+			if !media.Available("ffmpeg") {
 				return fmt.Errorf("ffmpeg not found in PATH")
 			}
+			toStdout := outPath == "-"
+			muxArgs, err := containerArgs(container, toStdout)
+			if err != nil {
+				return err
+			}
 			if outPath == "" {
-				tmp, err := os.CreateTemp("", "camsnap-*.mp4")
-				if err != nil {
-					return fmt.Errorf("create temp file: %w", err)
+				ext := ".mp4"
+				if container == "ts" {
+					ext = ".ts"
 				}
-				if err := tmp.Close(); err != nil {
-					return fmt.Errorf("close temp file: %w", err)
+				if outPath, err = tempOutput("camsnap-*" + ext); err != nil {
+					return err
 				}
-				outPath = tmp.Name()
 				cmd.Printf("No --out provided, writing clip to %s\n", outPath)
 			}
+			label := outPath
+			if toStdout {
+				label = "stdout"
+			}
+			progress, err := newProgressPrinter(cmd.ErrOrStderr(), progressMode, cameraName, label, duration)
+			if err != nil {
+				return err
+			}
 
 			cfgFlag, err := configPathFlag(cmd)
 			if err != nil {
@@ -64,6 +78,9 @@ func newClipCmd() *cobra.Command {
 				return fmt.Errorf("camera %q not found", cameraName)
 			}
 
+			if httpcam.IsHTTPSource(cam) {
+				return fmt.Errorf("clip needs an RTSP source; camera %q is an HTTP %s source", cam.Name, cam.Source)
+			}
 			if stream != "" && path != "" {
 				return fmt.Errorf("use --path for custom RTSP token URLs; omit --stream")
 			}
@@ -97,7 +114,8 @@ func newClipCmd() *cobra.Command {
 			if !ok {
 				return fmt.Errorf("invalid --rtsp-transport (use tcp|udp)")
 			}
-			url, err := rtsp.BuildURL(cam)
+			cam.Stream = stream
+			url, err := streamURL(cam)
 			if err != nil {
 				return err
 			}
@@ -105,18 +123,25 @@ func newClipCmd() *cobra.Command {
 			ctx, cancel := exec.WithTimeout(context.Background(), timeout)
 			defer cancel()
 
-			if path != "" {
-				url = appendPath(url, path)
-			} else {
-				url = appendStream(url, stream)
+			caps := media.Caps(ctx)
+			if err := caps.CheckRTSPTransport(xport); err != nil {
+				return err
+			}
+			if !noAudio && audioCodec != "" && audioCodec != "copy" && !caps.HasEncoder(audioCodec) {
+				return fmt.Errorf("this ffmpeg build has no %s encoder; pick another --audio-codec or pass --no-audio", audioCodec)
+			}
+			if !noAudio && audioCodec == "" && !caps.HasEncoder("aac") {
+				msgs := messages(cmd, toStdout)
+				_, _ = fmt.Fprintln(msgs, newStyler(msgs).Warn("ffmpeg has no aac encoder; recording without audio (pick one with --audio-codec)"))
+				noAudio = true
 			}
 
-			ffArgs := []string{
-				"-y",
-				"-rtsp_transport", xport,
+			ffArgs := []string{"-y", "-rtsp_transport", xport}
+			ffArgs = append(ffArgs, caps.RTSPTimeoutArgs(ioTimeout)...)
+			ffArgs = append(ffArgs,
 				"-i", url,
 				"-t", fmt.Sprintf("%.0f", duration.Seconds()),
-			}
+			)
 			// Video: copy
 			ffArgs = append(ffArgs, "-c:v", "copy")
 			if noAudio {
@@ -129,21 +154,59 @@ func newClipCmd() *cobra.Command {
 					ffArgs = append(ffArgs, "-c:a", audioCodec)
 				}
 			}
-			ffArgs = append(ffArgs, outPath)
-			return exec.RunFFmpeg(ctx, ffArgs...)
+			ffArgs = append(ffArgs, muxArgs...)
+			opts := exec.RTSPOptions{OnProgress: progress.update}
+			if toStdout {
+				ffArgs = append(ffArgs, "pipe:1")
+				opts.Stdout = cmd.OutOrStdout()
+			} else {
+				ffArgs = append(ffArgs, outPath)
+			}
+			if err := exec.RunRTSP(ctx, media, caps, opts, ffArgs...); err != nil {
+				progress.abort()
+				return err
+			}
+			progress.finish(messages(cmd, toStdout))
+			return nil
 		},
 	}
 
 	cmd.Flags().StringVar(&cameraName, "camera", "", "Camera name to use")
-	cmd.Flags().StringVar(&outPath, "out", "", "Output file (e.g., clip.mp4)")
+	cmd.Flags().StringVar(&outPath, "out", "", "Output file (e.g., clip.mp4), or - to stream to stdout")
+	cmd.Flags().StringVar(&container, "container", "", "Container: mp4|fmp4|ts (default: from the --out extension; fmp4 for --out -)")
 	cmd.Flags().DurationVar(&duration, "dur", 10*time.Second, "Clip duration (e.g., 10s)")
 	cmd.Flags().DurationVar(&timeout, "timeout", 20*time.Second, "Timeout for ffmpeg invocation")
+	cmd.Flags().DurationVar(&ioTimeout, "rtsp-timeout", defaultRTSPTimeout, "Give up when the camera sends nothing for this long, in the handshake or the stream (0 = only --timeout)")
 	cmd.Flags().StringVar(&authMode, "rtsp-auth", "auto", "RTSP auth mode: auto|basic|digest")
-	cmd.Flags().StringVar(&transport, "rtsp-transport", "tcp", "RTSP transport: tcp|udp")
-	cmd.Flags().StringVar(&stream, "stream", "", "RTSP path segment (stream1 or stream2); ignored if --path is set")
+	cmd.Flags().StringVar(&transport, "rtsp-transport", "", "RTSP transport: tcp|udp (default: the camera's, else tcp)")
+	cmd.Flags().StringVar(&stream, "stream", "", "RTSP path segment (stream1 or stream2; main|sub for vendor presets); ignored if --path is set")
 	cmd.Flags().StringVar(&path, "path", "", "Custom RTSP path (overrides --stream), e.g., /Bfy... from UniFi Protect")
 	cmd.Flags().BoolVar(&noAudio, "no-audio", false, "Drop audio track")
+	cmd.Flags().StringVar(&progressMode, "progress", "auto", "Progress while recording on stderr: auto (live line on a terminal, else json)|line|json|none")
 	cmd.Flags().StringVar(&audioCodec, "audio-codec", "", "Audio codec (default aac); ignored if --no-audio")
 
 	return cmd
 }
+
+// containerArgs are the ffmpeg muxer options for --container; stdout cannot take a plain mp4,
+// whose index is written at the end by seeking back.
+func containerArgs(container string, toStdout bool) ([]string, error) {
+	if container == "" {
+		if !toStdout {
+			return nil, nil
+		}
+		container = "fmp4"
+	}
+	switch container {
+	case "mp4":
+		if toStdout {
+			return nil, fmt.Errorf("a plain mp4 cannot be streamed; use --container fmp4 or ts with --out -")
+		}
+		return []string{"-f", "mp4"}, nil
+	case "fmp4":
+		return []string{"-f", "mp4", "-movflags", "frag_keyframe+empty_moov+default_base_moof"}, nil
+	case "ts":
+		return []string{"-f", "mpegts"}, nil
+	}
+	return nil, fmt.Errorf("invalid --container (use mp4|fmp4|ts)")
+}
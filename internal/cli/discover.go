@@ -2,71 +2,462 @@ package cli
 
 import (
 	"context"
+	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/steipete/camsnap/internal/config"
 	"github.com/steipete/camsnap/internal/discovery"
+	"github.com/steipete/camsnap/internal/exec"
+	"github.com/steipete/camsnap/internal/hostport"
+	"github.com/steipete/camsnap/internal/presets"
 )
 
 func newDiscoverCmd() *cobra.Command {
 	var timeout time.Duration
 	var includeInfo bool
+	var addAll bool
+	var user string
+	var pass string
+	var profile string
+	var listen bool
+	var update bool
+	var duration time.Duration
+	var ifaces []string
+	var ttl int
+	var scanCIDR string
+	var scanTimeout time.Duration
+	var output string
 	cmd := &cobra.Command{
 		Use:   "discover",
 		Short: "Discover cameras on the local network via ONVIF WS-Discovery",
 		RunE: func(cmd *cobra.Command, _ []string) error {
+			format, err := parseOutput(output)
+			if err != nil {
+				return err
+			}
+			table := format == outputTable
 			sty := newStyler(cmd.OutOrStdout())
+			// in json/yaml mode stdout carries only the records
+			warn := cmd.OutOrStdout()
+			if !table {
+				warn = cmd.ErrOrStderr()
+			}
+
+			cfg, cfgPath, cfgErr := loadConfigFromFlag(cmd)
+			if (addAll || update) && cfgErr != nil {
+				return cfgErr
+			}
+			if listen {
+				if !table {
+					return fmt.Errorf("--output %s is not supported with --listen", format)
+				}
+				return listenAnnouncements(cmd, sty, cfg, cfgPath, discovery.Options{Interfaces: ifaces}, duration, update)
+			}
+
+			// the port scan runs alongside the probe window; a /24 takes longer than --timeout
+			// at the scan's rate limit, so it has a deadline of its own
+			type scanOutcome struct {
+				results []discovery.ScanResult
+				err     error
+				partial bool
+			}
+			scanned := make(chan scanOutcome, 1)
+			if scanCIDR != "" {
+				go func() {
+					ctx, cancel := exec.WithTimeout(context.Background(), scanTimeout)
+					defer cancel()
+					results, err := discovery.Scan(ctx, scanCIDR, discovery.ScanOptions{})
+					scanned <- scanOutcome{results, err, ctx.Err() != nil}
+				}()
+			}
+
 			ctx, cancel := context.WithTimeout(context.Background(), timeout)
 			defer cancel()
 
-			cfg, _, _ := loadConfigFromFlag(cmd)
-
-			devs, err := discovery.Discover(ctx, timeout)
+			devs, err := discovery.DiscoverWith(ctx, timeout, discovery.Options{Interfaces: ifaces, TTL: ttl})
 			if err != nil {
-				return err
+				if scanCIDR == "" {
+					return err
+				}
+				_, _ = fmt.Fprintf(warn, "%s ONVIF probe failed: %v\n", sty.Warn("!"), err)
+			}
+			var rtspHosts map[string][]discovery.ScanResult
+			var scanResults []discovery.ScanResult
+			if scanCIDR != "" {
+				out := <-scanned
+				if out.err != nil {
+					return out.err
+				}
+				if out.partial {
+					_, _ = fmt.Fprintf(warn, "%s scan stopped after --scan-timeout %s; results are partial\n", sty.Warn("!"), scanTimeout)
+				}
+				scanResults = out.results
+				rtspHosts = map[string][]discovery.ScanResult{}
+				for _, r := range scanResults {
+					rtspHosts[r.Host] = append(rtspHosts[r.Host], r)
+				}
 			}
-			if len(devs) == 0 {
+			records := []discoveredRecord{}
+			if len(devs) == 0 && len(scanResults) == 0 {
+				if !table {
+					return writeStructured(cmd.OutOrStdout(), format, records)
+				}
 				cmd.Println(sty.Warn("No devices found. Ensure cameras and this host are on the same LAN."))
 				return nil
 			}
+			added := 0
 			for _, d := range devs {
+				rec := discoveredRecord{Host: d.Host, XAddr: d.Address, Endpoint: d.Endpoint, Interface: d.Interface}
+				rtspStr := ""
+				ip, _ := hostport.Split(d.Host)
+				if found := rtspHosts[ip]; len(found) > 0 {
+					rtspStr = " " + rtspSummary(found)
+					rec.RTSP = rtspPortRecords(found)
+					delete(rtspHosts, ip)
+				}
+				// the discovery context is spent once the probe window closes
+				devCtx, devCancel := context.WithTimeout(context.Background(), 15*time.Second)
 				infoStr := ""
-				if includeInfo {
-					info := fetchInfo(ctx, cfg, d)
-					if info != "" {
-						infoStr = " [" + info + "]"
+				vendorFlag := ""
+				vendor := ""
+				if includeInfo || addAll {
+					var info discovery.DeviceInfo
+					info, vendor = fetchInfo(devCtx, cfg, d, user, pass)
+					if s := infoSummary(info); s != "" {
+						infoStr = " [" + s + "]"
+					}
+					if vendor != "" {
+						vendorFlag = " --vendor " + vendor
+					}
+					rec.Manufacturer, rec.Model, rec.Firmware, rec.Vendor = info.Manufacturer, info.Model, info.Firmware, vendor
+				}
+				if addAll {
+					cam, err := discoveredCamera(devCtx, cfg, d, vendor, user, pass, profile)
+					devCancel()
+					if err != nil {
+						if !table {
+							rec.Error = err.Error()
+							records = append(records, rec)
+						}
+						_, _ = fmt.Fprintf(warn, "%s %s: %v\n", sty.Err("✖"), d.Host, err)
+						continue
+					}
+					var created bool
+					cfg, created = config.UpsertCamera(cfg, cam)
+					verb := "updated"
+					if created {
+						verb = "added"
 					}
+					added++
+					rec.Action, rec.Camera, rec.Profile = verb, cam.Name, cam.Profiles[0].Name
+					if !table {
+						records = append(records, rec)
+						continue
+					}
+					cmd.Printf("%s %s%s%s %s as %q: %s%s\n", sty.OK("✔"), d.Host, viaInterface(d), rtspStr, verb, cam.Name, describeProfile(cam.Profiles[0]), infoStr)
+					continue
+				}
+				devCancel()
+				rec.AddCommand = "camsnap add --name cam-" + safeName(d.Host) + " " + onvifAddFlags(d.Host) + " --user <user> --pass <pass>" + vendorFlag
+				if !table {
+					records = append(records, rec)
+					continue
+				}
+				cmd.Printf("%s%s%s\t(add: camsnap add --name cam-%s %s --user <user> --pass <pass>%s)%s\n",
+					sty.OK(d.Host), viaInterface(d), rtspStr, safeName(d.Host), onvifAddFlags(d.Host), vendorFlag, infoStr)
+			}
+			// cameras that only answered the port scan
+			if !table {
+				records = append(records, scanOnlyRecords(scanResults, rtspHosts)...)
+			} else {
+				for _, r := range scanResults {
+					if _, ok := rtspHosts[r.Host]; !ok {
+						continue
+					}
+					cmd.Printf("%s %s\t(add: %s)\n", sty.OK(hostport.Join(r.Host, r.Port)), rtspSummary([]discovery.ScanResult{r}), scanAddCommand(r))
+				}
+			}
+			if addAll && added > 0 {
+				if err := saveConfig(cfgPath, cfg); err != nil {
+					return err
 				}
-				cmd.Printf("%s\t(add: camsnap add --name cam-%s --host %s --user <user> --pass <pass>)%s\n",
-					sty.OK(d.Host), safeName(d.Host), d.Host, infoStr)
+			}
+			if !table {
+				return writeStructured(cmd.OutOrStdout(), format, records)
 			}
 			return nil
 		},
 	}
 	cmd.Flags().DurationVar(&timeout, "timeout", 3*time.Second, "Discovery timeout")
 	cmd.Flags().BoolVar(&includeInfo, "info", false, "Attempt ONVIF GetDeviceInformation (may require credentials)")
+	cmd.Flags().BoolVar(&addAll, "add", false, "Save every discovered camera using its ONVIF media profiles")
+	cmd.Flags().StringVar(&user, "user", "", "Camera username for --info/--add (default: saved credentials for the host)")
+	cmd.Flags().StringVar(&pass, "pass", "", "Camera password for --info/--add")
+	cmd.Flags().StringVar(&profile, "profile", "", "ONVIF profile to make the default with --add (name, token or 1-based index)")
+	cmd.Flags().BoolVar(&listen, "listen", false, "Passively report WS-Discovery Hello/Bye announcements instead of probing")
+	cmd.Flags().BoolVar(&update, "update", false, "With --listen, save the new host of saved cameras that change IP")
+	cmd.Flags().DurationVar(&duration, "duration", 0, "How long --listen runs (0 = until interrupted)")
+	cmd.Flags().StringSliceVar(&ifaces, "iface", nil, "Probe/listen only on these interfaces (repeatable; default: every up, multicast-capable interface)")
+	cmd.Flags().IntVar(&ttl, "ttl", 1, "Multicast TTL for probes (raise only if multicast is routed between VLANs)")
+	cmd.Flags().StringVar(&scanCIDR, "scan", "", "Also scan a range (e.g. 192.168.1.0/24) for RTSP ports 554, 8554, 7447, 7441")
+	cmd.Flags().DurationVar(&scanTimeout, "scan-timeout", 2*time.Minute, "Stop --scan after this long (0 = scan the whole range)")
+	addOutputFlag(cmd, &output)
 	return cmd
 }
 
-func safeName(host string) string {
-	// use host part without port for a short name
-	for i, r := range host {
-		if r == ':' {
-			return host[:i]
+// scanOnlyRecords makes one record per host that only answered the port scan; like ONVIF
+// records, host is the bare host and the ports are in rtsp[].
+func scanOnlyRecords(results []discovery.ScanResult, scanOnly map[string][]discovery.ScanResult) []discoveredRecord {
+	var out []discoveredRecord
+	for _, r := range results {
+		ports, ok := scanOnly[r.Host]
+		if !ok || r != ports[0] {
+			continue
 		}
+		out = append(out, discoveredRecord{Host: r.Host, RTSP: rtspPortRecords(ports), AddCommand: scanAddCommand(r)})
+	}
+	return out
+}
+
+// scanAddCommand suggests an add command for a port scan hit.
+func scanAddCommand(r discovery.ScanResult) string {
+	protoFlag := ""
+	if r.TLS {
+		protoFlag = " --protocol rtsps"
 	}
-	return host
+	return fmt.Sprintf("camsnap add --name cam-%s --host %s --port %d%s --user <user> --pass <pass>", safeName(r.Host), r.Host, r.Port, protoFlag)
 }
 
-func fetchInfo(ctx context.Context, cfg config.Config, d discovery.Device) string {
+// discoveredRecord is one device as printed by discover --output json|yaml.
+type discoveredRecord struct {
+	Host         string           `json:"host" yaml:"host"`
+	XAddr        string           `json:"xaddr,omitempty" yaml:"xaddr,omitempty"`
+	Endpoint     string           `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	Interface    string           `json:"interface,omitempty" yaml:"interface,omitempty"`
+	Manufacturer string           `json:"manufacturer,omitempty" yaml:"manufacturer,omitempty"`
+	Model        string           `json:"model,omitempty" yaml:"model,omitempty"`
+	Firmware     string           `json:"firmware,omitempty" yaml:"firmware,omitempty"`
+	Vendor       string           `json:"vendor,omitempty" yaml:"vendor,omitempty"`
+	RTSP         []rtspPortRecord `json:"rtsp,omitempty" yaml:"rtsp,omitempty"`
+	Action       string           `json:"action,omitempty" yaml:"action,omitempty"` // added|updated with --add
+	Camera       string           `json:"camera,omitempty" yaml:"camera,omitempty"`
+	Profile      string           `json:"profile,omitempty" yaml:"profile,omitempty"`
+	AddCommand   string           `json:"add_command,omitempty" yaml:"add_command,omitempty"`
+	Error        string           `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// rtspPortRecord is one --scan hit.
+type rtspPortRecord struct {
+	Port         int    `json:"port" yaml:"port"`
+	TLS          bool   `json:"tls" yaml:"tls"`
+	Server       string `json:"server,omitempty" yaml:"server,omitempty"`
+	Status       int    `json:"status" yaml:"status"`
+	AuthRequired bool   `json:"auth_required" yaml:"auth_required"`
+	AuthScheme   string `json:"auth_scheme,omitempty" yaml:"auth_scheme,omitempty"`
+}
+
+func rtspPortRecords(results []discovery.ScanResult) []rtspPortRecord {
+	out := make([]rtspPortRecord, 0, len(results))
+	for _, r := range results {
+		out = append(out, rtspPortRecord{Port: r.Port, TLS: r.TLS, Server: r.Server, Status: r.Status, AuthRequired: r.AuthRequired, AuthScheme: r.AuthScheme})
+	}
+	return out
+}
+
+// rtspSummary describes scan hits: "rtsp:554 [Hikvision, digest auth]".
+func rtspSummary(results []discovery.ScanResult) string {
+	parts := make([]string, 0, len(results))
+	for _, r := range results {
+		proto := "rtsp"
+		if r.TLS {
+			proto = "rtsps"
+		}
+		var notes []string
+		if r.Server != "" {
+			notes = append(notes, r.Server)
+		}
+		switch {
+		case r.AuthRequired && r.AuthScheme != "":
+			notes = append(notes, strings.ToLower(r.AuthScheme)+" auth")
+		case r.AuthRequired:
+			notes = append(notes, "auth required")
+		case r.Status == 200:
+			notes = append(notes, "no auth")
+		}
+		part := proto + ":" + strconv.Itoa(r.Port)
+		if len(notes) > 0 {
+			part += " [" + strings.Join(notes, ", ") + "]"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, " ")
+}
+
+// viaInterface names the local interface a device answered on, if known.
+func viaInterface(d discovery.Device) string {
+	if d.Interface == "" {
+		return ""
+	}
+	return " via " + d.Interface
+}
+
+// listenAnnouncements prints Hello/Bye announcements and follows saved cameras across IP changes.
+func listenAnnouncements(cmd *cobra.Command, sty styler, cfg config.Config, cfgPath string, opts discovery.Options, duration time.Duration, update bool) error {
+	ctx := context.Background()
+	if duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, duration)
+		defer cancel()
+	}
+	cmd.Println("Listening for WS-Discovery Hello/Bye announcements...")
+	var saveErr error
+	err := discovery.Listen(ctx, opts, func(a discovery.Announcement) {
+		var changed bool
+		cfg, changed = reportAnnouncement(cmd, sty, cfg, a, update)
+		if changed && saveErr == nil {
+			saveErr = saveConfig(cfgPath, cfg)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return saveErr
+}
+
+// reportAnnouncement prints one announcement. A Hello from a saved camera (matched by endpoint UUID,
+// or by host for cameras saved without one) at a new address is applied to cfg when update is set.
+func reportAnnouncement(cmd *cobra.Command, sty styler, cfg config.Config, a discovery.Announcement, update bool) (config.Config, bool) {
+	idx := -1
+	for i, cam := range cfg.Cameras {
+		if a.Device.Endpoint != "" && cam.Endpoint == a.Device.Endpoint {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 && a.Kind == discovery.Hello && a.Device.Host != "" {
+		for i, cam := range cfg.Cameras {
+			if cam.Endpoint == "" && hostport.SameHost(cam.Host, a.Device.Host) {
+				idx = i
+				break
+			}
+		}
+	}
+
+	known := ""
+	if idx >= 0 {
+		known = " (camera " + cfg.Cameras[idx].Name + ")"
+	}
+	if a.Kind == discovery.Bye {
+		cmd.Printf("%s bye endpoint=%s%s\n", sty.Warn("-"), a.Device.Endpoint, known)
+		return cfg, false
+	}
+	cmd.Printf("%s hello %s%s endpoint=%s%s\n", sty.OK("+"), a.Device.Host, viaInterface(a.Device), a.Device.Endpoint, known)
+	if idx < 0 || a.Device.Host == "" {
+		return cfg, false
+	}
+
+	cam := cfg.Cameras[idx]
+	newHost, _ := hostport.Split(a.Device.Host)
+	moved := !hostport.SameHost(cam.Host, newHost)
+	learned := cam.Endpoint == "" && a.Device.Endpoint != ""
+	if !moved && !learned {
+		return cfg, false
+	}
+	if moved {
+		cmd.Printf("  %s moved %s -> %s\n", cam.Name, cam.Host, newHost)
+	}
+	if !update {
+		if moved {
+			cmd.Println("  rerun with --update to save the new address")
+		}
+		return cfg, false
+	}
+	cam.Host = newHost
+	cam.ONVIF = a.Device.Address
+	if learned {
+		cam.Endpoint = a.Device.Endpoint
+	}
+	cfg.Cameras[idx] = cam
+	cmd.Println(sty.OK("  updated " + cam.Name))
+	return cfg, true
+}
+
+// onvifAddFlags suggests add flags for a discovered ONVIF host:port.
+func onvifAddFlags(host string) string {
+	h, port := hostport.Split(host)
+	flags := "--host " + h + " --onvif"
+	if port != 0 && port != 80 {
+		flags += " --onvif-port " + strconv.Itoa(port)
+	}
+	return flags
+}
+
+// discoveredCamera builds a camera entry for a discovered device from its ONVIF profiles.
+func discoveredCamera(ctx context.Context, cfg config.Config, d discovery.Device, vendor, user, pass, profile string) (config.Camera, error) {
+	host, _ := hostport.Split(d.Host)
+	cam := config.Camera{
+		Name:     "cam-" + safeName(d.Host),
+		Host:     host,
+		Port:     554,
+		Protocol: "rtsp",
+		Username: user,
+		Password: pass,
+	}
+	for _, existing := range cfg.Cameras {
+		sameDevice := d.Endpoint != "" && existing.Endpoint == d.Endpoint
+		if sameDevice || hostport.SameHost(existing.Host, d.Host) {
+			// keep the user's name and per-camera defaults; refresh stream details
+			cam = existing
+			if user != "" {
+				cam.Username, cam.Password = user, pass
+			}
+			break
+		}
+	}
+	if d.Endpoint != "" {
+		cam.Endpoint = d.Endpoint
+	}
+	if preset, ok := presets.Lookup(vendor); ok && cam.Vendor == "" {
+		cam.Vendor = preset.Name
+		if cam.RTSPTransport == "" {
+			cam.RTSPTransport = preset.Transport
+		}
+		if cam.AudioCodec == "" && !cam.NoAudio {
+			cam.AudioCodec = preset.AudioCodec
+		}
+	}
+	return applyONVIFProfiles(ctx, cam, d.Address, profile, false)
+}
+
+func safeName(host string) string {
+	// use host part without port for a short name; IPv6 colons/zones become dashes
+	h, _ := hostport.Split(host)
+	return strings.NewReplacer(":", "-", "%", "-").Replace(h)
+}
+
+// fetchInfo returns the device information and the vendor preset matching the ONVIF manufacturer.
+// Devices that refuse GetDeviceInformation yield a zero DeviceInfo.
+func fetchInfo(ctx context.Context, cfg config.Config, d discovery.Device, user, pass string) (discovery.DeviceInfo, string) {
 	// If we already have creds for this host, try them first.
-	user, pass := findCreds(cfg, d.Host)
+	if user == "" {
+		user, pass = findCreds(cfg, d.Host)
+	}
 	info, err := discovery.FetchDeviceInfo(ctx, d.Address, user, pass)
 	if err != nil {
-		return ""
+		return discovery.DeviceInfo{}, ""
 	}
+	vendor := ""
+	if preset, ok := presets.ForManufacturer(info.Manufacturer); ok {
+		vendor = preset.Name
+	}
+	return info, vendor
+}
+
+// infoSummary is a short model/firmware line: "C320WS, fw 1.3.0, TP-Link".
+func infoSummary(info discovery.DeviceInfo) string {
 	parts := []string{}
 	if info.Model != "" {
 		parts = append(parts, info.Model)
@@ -82,7 +473,7 @@ func fetchInfo(ctx context.Context, cfg config.Config, d discovery.Device) strin
 
 func findCreds(cfg config.Config, host string) (string, string) {
 	for _, cam := range cfg.Cameras {
-		if cam.Host == host || strings.HasPrefix(host, cam.Host+":") {
+		if hostport.SameHost(cam.Host, host) {
 			return cam.Username, cam.Password
 		}
 	}
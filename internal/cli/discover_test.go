@@ -0,0 +1,32 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/steipete/camsnap/internal/discovery"
+)
+
+func TestRTSPSummary(t *testing.T) {
+	got := rtspSummary([]discovery.ScanResult{
+		{Port: 554, Server: "Hikvision/1.0", Status: 401, AuthRequired: true, AuthScheme: "Digest"},
+		{Port: 7441, TLS: true, Status: 404},
+		{Port: 8554, Status: 200},
+	})
+	want := "rtsp:554 [Hikvision/1.0, digest auth] rtsps:7441 rtsp:8554 [no auth]"
+	if got != want {
+		t.Fatalf("got %q want %q", got, want)
+	}
+}
+
+func TestScanOnlyRecords(t *testing.T) {
+	results := []discovery.ScanResult{
+		{Host: "10.0.0.5", Port: 554, Status: 401},
+		{Host: "10.0.0.5", Port: 7441, TLS: true},
+		{Host: "10.0.0.6", Port: 554},
+	}
+	scanOnly := map[string][]discovery.ScanResult{"10.0.0.5": results[:2]}
+	got := scanOnlyRecords(results, scanOnly)
+	if len(got) != 1 || got[0].Host != "10.0.0.5" || len(got[0].RTSP) != 2 || got[0].RTSP[1].Port != 7441 {
+		t.Fatalf("expected one record per scan-only host with its ports, got %+v", got)
+	}
+}
@@ -7,19 +7,83 @@ import (
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/steipete/camsnap/internal/camerr"
+	"github.com/steipete/camsnap/internal/config"
+	"github.com/steipete/camsnap/internal/discovery"
 	"github.com/steipete/camsnap/internal/exec"
+	"github.com/steipete/camsnap/internal/hostport"
+	"github.com/steipete/camsnap/internal/httpcam"
 	"github.com/steipete/camsnap/internal/rtsp"
 )
 
+// doctorReport is what doctor --output json|yaml prints.
+type doctorReport struct {
+	FFmpeg        bool           `json:"ffmpeg" yaml:"ffmpeg"`
+	FFmpegVersion string         `json:"ffmpeg_version,omitempty" yaml:"ffmpeg_version,omitempty"`
+	FFmpegMissing []exec.Feature `json:"ffmpeg_missing,omitempty" yaml:"ffmpeg_missing,omitempty"` // encoders, protocols and filters the build lacks
+	Config        string         `json:"config" yaml:"config"`
+	Cameras       []cameraCheck  `json:"cameras" yaml:"cameras"`
+}
+
+// cameraCheck is the result of checking one saved camera.
+type cameraCheck struct {
+	Name      string      `json:"name" yaml:"name"`
+	Host      string      `json:"host" yaml:"host"`
+	Address   string      `json:"address,omitempty" yaml:"address,omitempty"` // host:port that was dialed
+	Source    string      `json:"source,omitempty" yaml:"source,omitempty"`
+	XAddr     string      `json:"xaddr,omitempty" yaml:"xaddr,omitempty"`
+	Reachable bool        `json:"reachable" yaml:"reachable"`
+	LatencyMS float64     `json:"latency_ms,omitempty" yaml:"latency_ms,omitempty"` // TCP connect time
+	Failed    string      `json:"failed,omitempty" yaml:"failed,omitempty"`         // url|dial|probe
+	Error     string      `json:"error,omitempty" yaml:"error,omitempty"`
+	Probe     *probeCheck `json:"probe,omitempty" yaml:"probe,omitempty"`
+	Clock     *clockCheck `json:"clock,omitempty" yaml:"clock,omitempty"`
+	Matrix    []matrixRun `json:"matrix,omitempty" yaml:"matrix,omitempty"`
+	Changes   []string    `json:"changes,omitempty" yaml:"changes,omitempty"` // config diff applied by --fix
+}
+
+// probeCheck is the outcome of --probe; Class is the camerr class of the failure.
+type probeCheck struct {
+	Transport string `json:"transport,omitempty" yaml:"transport,omitempty"`
+	Client    string `json:"client,omitempty" yaml:"client,omitempty"`
+	OK        bool   `json:"ok" yaml:"ok"`
+	Class     string `json:"class,omitempty" yaml:"class,omitempty"`
+	Error     string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// clockCheck is the camera clock offset for ONVIF cameras; positive when the camera runs ahead.
+// Skipped is set when a camera added without --onvif has no device service on --onvif-port.
+type clockCheck struct {
+	DriftSeconds float64 `json:"drift_seconds" yaml:"drift_seconds"`
+	Skipped      bool    `json:"skipped,omitempty" yaml:"skipped,omitempty"`
+	Error        string  `json:"error,omitempty" yaml:"error,omitempty"`
+
+	offset time.Duration
+}
+
+// doctorOptions are the per-camera check settings from doctor's flags.
+type doctorOptions struct {
+	timeout   time.Duration
+	probe     bool
+	authMode  string
+	transport string // overrides each camera's rtsp_transport when set
+	runs      int    // attempts per --matrix combination
+	onvifPort int    // clock-check cameras added without --onvif on this port; 0 skips them
+}
+
 func newDoctorCmd() *cobra.Command {
-	var timeout time.Duration
-	var probe bool
-	var authMode string
-	var transport string
+	var opts doctorOptions
+	var matrix bool
+	var fix bool
+	var output string
 	cmd := &cobra.Command{
 		Use:   "doctor",
 		Short: "Run basic checks (ffmpeg in PATH, config present, camera ports reachable)",
 		RunE: func(cmd *cobra.Command, _ []string) error {
+			format, err := parseOutput(output)
+			if err != nil {
+				return err
+			}
 			sty := newStyler(cmd.OutOrStdout())
 
 			cfgFlag, err := configPathFlag(cmd)
@@ -30,69 +94,266 @@ func newDoctorCmd() *cobra.Command {
 			if err != nil {
 				return err
 			}
-
-			if exec.HasBinary("ffmpeg") {
-				cmd.Println(sty.OK("✔ ffmpeg found in PATH"))
-			} else {
-				cmd.Println(sty.Err("✖ ffmpeg missing (install ffmpeg and retry)"))
+			if fix {
+				matrix = true
 			}
-
-			cmd.Printf("Config file: %s\n", path)
-			if len(cfg.Cameras) == 0 {
-				cmd.Println(sty.Warn("No cameras saved. Add one with camsnap add ..."))
-				return nil
+			if opts.runs < 1 {
+				return fmt.Errorf("--runs must be at least 1")
 			}
 
-			for _, cam := range cfg.Cameras {
-				host := cam.Host
-				port := cam.Port
-				if port == 0 {
-					port = 554
+			report := doctorReport{FFmpeg: media.Available("ffmpeg"), Config: path, Cameras: []cameraCheck{}}
+			if matrix && !report.FFmpeg {
+				return fmt.Errorf("--matrix needs ffmpeg, which is not in PATH (install ffmpeg and retry)")
+			}
+			if report.FFmpeg {
+				caps := media.Caps(context.Background())
+				report.FFmpegVersion = caps.Version
+				report.FFmpegMissing = caps.Missing()
+			}
+			table := format == outputTable
+			if table {
+				if report.FFmpeg {
+					if report.FFmpegVersion != "" {
+						cmd.Println(sty.OK("✔ ffmpeg " + report.FFmpegVersion + " found in PATH"))
+					} else {
+						cmd.Println(sty.OK("✔ ffmpeg found in PATH"))
+					}
+					for _, f := range report.FFmpegMissing {
+						cmd.Printf("%s ffmpeg lacks %s\n", sty.Warn("!"), f)
+					}
+				} else {
+					cmd.Println(sty.Err("✖ ffmpeg missing (install ffmpeg and retry)"))
 				}
-				addr := net.JoinHostPort(host, fmt.Sprintf("%d", port))
-
-				if err := dialOnce(addr, timeout); err != nil {
-					cmd.Printf("%s %s dial %s failed: %v\n", sty.Err("✖"), cam.Name, addr, err)
-					continue
+				cmd.Printf("Config file: %s\n", path)
+				if len(cfg.Cameras) == 0 {
+					cmd.Println(sty.Warn("No cameras saved. Add one with camsnap add ..."))
+					return nil
 				}
-				url, err := rtsp.BuildURL(cam)
-				if err != nil {
-					cmd.Printf("%s %s RTSP URL invalid: %v\n", sty.Err("✖"), cam.Name, err)
-					continue
+			}
+
+			fixed := false
+			for i, cam := range cfg.Cameras {
+				c := checkCamera(cam, opts)
+				if table {
+					printCameraCheck(cmd, sty, c)
 				}
-				if probe {
-					if err := probeRTSP(cmd, url, timeout+2*time.Second, authMode, transport); err != nil {
-						cmd.Printf("%s %s ffmpeg probe failed: %v\n", sty.Err("✖"), cam.Name, err)
-						continue
+				if matrix && c.Reachable && !httpcam.IsHTTPSource(cam) {
+					var best *matrixRun
+					c.Matrix, best = runMatrix(cam, opts)
+					if table {
+						printMatrix(cmd, sty, c.Name, c.Matrix, best)
 					}
+					if fix && best != nil && !best.Current {
+						c.Changes = cameraDiff(cam, best.camera)
+						cfg.Cameras[i] = best.camera
+						fixed = true
+						if table {
+							printChanges(cmd, sty, c.Name, c.Changes)
+						}
+					}
+				}
+				report.Cameras = append(report.Cameras, c)
+			}
+			if fixed {
+				if err := saveConfig(path, cfg); err != nil {
+					return err
+				}
+				if table {
+					cmd.Println(sty.OK("Saved " + path))
 				}
-				cmd.Printf("%s %s reachable at %s\n", sty.OK("✔"), cam.Name, addr)
+			}
+			if !table {
+				return writeStructured(cmd.OutOrStdout(), format, report)
 			}
 			return nil
 		},
 	}
-	cmd.Flags().DurationVar(&timeout, "timeout", 2*time.Second, "Dial timeout per camera")
-	cmd.Flags().BoolVar(&probe, "probe", false, "Use ffmpeg to probe each RTSP URL briefly")
-	cmd.Flags().StringVar(&authMode, "rtsp-auth", "auto", "RTSP auth mode: auto|basic|digest")
-	cmd.Flags().StringVar(&transport, "rtsp-transport", "tcp", "RTSP transport: tcp|udp (probe)")
+	cmd.Flags().DurationVar(&opts.timeout, "timeout", 2*time.Second, "Dial timeout per camera")
+	cmd.Flags().BoolVar(&opts.probe, "probe", false, "Probe each RTSP stream briefly with the camera's own transport, client and stream")
+	cmd.Flags().StringVar(&opts.authMode, "rtsp-auth", "auto", "RTSP auth mode: auto|basic|digest")
+	cmd.Flags().StringVar(&opts.transport, "rtsp-transport", "", "Probe with this RTSP transport (tcp|udp) instead of each camera's own")
+	cmd.Flags().IntVar(&opts.onvifPort, "onvif-port", 0, "Also check the clock of cameras added without --onvif, on this ONVIF port")
+	cmd.Flags().BoolVar(&matrix, "matrix", false, "Time every transport, client and stream combination per camera")
+	cmd.Flags().IntVar(&opts.runs, "runs", 2, "Attempts per --matrix combination; only combinations that pass every run count as reliable")
+	cmd.Flags().BoolVar(&fix, "fix", false, "Run --matrix and save each camera's fastest reliable combination to the config")
+	addOutputFlag(cmd, &output)
 	return cmd
 }
 
-func dialOnce(addr string, timeout time.Duration) error {
+// checkCamera dials a camera, optionally probes its stream, and measures ONVIF clock drift.
+func checkCamera(cam config.Camera, opts doctorOptions) cameraCheck {
+	timeout := opts.timeout
+	c := cameraCheck{Name: cam.Name, Host: cam.Host, XAddr: cam.ONVIF}
+	fail := func(stage string, err error) cameraCheck {
+		c.Failed, c.Error = stage, err.Error()
+		return c
+	}
+
+	if httpcam.IsHTTPSource(cam) {
+		c.Source = cam.Source
+		url, err := httpcam.BuildURL(cam)
+		if err != nil {
+			return fail("url", err)
+		}
+		c.Address = httpcam.DialAddr(url)
+		latency, err := dialOnce(c.Address, timeout)
+		if err != nil {
+			return fail("dial", err)
+		}
+		c.Reachable, c.LatencyMS = true, milliseconds(latency)
+		if opts.probe {
+			ctx, cancel := exec.WithTimeout(context.Background(), timeout+2*time.Second)
+			_, err := httpcam.NewClient(cam.Username, cam.Password, timeout+2*time.Second).FetchSnapshot(ctx, url)
+			cancel()
+			if err != nil {
+				c.Probe = &probeCheck{Class: camerr.Class(err), Error: err.Error()}
+				return fail("probe", err)
+			}
+			c.Probe = &probeCheck{OK: true}
+		}
+		return c
+	}
+
+	port := cam.Port
+	if port == 0 {
+		port = rtsp.DefaultPort(cam.Protocol)
+	}
+	c.Address = hostport.Join(cam.Host, port)
+	latency, err := dialOnce(c.Address, timeout)
+	if err != nil {
+		return fail("dial", err)
+	}
+	c.Reachable, c.LatencyMS = true, milliseconds(latency)
+	url, err := streamURL(cam)
+	if err != nil {
+		return fail("url", err)
+	}
+	if opts.probe {
+		transport, client := effectiveRTSP(cam, opts.transport)
+		c.Probe = &probeCheck{Transport: transport, Client: client}
+		if client == "gortsplib" {
+			err = grabTestFrame(cam, url, transport, client, timeout+2*time.Second)
+		} else {
+			err = probeRTSP(url, timeout+2*time.Second, opts.authMode, transport)
+		}
+		if err != nil {
+			c.Probe.Class, c.Probe.Error = camerr.Class(err), err.Error()
+			return fail("probe", err)
+		}
+		c.Probe.OK = true
+	}
+	if cam.ONVIF != "" {
+		c.Clock = checkClock(cam.ONVIF, timeout)
+	} else if opts.onvifPort != 0 {
+		c.Clock = checkClock(onvifDeviceURL(cam.Host, opts.onvifPort), timeout)
+		if c.Clock.Error != "" {
+			c.Clock.Skipped = true
+		}
+	}
+	return c
+}
+
+// printCameraCheck renders a check as doctor's human output.
+func printCameraCheck(cmd *cobra.Command, sty styler, c cameraCheck) {
+	switch c.Failed {
+	case "url":
+		if c.Source != "" {
+			cmd.Printf("%s %s %s URL invalid: %s\n", sty.Err("✖"), c.Name, c.Source, c.Error)
+		} else {
+			cmd.Printf("%s %s RTSP URL invalid: %s\n", sty.Err("✖"), c.Name, c.Error)
+		}
+		return
+	case "dial":
+		cmd.Printf("%s %s dial %s failed: %s\n", sty.Err("✖"), c.Name, c.Address, c.Error)
+		return
+	case "probe":
+		if c.Source != "" {
+			cmd.Printf("%s %s %s fetch failed: %s\n", sty.Err("✖"), c.Name, c.Source, c.Error)
+		} else {
+			cmd.Printf("%s %s %s probe (%s) failed: %s (%s)\n", sty.Err("✖"), c.Name, c.Probe.Client, c.Probe.Transport, c.Error, c.Probe.Class)
+		}
+		return
+	}
+	if c.Source != "" {
+		cmd.Printf("%s %s reachable at %s (%s)\n", sty.OK("✔"), c.Name, c.Address, c.Source)
+		return
+	}
+	if c.Probe != nil {
+		cmd.Printf("%s %s reachable at %s, %s probe (%s) ok\n", sty.OK("✔"), c.Name, c.Address, c.Probe.Client, c.Probe.Transport)
+	} else {
+		cmd.Printf("%s %s reachable at %s\n", sty.OK("✔"), c.Name, c.Address)
+	}
+	if c.Clock != nil {
+		printClockCheck(cmd, sty, c.Name, *c.Clock)
+	}
+}
+
+// clockDriftWarn is the drift beyond which cameras commonly reject WS-Security digests.
+const clockDriftWarn = 5 * time.Second
+
+// checkClock measures how far the camera clock is off; camsnap compensates, but events and
+// recordings carry the camera's timestamps.
+func checkClock(xaddr string, timeout time.Duration) *clockCheck {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout+2*time.Second)
+	defer cancel()
+	offset, err := discovery.ClockOffset(ctx, xaddr)
+	if err != nil {
+		return &clockCheck{Error: err.Error()}
+	}
+	return &clockCheck{DriftSeconds: offset.Seconds(), offset: offset}
+}
+
+func printClockCheck(cmd *cobra.Command, sty styler, name string, c clockCheck) {
+	if c.Skipped {
+		cmd.Printf("- %s clock check skipped (no ONVIF device service on --onvif-port)\n", name)
+		return
+	}
+	if c.Error != "" {
+		cmd.Printf("%s %s clock check failed: %s\n", sty.Warn("!"), name, c.Error)
+		return
+	}
+	drift := c.offset
+	if drift < 0 {
+		drift = -drift
+	}
+	if drift > clockDriftWarn {
+		cmd.Printf("%s %s clock drift %s (compensated for ONVIF; enable NTP on the camera)\n", sty.Warn("!"), name, formatDrift(c.offset))
+		return
+	}
+	cmd.Printf("%s %s clock in sync (%s)\n", sty.OK("✔"), name, formatDrift(c.offset))
+}
+
+// milliseconds rounds a latency to 0.1ms.
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Round(100*time.Microsecond)) / float64(time.Millisecond)
+}
+
+// formatDrift renders an offset with an explicit sign: +1m30s ahead, -4s behind.
+func formatDrift(d time.Duration) string {
+	if d < 0 {
+		return d.String()
+	}
+	return "+" + d.String()
+}
+
+// dialOnce opens and closes a TCP connection, returning how long the connect took.
+func dialOnce(addr string, timeout time.Duration) (time.Duration, error) {
 	if timeout <= 0 {
 		timeout = 2 * time.Second
 	}
+	start := time.Now()
 	conn, err := net.DialTimeout("tcp", addr, timeout)
 	if err != nil {
-		return err
+		return 0, camerr.Wrap(camerr.FromNet(err), err)
 	}
-	return conn.Close()
+	latency := time.Since(start)
+	return latency, conn.Close()
 }
 
-func probeRTSP(_ *cobra.Command, url string, timeout time.Duration, authMode, transport string) error {
+// probeRTSP reads a second of the stream with ffmpeg.
+func probeRTSP(url string, timeout time.Duration, authMode, transport string) error {
 	// retry a couple times to avoid transient RTSP setup errors
 	var lastErr error
-	var lastOut string
 	if _, ok := parseRTSPAuth(authMode); !ok {
 		return fmt.Errorf("invalid --rtsp-auth (use auto|basic|digest)")
 	}
@@ -101,6 +362,10 @@ func probeRTSP(_ *cobra.Command, url string, timeout time.Duration, authMode, tr
 		return fmt.Errorf("invalid --rtsp-transport (use tcp|udp)")
 	}
 
+	if err := media.Caps(context.Background()).CheckRTSPTransport(xport); err != nil {
+		return err
+	}
+
 	for attempt := 0; attempt < 3; attempt++ {
 		ctx, cancel := exec.WithTimeout(context.Background(), timeout)
 		args := []string{
@@ -114,13 +379,12 @@ func probeRTSP(_ *cobra.Command, url string, timeout time.Duration, authMode, tr
 			"-f", "null",
 			"-",
 		)
-		lastOut, lastErr = exec.RunFFmpegWithOutput(ctx, args...)
+		_, lastErr = media.Run(ctx, args...)
 		cancel()
 		if lastErr == nil {
 			return nil
 		}
 		time.Sleep(500 * time.Millisecond)
 	}
-	class := exec.ClassifyError(lastOut)
-	return fmt.Errorf("%s (%s)", lastErr, class)
+	return lastErr
 }
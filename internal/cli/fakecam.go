@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/steipete/camsnap/internal/fakecam"
+)
+
+func newFakecamCmd() *cobra.Command {
+	var cfg fakecam.Config
+	var size string
+	var duration time.Duration
+	var quiet bool
+	cmd := &cobra.Command{
+		Use:   "fakecam",
+		Short: "Serve a synthetic H264 RTSP camera for tests and demos",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if _, err := fmt.Sscanf(size, "%dx%d", &cfg.Width, &cfg.Height); err != nil {
+				return fmt.Errorf("invalid --size %q (use WIDTHxHEIGHT, e.g. 640x480)", size)
+			}
+			if !quiet {
+				cfg.Logf = func(format string, args ...any) {
+					cmd.Printf("%s "+format+"\n", append([]any{time.Now().Format("15:04:05")}, args...)...)
+				}
+			}
+			cam, err := fakecam.Start(cfg)
+			if err != nil {
+				return err
+			}
+			defer cam.Close()
+
+			sty := newStyler(cmd.OutOrStdout())
+			cmd.Printf("%s fakecam serving %s (%s)\n", sty.OK("✔"), cam.URL(), cam.Describe())
+			cmd.Printf("  add it: camsnap add --name fakecam --url '%s'\n", cam.URL())
+
+			ctx := context.Background()
+			if duration > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, duration)
+				defer cancel()
+			}
+			<-ctx.Done()
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&cfg.Addr, "listen", ":8554", "RTSP listen address")
+	cmd.Flags().IntVar(&cfg.UDPPort, "udp-port", 8000, "UDP port for RTP (RTCP uses the next one); 0 picks a free pair")
+	cmd.Flags().StringVar(&cfg.Path, "path", "/stream1", "Stream path; other paths answer 404")
+	cmd.Flags().StringVar(&cfg.Username, "user", "", "Require this username (empty = no auth)")
+	cmd.Flags().StringVar(&cfg.Password, "pass", "", "Password for --user")
+	cmd.Flags().StringVar(&cfg.Auth, "auth", "any", "Accepted auth: basic|digest|any")
+	cmd.Flags().StringVar(&cfg.Transport, "transport", "any", "Accepted RTSP transport: tcp|udp|any (others get 461)")
+	cmd.Flags().StringVar(&size, "size", "320x240", "Test pattern size (multiples of 16)")
+	cmd.Flags().IntVar(&cfg.FPS, "fps", 10, "Frames per second")
+	cmd.Flags().IntVar(&cfg.GOP, "gop", 0, "Frames per keyframe (0 = one keyframe per second)")
+	cmd.Flags().StringVar(&cfg.File, "file", "", "Loop this Annex-B .h264 file instead of the test pattern")
+	cmd.Flags().IntVar(&cfg.MaxSessions, "max-sessions", 0, "Answer 453 Not Enough Bandwidth beyond this many sessions (0 = unlimited)")
+	cmd.Flags().DurationVar(&cfg.DropAfter, "drop-after", 0, "Disconnect every session this long after PLAY (0 = never)")
+	cmd.Flags().DurationVar(&cfg.MotionEvery, "motion-every", 0, "Start a motion burst this often (0 = static picture)")
+	cmd.Flags().DurationVar(&cfg.MotionFor, "motion-for", time.Second, "Length of each motion burst")
+	cmd.Flags().DurationVar(&duration, "duration", 0, "How long to serve (0 = until interrupted)")
+	cmd.Flags().BoolVar(&quiet, "quiet", false, "Do not log connections and sessions")
+	return cmd
+}
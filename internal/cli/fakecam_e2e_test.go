@@ -0,0 +1,170 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steipete/camsnap/internal/config"
+	"github.com/steipete/camsnap/internal/exec"
+	"github.com/steipete/camsnap/internal/fakecam"
+)
+
+// These tests run the commands against a fakecam over loopback. The ones that decode video need a
+// real ffmpeg and are skipped without one.
+
+func startFakecam(t *testing.T, cfg fakecam.Config) *fakecam.Server {
+	t.Helper()
+	cam, err := fakecam.Start(cfg)
+	if err != nil {
+		t.Fatalf("fakecam: %v", err)
+	}
+	t.Cleanup(cam.Close)
+	return cam
+}
+
+// runCamsnap executes one command line and returns its stdout.
+func runCamsnap(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	var buf bytes.Buffer
+	root := NewRootCommand("test")
+	root.SetOut(&buf)
+	root.SetErr(&bytes.Buffer{})
+	root.SetArgs(args)
+	err := root.Execute()
+	return buf.String(), err
+}
+
+func addFakecam(t *testing.T, cfgPath, name, url string, extra ...string) {
+	t.Helper()
+	args := append([]string{"--config", cfgPath, "add", "--name", name, "--url", url}, extra...)
+	if _, err := runCamsnap(t, args...); err != nil {
+		t.Fatalf("add %s: %v", name, err)
+	}
+}
+
+func requireFFmpeg(t *testing.T) {
+	t.Helper()
+	if !exec.HasBinary("ffmpeg") {
+		t.Skip("ffmpeg not installed")
+	}
+}
+
+func TestE2EProbe(t *testing.T) {
+	cam := startFakecam(t, fakecam.Config{Username: "admin", Password: "s3cret", FPS: 15, GOP: 5})
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	addFakecam(t, cfgPath, "fake", cam.URL())
+
+	out, err := runCamsnap(t, "--config", cfgPath, "probe", "fake", "--window", "1500ms", "--output", "json")
+	if err != nil {
+		t.Fatalf("probe: %v\n%s", err, out)
+	}
+	var rec probeRecord
+	if err := json.Unmarshal([]byte(out), &rec); err != nil {
+		t.Fatalf("decode: %v\n%s", err, out)
+	}
+	if strings.Contains(out, "s3cret") {
+		t.Fatal("password in probe output")
+	}
+	v := rec.Video
+	if len(rec.Medias) != 1 || rec.Medias[0].Formats[0].Codec != "H264" || v == nil || v.Width != 320 || v.GOPFrames != 5 {
+		t.Fatalf("unexpected probe: %+v %+v", rec.Medias, v)
+	}
+}
+
+func TestE2EDoctorClassifiesFakecamFailures(t *testing.T) {
+	cam := startFakecam(t, fakecam.Config{Username: "admin", Password: "s3cret", MaxSessions: 1, Transport: "tcp"})
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	base := config.Camera{Host: "127.0.0.1", Port: cam.Port(), Protocol: "rtsp", Username: "admin", Password: "s3cret", RTSPClient: "gortsplib", RTSPTransport: "tcp", Path: "/stream1"}
+	wrongPass, wrongPath, udp := base, base, base
+	wrongPass.Name, wrongPass.Password = "wrong-pass", "nope"
+	wrongPath.Name, wrongPath.Path = "wrong-path", "/stream9"
+	udp.Name, udp.RTSPTransport = "udp-only-client", "udp"
+	if err := config.Save(cfgPath, config.Config{Cameras: []config.Camera{wrongPass, wrongPath, udp}}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	out, err := runCamsnap(t, "--config", cfgPath, "doctor", "--probe", "--timeout", "2s", "--output", "json")
+	if err != nil {
+		t.Fatalf("doctor: %v", err)
+	}
+	var report doctorReport
+	if err := json.Unmarshal([]byte(out), &report); err != nil {
+		t.Fatalf("decode: %v\n%s", err, out)
+	}
+	want := map[string]string{"wrong-pass": "auth", "wrong-path": "not-found", "udp-only-client": "unknown"}
+	for _, c := range report.Cameras {
+		if !c.Reachable || c.Probe == nil || c.Probe.OK || c.Probe.Class != want[c.Name] {
+			t.Fatalf("%s: %+v %+v", c.Name, c, c.Probe)
+		}
+	}
+}
+
+func TestE2ESnapClipDoctor(t *testing.T) {
+	requireFFmpeg(t)
+	cam := startFakecam(t, fakecam.Config{Username: "admin", Password: "s3cret", Auth: "digest"})
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	addFakecam(t, cfgPath, "fake", cam.URL())
+	dir := t.TempDir()
+
+	for _, client := range []string{"ffmpeg", "gortsplib"} {
+		shot := filepath.Join(dir, client+".jpg")
+		if out, err := runCamsnap(t, "--config", cfgPath, "snap", "fake", "--rtsp-client", client, "--out", shot); err != nil {
+			t.Fatalf("snap %s: %v\n%s", client, err, out)
+		}
+		if data, err := os.ReadFile(shot); err != nil || len(data) < 4 || data[0] != 0xff || data[1] != 0xd8 {
+			t.Fatalf("snap %s: not a JPEG (%v)", client, err)
+		}
+	}
+
+	burst := filepath.Join(dir, "burst-{seq}.jpg")
+	if out, err := runCamsnap(t, "--config", cfgPath, "snap", "fake", "--count", "3", "--every", "500ms", "--out", burst); err != nil {
+		t.Fatalf("snap burst: %v\n%s", err, out)
+	}
+	for seq := 1; seq <= 3; seq++ {
+		frame := filepath.Join(dir, fmt.Sprintf("burst-%06d.jpg", seq))
+		if data, err := os.ReadFile(frame); err != nil || len(data) < 4 || data[0] != 0xff || data[1] != 0xd8 {
+			t.Fatalf("burst frame %d: not a JPEG (%v)", seq, err)
+		}
+	}
+
+	clip := filepath.Join(dir, "clip.mp4")
+	if out, err := runCamsnap(t, "--config", cfgPath, "clip", "fake", "--dur", "2s", "--no-audio", "--out", clip); err != nil {
+		t.Fatalf("clip: %v\n%s", err, out)
+	}
+	if info, err := os.Stat(clip); err != nil || info.Size() == 0 {
+		t.Fatalf("clip not written: %v", err)
+	}
+
+	out, err := runCamsnap(t, "--config", cfgPath, "doctor", "--probe", "--output", "json")
+	if err != nil {
+		t.Fatalf("doctor: %v", err)
+	}
+	var report doctorReport
+	if err := json.Unmarshal([]byte(out), &report); err != nil {
+		t.Fatalf("decode: %v\n%s", err, out)
+	}
+	if p := report.Cameras[0].Probe; p == nil || !p.OK {
+		t.Fatalf("doctor probe: %+v", p)
+	}
+}
+
+func TestE2EWatchMotionBursts(t *testing.T) {
+	requireFFmpeg(t)
+	cam := startFakecam(t, fakecam.Config{MotionEvery: 1500 * time.Millisecond, MotionFor: 500 * time.Millisecond})
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	addFakecam(t, cfgPath, "fake", cam.URL())
+
+	out, err := runCamsnap(t, "--config", cfgPath, "watch", "fake", "--action", "true", "--json", "--threshold", "0.2", "--cooldown", "0", "--duration", "5s")
+	if err != nil {
+		t.Fatalf("watch: %v", err)
+	}
+	if !strings.Contains(out, `"event":"motion"`) {
+		t.Fatalf("no motion from bursts:\n%s", out)
+	}
+}
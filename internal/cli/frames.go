@@ -0,0 +1,168 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/steipete/camsnap/internal/exec"
+	"github.com/steipete/camsnap/internal/httpcam"
+)
+
+// frameSeries is a run of frames: count of them, one every interval, named from a template.
+type frameSeries struct {
+	tmpl  string // output path with {camera}, {time} and {seq} placeholders
+	every time.Duration
+	count int
+	first int // {seq} of the first frame
+}
+
+// frameName fills in the placeholders of an output template.
+func frameName(tmpl, camera string, seq int, t time.Time) string {
+	return strings.NewReplacer(
+		"{camera}", camera,
+		"{time}", t.Format("20060102-150405"),
+		"{seq}", fmt.Sprintf("%06d", seq),
+	).Replace(tmpl)
+}
+
+// withSeq adds a {seq} before the extension of a template that has none, so frames never
+// overwrite each other.
+func withSeq(tmpl string) string {
+	if strings.Contains(tmpl, "{seq}") {
+		return tmpl
+	}
+	ext := filepath.Ext(tmpl)
+	return strings.TrimSuffix(tmpl, ext) + "-{seq}" + ext
+}
+
+// snapBurst writes count frames, one every interval, and prints each path as it lands.
+func snapBurst(cmd *cobra.Command, src captureSource, img exec.Image, outPath string, count int, every, timeout, ioTimeout time.Duration) error {
+	if outPath == "" {
+		dir, err := os.MkdirTemp("", "camsnap-burst-*")
+		if err != nil {
+			return fmt.Errorf("create temp dir: %w", err)
+		}
+		outPath = filepath.Join(dir, "{camera}-{seq}"+exec.ImageExt(img.Format))
+		cmd.Printf("No --out provided, writing frames to %s\n", dir)
+	}
+	// the deadline covers the whole burst; --timeout is the slack on top
+	deadline := time.Duration(0)
+	if timeout > 0 {
+		deadline = timeout + time.Duration(count-1)*every
+	}
+	ctx, cancel := exec.WithTimeout(context.Background(), deadline)
+	defer cancel()
+
+	series := frameSeries{tmpl: withSeq(outPath), every: every, count: count, first: 1}
+	_, err := captureFrames(ctx, src, img, series, timeout, ioTimeout, func(path string) {
+		cmd.Println(path)
+	})
+	return err
+}
+
+// captureFrames writes the frames of s and calls onFrame with each path. RTSP sources keep one
+// ffmpeg session open that picks a frame every interval (ffmpeg client only); HTTP sources fetch a
+// snapshot each interval. It returns how many frames were written, also on failure.
+func captureFrames(ctx context.Context, src captureSource, img exec.Image, s frameSeries, timeout, ioTimeout time.Duration, onFrame func(string)) (int, error) {
+	if httpcam.IsHTTPSource(src.cam) {
+		return pollHTTPFrames(ctx, src, img, s, timeout, onFrame)
+	}
+	caps := media.Caps(ctx)
+	img, err := img.Resolve(s.tmpl, caps)
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case img.Format == "avif":
+		return 0, fmt.Errorf("bursts write jpeg, png or webp; AVIF needs one ffmpeg run per frame")
+	case src.client == "gortsplib":
+		return 0, fmt.Errorf("bursts keep one ffmpeg session open; use --rtsp-client ffmpeg")
+	case !caps.HasFilter("fps"):
+		return 0, fmt.Errorf("this ffmpeg build has no fps filter")
+	}
+	if err := caps.CheckRTSPTransport(src.transport); err != nil {
+		return 0, err
+	}
+	ffArgs := []string{"-y", "-rtsp_transport", src.transport}
+	ffArgs = append(ffArgs, caps.RTSPTimeoutArgs(ioTimeout)...)
+	ffArgs = append(ffArgs, "-i", src.url)
+	ffArgs = append(ffArgs, img.BurstArgs(s.every, s.count)...)
+	ffArgs = append(ffArgs, "pipe:1")
+
+	// ffmpeg writes the images back to back; each is saved as soon as it is complete
+	pr, pw := io.Pipe()
+	written := 0
+	saved := make(chan error, 1)
+	go func() {
+		sc := bufio.NewScanner(pr)
+		sc.Buffer(make([]byte, 256*1024), 64*1024*1024)
+		sc.Split(exec.ScanImages(img.Format))
+		var err error
+		for sc.Scan() {
+			path := frameName(s.tmpl, src.cam.Name, s.first+written, time.Now())
+			if err = writeFrame(path, sc.Bytes()); err != nil {
+				break
+			}
+			written++
+			onFrame(path)
+		}
+		if err == nil {
+			err = sc.Err()
+		}
+		// keep ffmpeg from blocking on a full pipe
+		_, _ = io.Copy(io.Discard, pr)
+		saved <- err
+	}()
+	err = exec.RunRTSP(ctx, media, caps, exec.RTSPOptions{Stdout: pw}, ffArgs...)
+	_ = pw.Close()
+	if serr := <-saved; err == nil {
+		err = serr
+	}
+	if err == nil && written < s.count {
+		err = fmt.Errorf("the stream ended after %d of %d frames", written, s.count)
+	}
+	return written, err
+}
+
+// pollHTTPFrames fetches a snapshot every interval.
+func pollHTTPFrames(ctx context.Context, src captureSource, img exec.Image, s frameSeries, timeout time.Duration, onFrame func(string)) (int, error) {
+	ticker := time.NewTicker(s.every)
+	defer ticker.Stop()
+	written := 0
+	for written < s.count {
+		if written > 0 {
+			select {
+			case <-ctx.Done():
+				return written, fmt.Errorf("capture stopped after %d of %d frames: %w", written, s.count, ctx.Err())
+			case <-ticker.C:
+			}
+		}
+		path := frameName(s.tmpl, src.cam.Name, s.first+written, time.Now())
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return written, fmt.Errorf("create frame dir: %w", err)
+		}
+		if err := snapHTTP(src.cam, path, img, timeout); err != nil {
+			return written, err
+		}
+		written++
+		onFrame(path)
+	}
+	return written, nil
+}
+
+func writeFrame(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create frame dir: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("write frame: %w", err)
+	}
+	return nil
+}
@@ -2,11 +2,20 @@ package cli
 
 import (
 	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/steipete/camsnap/internal/config"
+	"github.com/steipete/camsnap/internal/exec"
+	"github.com/steipete/camsnap/internal/rtsp"
 )
 
+// media runs ffmpeg for snap, clip, watch, setup and doctor; tests swap in an exectest.Fake.
+var media exec.Runner = exec.FFmpeg{}
+
 func loadConfig(pathFlag string) (config.Config, string, error) {
 	var path string
 	var err error
@@ -36,7 +45,7 @@ func findCamera(cfg config.Config, name string) (config.Camera, bool) {
 	return config.FindCamera(cfg, name)
 }
 
-// appendStream swaps the path to the desired stream if provided.
+// appendStream swaps the path to the desired stream if provided; a query string is kept.
 func appendStream(baseURL, stream string) string {
 	if stream == "" {
 		return baseURL
@@ -44,29 +53,51 @@ func appendStream(baseURL, stream string) string {
 	if stream[0] != '/' {
 		stream = "/" + stream
 	}
+	query := ""
+	if i := strings.IndexByte(baseURL, '?'); i >= 0 {
+		baseURL, query = baseURL[:i], baseURL[i:]
+	}
 	// replace last path segment
 	for i := len(baseURL) - 1; i >= 0; i-- {
 		if baseURL[i] == '/' {
-			return baseURL[:i] + stream
+			return baseURL[:i] + stream + query
 		}
 	}
-	return baseURL + stream
+	return baseURL + stream + query
 }
 
-// appendPath replaces the trailing path of an RTSP URL with a custom path.
-func appendPath(baseURL, path string) string {
-	if path == "" {
-		return baseURL
+// presetSubstream maps --stream for a vendor preset camera: main and sub pick the preset's paths;
+// anything else would replace part of a multi-segment vendor path.
+func presetSubstream(cam config.Camera, stream string) (bool, error) {
+	switch strings.ToLower(stream) {
+	case "":
+		return cam.Substream, nil
+	case "main":
+		return false, nil
+	case "sub":
+		return true, nil
 	}
-	if path[0] != '/' {
-		path = "/" + path
-	}
-	for i := len(baseURL) - 1; i >= 0; i-- {
-		if baseURL[i] == '/' {
-			return baseURL[:i] + path
+	return false, fmt.Errorf("--stream %q does not fit the %s preset; use --stream main|sub, or --path for a custom path", stream, cam.Vendor)
+}
+
+// streamURL is the RTSP URL for a camera's stream settings: a custom path as is, a vendor preset's
+// main or sub path, else cam.Stream as the last path segment.
+func streamURL(cam config.Camera) (string, error) {
+	if cam.Path == "" && cam.Vendor != "" {
+		sub, err := presetSubstream(cam, cam.Stream)
+		if err != nil {
+			return "", err
 		}
+		cam.Substream = sub
+	}
+	url, err := rtsp.BuildURL(cam)
+	if err != nil {
+		return "", err
+	}
+	if cam.Path == "" && cam.Vendor == "" {
+		url = appendStream(url, cam.Stream)
 	}
-	return baseURL + path
+	return url, nil
 }
 
 // loadConfigFromFlag reads the persistent config flag off a command and loads the config.
@@ -81,3 +112,34 @@ func loadConfigFromFlag(cmd *cobra.Command) (config.Config, string, error) {
 	}
 	return cfg, path, nil
 }
+
+// redactSecret masks a password, raw or URL-escaped, wherever it appears in s.
+func redactSecret(s, secret string) string {
+	if secret == "" {
+		return s
+	}
+	for _, v := range []string{url.UserPassword("", secret).String()[1:], url.QueryEscape(secret), secret} {
+		s = strings.ReplaceAll(s, v, "***")
+	}
+	return s
+}
+
+// tempOutput creates an empty temp file named like pattern (e.g. "camsnap-*.jpg") and returns its path.
+func tempOutput(pattern string) (string, error) {
+	tmp, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", fmt.Errorf("create temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("close temp file: %w", err)
+	}
+	return tmp.Name(), nil
+}
+
+// messages is where a command's human output goes: stderr when stdout carries data (--out -).
+func messages(cmd *cobra.Command, toStdout bool) io.Writer {
+	if toStdout {
+		return cmd.ErrOrStderr()
+	}
+	return cmd.OutOrStdout()
+}
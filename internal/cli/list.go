@@ -5,13 +5,36 @@ import (
 	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/steipete/camsnap/internal/config"
 )
 
+// cameraRecord is a saved camera as printed by list --output json|yaml. Passwords and paths are
+// never included; a UniFi Protect path is the stream's access token.
+type cameraRecord struct {
+	Name      string `json:"name" yaml:"name"`
+	Host      string `json:"host" yaml:"host"`
+	Port      int    `json:"port" yaml:"port"`
+	Protocol  string `json:"protocol" yaml:"protocol"`
+	Source    string `json:"source,omitempty" yaml:"source,omitempty"`
+	Username  string `json:"username,omitempty" yaml:"username,omitempty"`
+	Vendor    string `json:"vendor,omitempty" yaml:"vendor,omitempty"`
+	XAddr     string `json:"xaddr,omitempty" yaml:"xaddr,omitempty"`
+	Endpoint  string `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	Transport string `json:"transport,omitempty" yaml:"transport,omitempty"`
+	Stream    string `json:"stream,omitempty" yaml:"stream,omitempty"`
+	Profile   string `json:"profile,omitempty" yaml:"profile,omitempty"`
+}
+
 func newListCmd() *cobra.Command {
+	var output string
 	cmd := &cobra.Command{
 		Use:   "list",
 		Short: "List saved cameras",
 		RunE: func(cmd *cobra.Command, _ []string) error {
+			format, err := parseOutput(output)
+			if err != nil {
+				return err
+			}
 			sty := newStyler(cmd.OutOrStdout())
 			cfgFlag, err := configPathFlag(cmd)
 			if err != nil {
@@ -21,24 +44,56 @@ func newListCmd() *cobra.Command {
 			if err != nil {
 				return err
 			}
+			// deterministic order
+			sort.Slice(cfg.Cameras, func(i, j int) bool { return cfg.Cameras[i].Name < cfg.Cameras[j].Name })
+
+			if format != outputTable {
+				records := make([]cameraRecord, 0, len(cfg.Cameras))
+				for _, cam := range cfg.Cameras {
+					records = append(records, newCameraRecord(cam))
+				}
+				return writeStructured(cmd.OutOrStdout(), format, records)
+			}
 			if len(cfg.Cameras) == 0 {
 				cmd.Println(sty.Warn("No cameras saved. Add one with: camsnap add --name cam1 --host 192.168.1.50 --user tapo --pass secret"))
 				return nil
 			}
-			// deterministic order
-			sort.Slice(cfg.Cameras, func(i, j int) bool { return cfg.Cameras[i].Name < cfg.Cameras[j].Name })
-
 			for _, cam := range cfg.Cameras {
 				// avoid printing password
 				auth := cam.Username
 				if auth != "" && cam.Password != "" {
 					auth += ":***"
 				}
-				cmd.Printf("%-12s host=%s port=%d proto=%s auth=%s\n",
-					cam.Name, cam.Host, cam.Port, strings.ToLower(cam.Protocol), auth)
+				vendor := ""
+				if cam.Vendor != "" {
+					vendor = " vendor=" + cam.Vendor
+				}
+				cmd.Printf("%-12s host=%s port=%d proto=%s auth=%s%s\n",
+					cam.Name, cam.Host, cam.Port, strings.ToLower(cam.Protocol), auth, vendor)
 			}
 			return nil
 		},
 	}
+	addOutputFlag(cmd, &output)
 	return cmd
 }
+
+func newCameraRecord(cam config.Camera) cameraRecord {
+	r := cameraRecord{
+		Name:      cam.Name,
+		Host:      cam.Host,
+		Port:      cam.Port,
+		Protocol:  strings.ToLower(cam.Protocol),
+		Source:    cam.Source,
+		Username:  cam.Username,
+		Vendor:    cam.Vendor,
+		XAddr:     cam.ONVIF,
+		Endpoint:  cam.Endpoint,
+		Transport: cam.RTSPTransport,
+		Stream:    cam.Stream,
+	}
+	if len(cam.Profiles) > 0 {
+		r.Profile = cam.Profiles[0].Name
+	}
+	return r
+}
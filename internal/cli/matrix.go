@@ -0,0 +1,209 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/steipete/camsnap/internal/camerr"
+	"github.com/steipete/camsnap/internal/config"
+	"github.com/steipete/camsnap/internal/exec"
+	"gopkg.in/yaml.v3"
+)
+
+// matrixRun is one transport/client/stream combination timed by doctor --matrix.
+type matrixRun struct {
+	Transport string  `json:"transport" yaml:"transport"`
+	Client    string  `json:"client" yaml:"client"`
+	Stream    string  `json:"stream" yaml:"stream"`
+	Runs      int     `json:"runs" yaml:"runs"`
+	OK        int     `json:"ok" yaml:"ok"`
+	LatencyMS float64 `json:"latency_ms,omitempty" yaml:"latency_ms,omitempty"` // mean time to first frame of the successful runs
+	Class     string  `json:"class,omitempty" yaml:"class,omitempty"`           // last failure
+	Current   bool    `json:"current,omitempty" yaml:"current,omitempty"`       // the camera's saved settings
+	Best      bool    `json:"best,omitempty" yaml:"best,omitempty"`
+
+	camera config.Camera
+}
+
+// Reliable reports whether every run produced a frame.
+func (r matrixRun) Reliable() bool { return r.Runs > 0 && r.OK == r.Runs }
+
+func (r matrixRun) String() string {
+	return r.Transport + "/" + r.Client + "/" + r.Stream
+}
+
+// effectiveRTSP returns the transport and client snap would use for cam; override replaces the transport.
+func effectiveRTSP(cam config.Camera, override string) (string, string) {
+	transport := override
+	if transport == "" {
+		transport = cam.RTSPTransport
+	}
+	if transport == "" {
+		transport = "tcp"
+	}
+	client := cam.RTSPClient
+	if client == "" {
+		client = "ffmpeg"
+	}
+	return strings.ToLower(transport), strings.ToLower(client)
+}
+
+// grabTestFrame captures one frame to a temp file and discards it.
+func grabTestFrame(cam config.Camera, url, transport, client string, timeout time.Duration) error {
+	tmp, err := os.CreateTemp("", "camsnap-doctor-*.jpg")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	_ = tmp.Close()
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+	ctx, cancel := exec.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return grabFrame(ctx, url, transport, client, cam.RTSPCodec, tmp.Name(), exec.Image{}, timeout, defaultRTSPTimeout)
+}
+
+// runMatrix times every combination setup would try for cam and returns the runs in order, plus the
+// fastest reliable one (nil if none is reliable).
+func runMatrix(cam config.Camera, opts doctorOptions) ([]matrixRun, *matrixRun) {
+	curTransport, curClient := effectiveRTSP(cam, "")
+	curURL, _ := streamURL(cam)
+	attemptTimeout := opts.timeout + 8*time.Second
+
+	tmp, err := os.CreateTemp("", "camsnap-doctor-*.jpg")
+	if err != nil {
+		return nil, nil
+	}
+	_ = tmp.Close()
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+
+	var runs []matrixRun
+	for _, a := range setupAttempts(cam) {
+		r := matrixRun{Transport: a.Transport, Client: a.Client, Stream: a.Stream, camera: a.Camera}
+		if u, err := streamURL(a.Camera); err == nil && u == curURL && a.Transport == curTransport && a.Client == curClient {
+			r.Current = true
+			// keep the saved camera as is so --fix never rewrites it with equivalent values
+			r.camera = cam
+		}
+		var total time.Duration
+		for i := 0; i < opts.runs; i++ {
+			r.Runs++
+			start := time.Now()
+			if err := trySnapshot(a, tmp.Name(), attemptTimeout); err != nil {
+				r.Class = camerr.Class(err)
+				if i == 0 {
+					// a combination that fails outright is not worth a second wait
+					break
+				}
+				continue
+			}
+			r.OK++
+			total += time.Since(start)
+		}
+		if r.OK > 0 {
+			r.LatencyMS = milliseconds(total / time.Duration(r.OK))
+		}
+		runs = append(runs, r)
+	}
+
+	best := -1
+	for i, r := range runs {
+		if r.Reliable() && (best < 0 || r.LatencyMS < runs[best].LatencyMS) {
+			best = i
+		}
+	}
+	// keep the saved settings when they are within 10% of the fastest; timings jitter
+	for i, r := range runs {
+		if best >= 0 && r.Current && r.Reliable() && r.LatencyMS <= runs[best].LatencyMS*1.1 {
+			best = i
+		}
+	}
+	if best < 0 {
+		return runs, nil
+	}
+	runs[best].Best = true
+	return runs, &runs[best]
+}
+
+func printMatrix(cmd *cobra.Command, sty styler, name string, runs []matrixRun, best *matrixRun) {
+	cmd.Printf("  %s matrix:\n", name)
+	for _, r := range runs {
+		mark := sty.OK("✔")
+		detail := fmt.Sprintf("%d/%d  %.0fms", r.OK, r.Runs, r.LatencyMS)
+		switch {
+		case r.OK == 0:
+			mark = sty.Err("✖")
+			detail = fmt.Sprintf("%d/%d  %s", r.OK, r.Runs, r.Class)
+		case !r.Reliable():
+			mark = sty.Warn("!")
+			detail += "  flaky: " + r.Class
+		}
+		var notes []string
+		if r.Current {
+			notes = append(notes, "current")
+		}
+		if r.Best {
+			notes = append(notes, "fastest")
+		}
+		if len(notes) > 0 {
+			detail += "  (" + strings.Join(notes, ", ") + ")"
+		}
+		cmd.Printf("    %s %-28s %s\n", mark, r, detail)
+	}
+	if best == nil {
+		cmd.Printf("  %s %s no reliable combination\n", sty.Err("✖"), name)
+	}
+}
+
+// cameraDiff lists the config lines that change between two camera entries, as "-"/"+" pairs.
+func cameraDiff(before, after config.Camera) []string {
+	b, a := cameraLines(before), cameraLines(after)
+	inBefore := map[string]bool{}
+	for _, l := range b {
+		inBefore[l] = true
+	}
+	inAfter := map[string]bool{}
+	for _, l := range a {
+		inAfter[l] = true
+	}
+	var diff []string
+	for _, l := range b {
+		if !inAfter[l] {
+			diff = append(diff, "- "+l)
+		}
+	}
+	for _, l := range a {
+		if !inBefore[l] {
+			diff = append(diff, "+ "+l)
+		}
+	}
+	return diff
+}
+
+// cameraLines renders the scalar settings of a camera as YAML lines; secrets and profiles are left out.
+func cameraLines(cam config.Camera) []string {
+	cam.Password = ""
+	cam.Profiles = nil
+	data, err := yaml.Marshal(cam)
+	if err != nil {
+		return nil
+	}
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+func printChanges(cmd *cobra.Command, sty styler, name string, changes []string) {
+	cmd.Printf("  %s %s fixed:\n", sty.OK("✔"), name)
+	for _, l := range changes {
+		if strings.HasPrefix(l, "-") {
+			cmd.Printf("    %s\n", sty.Err(l))
+		} else {
+			cmd.Printf("    %s\n", sty.OK(l))
+		}
+	}
+}
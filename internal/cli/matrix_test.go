@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/steipete/camsnap/internal/config"
+)
+
+func TestCameraDiff(t *testing.T) {
+	before := config.Camera{Name: "kitchen", Host: "10.0.0.2", Port: 554, Protocol: "rtsp", Username: "tapo", Password: "secret", RTSPTransport: "udp"}
+	after := before
+	after.RTSPTransport, after.RTSPClient, after.Stream = "tcp", "gortsplib", "stream2"
+
+	got := strings.Join(cameraDiff(before, after), "\n")
+	want := "- rtsp_transport: udp\n+ rtsp_transport: tcp\n+ stream: stream2\n+ rtsp_client: gortsplib"
+	if got != want {
+		t.Fatalf("diff:\n%s\nwant:\n%s", got, want)
+	}
+	if strings.Contains(got, "secret") {
+		t.Fatal("password in diff")
+	}
+	if d := cameraDiff(before, before); len(d) != 0 {
+		t.Fatalf("expected no diff, got %v", d)
+	}
+}
+
+func TestEffectiveRTSP(t *testing.T) {
+	cases := []struct {
+		cam       config.Camera
+		override  string
+		transport string
+		client    string
+	}{
+		{config.Camera{}, "", "tcp", "ffmpeg"},
+		{config.Camera{RTSPTransport: "udp", RTSPClient: "gortsplib"}, "", "udp", "gortsplib"},
+		{config.Camera{RTSPTransport: "udp"}, "tcp", "tcp", "ffmpeg"},
+	}
+	for _, tc := range cases {
+		transport, client := effectiveRTSP(tc.cam, tc.override)
+		if transport != tc.transport || client != tc.client {
+			t.Fatalf("%+v override %q: got %s/%s", tc.cam, tc.override, transport, client)
+		}
+	}
+}
+
+func TestSetupStreamsKeepsCustomPath(t *testing.T) {
+	cam := config.Camera{Host: "192.168.1.1", Port: 7447, Path: "/Bfy47SNWz9n2WRrw"}
+	streams := setupStreams(cam)
+	if len(streams) != 1 || streams[0].Camera.Path != cam.Path {
+		t.Fatalf("custom path replaced: %+v", streams)
+	}
+	u, err := streamURL(streams[0].Camera)
+	if err != nil || u != "rtsp://192.168.1.1:7447/Bfy47SNWz9n2WRrw" {
+		t.Fatalf("url %q %v", u, err)
+	}
+}
@@ -0,0 +1,151 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/steipete/camsnap/internal/config"
+	"github.com/steipete/camsnap/internal/discovery"
+	"github.com/steipete/camsnap/internal/hostport"
+	"github.com/steipete/camsnap/internal/rtsp"
+)
+
+// onvifDeviceURL returns the conventional ONVIF device service address for a host.
+func onvifDeviceURL(host string, port int) string {
+	if port == 0 {
+		port = 80
+	}
+	return "http://" + hostport.URLHost(host, port) + "/onvif/device_service"
+}
+
+// applyONVIFProfiles fetches the camera's media profiles and points it at the selected one
+// (by name, token or 1-based index; empty picks the first). keepPort keeps cam.Port, e.g. an
+// explicit --port for a port-forwarded camera, instead of the ports in the stream URIs.
+func applyONVIFProfiles(ctx context.Context, cam config.Camera, xaddr, selector string, keepPort bool) (config.Camera, error) {
+	mediaProfiles, err := discovery.FetchProfiles(ctx, xaddr, cam.Username, cam.Password)
+	if err != nil {
+		return cam, fmt.Errorf("onvif profiles: %w", err)
+	}
+	if len(mediaProfiles) == 0 {
+		return cam, fmt.Errorf("onvif: device reports no media profiles")
+	}
+
+	var profiles []config.Profile
+	for _, mp := range mediaProfiles {
+		parsed, err := rtsp.ParseURL(mp.StreamURI)
+		if err != nil {
+			continue
+		}
+		// the device knows its RTSP port better than our 554 default, and profiles may differ
+		port := parsed.Port
+		if keepPort {
+			port = 0
+		}
+		profiles = append(profiles, config.Profile{
+			Name:        mp.Name,
+			Token:       mp.Token,
+			Port:        port,
+			Protocol:    parsed.Protocol,
+			Path:        parsed.Path,
+			Query:       parsed.Query,
+			Codec:       codecFromEncoding(mp.Encoding),
+			Width:       mp.Width,
+			Height:      mp.Height,
+			FPS:         mp.FPS,
+			SnapshotURL: mp.SnapshotURI,
+		})
+	}
+	if len(profiles) == 0 {
+		return cam, fmt.Errorf("onvif: no profile has an RTSP stream URI")
+	}
+	cam.ONVIF = xaddr
+	cam.Profiles = profiles
+	// the endpoint UUID lets discover --listen follow the camera across DHCP changes
+	if id, err := discovery.FetchEndpointReference(ctx, xaddr, cam.Username, cam.Password); err == nil && id != "" {
+		cam.Endpoint = id
+	}
+	return selectProfile(cam, selector)
+}
+
+// selectProfile applies a stored ONVIF profile's port, protocol, path, query and codec to cam.
+func selectProfile(cam config.Camera, selector string) (config.Camera, error) {
+	if len(cam.Profiles) == 0 {
+		if selector != "" {
+			return cam, fmt.Errorf("camera %q has no ONVIF profiles (add it with --onvif)", cam.Name)
+		}
+		return cam, nil
+	}
+	p, ok := findProfile(cam.Profiles, selector)
+	if !ok {
+		names := make([]string, 0, len(cam.Profiles))
+		for _, p := range cam.Profiles {
+			names = append(names, p.Name)
+		}
+		return cam, fmt.Errorf("profile %q not found (have %s)", selector, strings.Join(names, ", "))
+	}
+	// profiles stored before port and protocol were recorded keep the camera's
+	if p.Port != 0 {
+		cam.Port = p.Port
+	}
+	if p.Protocol != "" {
+		cam.Protocol = p.Protocol
+	}
+	cam.Path = p.Path
+	cam.Query = p.Query
+	cam.Stream = ""
+	// auto already handles H.264, and a codec left over from another profile would be wrong
+	cam.RTSPCodec = p.Codec
+	if p.Codec == "h264" {
+		cam.RTSPCodec = ""
+	}
+	return cam, nil
+}
+
+func findProfile(profiles []config.Profile, selector string) (config.Profile, bool) {
+	if selector == "" {
+		return profiles[0], true
+	}
+	for _, p := range profiles {
+		if strings.EqualFold(p.Name, selector) || p.Token == selector {
+			return p, true
+		}
+	}
+	if i, err := strconv.Atoi(selector); err == nil && i >= 1 && i <= len(profiles) {
+		return profiles[i-1], true
+	}
+	return config.Profile{}, false
+}
+
+func codecFromEncoding(enc string) string {
+	switch strings.ToUpper(enc) {
+	case "H264":
+		return "h264"
+	case "H265", "HEVC":
+		return "h265"
+	case "JPEG", "MJPEG":
+		return "mjpeg"
+	default:
+		return ""
+	}
+}
+
+func describeProfile(p config.Profile) string {
+	var parts []string
+	if p.Codec != "" {
+		parts = append(parts, p.Codec)
+	}
+	if p.Width > 0 && p.Height > 0 {
+		parts = append(parts, fmt.Sprintf("%dx%d", p.Width, p.Height))
+	}
+	if p.FPS > 0 {
+		parts = append(parts, fmt.Sprintf("%dfps", p.FPS))
+	}
+	path := p.Path
+	if p.Query != "" {
+		path += "?" + p.Query
+	}
+	parts = append(parts, path)
+	return fmt.Sprintf("%s (%s)", p.Name, strings.Join(parts, ", "))
+}
@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/steipete/camsnap/internal/config"
+	"github.com/steipete/camsnap/internal/discovery"
+	"github.com/steipete/camsnap/internal/discovery/onviftest"
+)
+
+func TestSelectProfile(t *testing.T) {
+	cam := config.Camera{
+		Name:   "door",
+		Stream: "stream1",
+		Profiles: []config.Profile{
+			{Name: "mainStream", Token: "prof0", Port: 554, Protocol: "rtsp", Path: "/h265Preview_01_main", Codec: "h265"},
+			{Name: "subStream", Token: "prof1", Port: 8554, Protocol: "rtsp", Path: "/cam/realmonitor", Query: "channel=1&subtype=1", Codec: "h264"},
+		},
+	}
+
+	got, err := selectProfile(cam, "")
+	if err != nil {
+		t.Fatalf("default profile: %v", err)
+	}
+	if got.Port != 554 || got.Path != "/h265Preview_01_main" || got.Stream != "" || got.RTSPCodec != "h265" {
+		t.Fatalf("default profile applied wrong: %+v", got)
+	}
+
+	for _, sel := range []string{"substream", "prof1", "2"} {
+		got, err := selectProfile(cam, sel)
+		if err != nil {
+			t.Fatalf("select %q: %v", sel, err)
+		}
+		if got.Port != 8554 || got.Path != "/cam/realmonitor" || got.Query != "channel=1&subtype=1" || got.RTSPCodec != "" {
+			t.Fatalf("select %q applied wrong: %+v", sel, got)
+		}
+	}
+
+	// switching from the H.265 main profile must not keep its codec
+	prev := cam
+	prev.RTSPCodec = "h265"
+	if got, err := selectProfile(prev, "subStream"); err != nil || got.RTSPCodec != "" {
+		t.Fatalf("switch to h264 profile kept codec: %+v, %v", got, err)
+	}
+
+	if _, err := selectProfile(cam, "3"); err == nil {
+		t.Fatalf("expected error for out-of-range profile")
+	}
+	if _, err := selectProfile(config.Camera{Name: "bare"}, "main"); err == nil {
+		t.Fatalf("expected error for camera without profiles")
+	}
+}
+
+func TestOnvifAddFlags(t *testing.T) {
+	if got := onvifAddFlags("192.168.1.50:2020"); got != "--host 192.168.1.50 --onvif --onvif-port 2020" {
+		t.Fatalf("got %q", got)
+	}
+	if got := onvifAddFlags("192.168.1.50"); got != "--host 192.168.1.50 --onvif" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestReportAnnouncementFollowsIPChange(t *testing.T) {
+	cfg := config.Config{Cameras: []config.Camera{
+		{Name: "porch", Host: "192.168.1.50", Endpoint: "3fa1c2d4", ONVIF: "http://192.168.1.50:2020/onvif/device_service"},
+		{Name: "garage", Host: "192.168.1.60"},
+	}}
+	hello := discovery.Announcement{Kind: discovery.Hello, Device: discovery.Device{
+		Endpoint: "3fa1c2d4",
+		Address:  "http://192.168.1.77:2020/onvif/device_service",
+		Host:     "192.168.1.77:2020",
+	}}
+	cmd := &cobra.Command{}
+	var buf bytes.Buffer
+	cmd.SetOut(&buf)
+	sty := newStyler(&buf)
+
+	if _, changed := reportAnnouncement(cmd, sty, cfg, hello, false); changed {
+		t.Fatalf("without --update the config must not change")
+	}
+	if !strings.Contains(buf.String(), "porch moved 192.168.1.50 -> 192.168.1.77") {
+		t.Fatalf("expected move notice, got %q", buf.String())
+	}
+
+	out, changed := reportAnnouncement(cmd, sty, cfg, hello, true)
+	if !changed || out.Cameras[0].Host != "192.168.1.77" || out.Cameras[0].ONVIF != hello.Device.Address {
+		t.Fatalf("expected porch to follow the new address: %+v", out.Cameras[0])
+	}
+
+	// a camera saved without an endpoint learns it from a Hello at its current host
+	learn := discovery.Announcement{Kind: discovery.Hello, Device: discovery.Device{
+		Endpoint: "77aa", Address: "http://192.168.1.60/onvif/device_service", Host: "192.168.1.60",
+	}}
+	out, changed = reportAnnouncement(cmd, sty, out, learn, true)
+	if !changed || out.Cameras[1].Endpoint != "77aa" || out.Cameras[1].Host != "192.168.1.60" {
+		t.Fatalf("expected garage to learn its endpoint: %+v", out.Cameras[1])
+	}
+}
+
+func TestApplyONVIFProfilesKeepsExplicitPort(t *testing.T) {
+	srv := onviftest.NewServer()
+	defer srv.Close()
+
+	cam := config.Camera{Name: "door", Host: "127.0.0.1", Port: 10554}
+	got, err := applyONVIFProfiles(context.Background(), cam, srv.DeviceURL(), "", true)
+	if err != nil {
+		t.Fatalf("applyONVIFProfiles: %v", err)
+	}
+	if got.Port != 10554 {
+		t.Fatalf("explicit --port replaced by the stream URI's: %d", got.Port)
+	}
+	if got, err = selectProfile(got, "2"); err != nil || got.Port != 10554 {
+		t.Fatalf("switching profiles replaced the explicit port: %d, %v", got.Port, err)
+	}
+
+	got, err = applyONVIFProfiles(context.Background(), cam, srv.DeviceURL(), "", false)
+	if err != nil || got.Port != 554 {
+		t.Fatalf("expected the stream URI's port, got %d, %v", got.Port, err)
+	}
+}
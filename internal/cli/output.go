@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats for commands that report records.
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+// addOutputFlag registers --output on a command.
+func addOutputFlag(cmd *cobra.Command, output *string) {
+	cmd.Flags().StringVar(output, "output", outputTable, "Output format: table|json|yaml")
+}
+
+// parseOutput validates an --output value.
+func parseOutput(v string) (string, error) {
+	switch f := strings.ToLower(strings.TrimSpace(v)); f {
+	case "", outputTable:
+		return outputTable, nil
+	case outputJSON, outputYAML:
+		return f, nil
+	default:
+		return "", fmt.Errorf("invalid --output %q (use table|json|yaml)", v)
+	}
+}
+
+// writeStructured encodes v as indented JSON or YAML; field names are part of the CLI contract.
+func writeStructured(w io.Writer, format string, v any) error {
+	switch format {
+	case outputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case outputYAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		return enc.Close()
+	default:
+		return fmt.Errorf("unsupported output format %q", format)
+	}
+}
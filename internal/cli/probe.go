@@ -0,0 +1,255 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/steipete/camsnap/internal/httpcam"
+	"github.com/steipete/camsnap/internal/rtspclient"
+)
+
+// probeRecord is what probe --output json|yaml prints.
+type probeRecord struct {
+	Camera    string       `json:"camera" yaml:"camera"`
+	URL       string       `json:"url" yaml:"url"` // password redacted
+	Transport string       `json:"transport" yaml:"transport"`
+	SDP       string       `json:"sdp" yaml:"sdp"`
+	Medias    []probeMedia `json:"medias" yaml:"medias"`
+	Video     *probeVideo  `json:"video,omitempty" yaml:"video,omitempty"`
+	Error     string       `json:"error,omitempty" yaml:"error,omitempty"` // sampling failed after DESCRIBE
+}
+
+type probeMedia struct {
+	Type        string        `json:"type" yaml:"type"`
+	Control     string        `json:"control,omitempty" yaml:"control,omitempty"`
+	BackChannel bool          `json:"backchannel" yaml:"backchannel"`
+	Formats     []probeFormat `json:"formats" yaml:"formats"`
+}
+
+type probeFormat struct {
+	PayloadType uint8             `json:"payload_type" yaml:"payload_type"`
+	Codec       string            `json:"codec" yaml:"codec"`
+	ClockRate   int               `json:"clock_rate" yaml:"clock_rate"`
+	RTPMap      string            `json:"rtpmap,omitempty" yaml:"rtpmap,omitempty"`
+	FMTP        map[string]string `json:"fmtp,omitempty" yaml:"fmtp,omitempty"`
+	Width       int               `json:"width,omitempty" yaml:"width,omitempty"`
+	Height      int               `json:"height,omitempty" yaml:"height,omitempty"`
+	Profile     string            `json:"profile,omitempty" yaml:"profile,omitempty"`
+	Level       string            `json:"level,omitempty" yaml:"level,omitempty"`
+	SampleRate  int               `json:"sample_rate,omitempty" yaml:"sample_rate,omitempty"`
+	Channels    int               `json:"channels,omitempty" yaml:"channels,omitempty"`
+}
+
+type probeVideo struct {
+	Codec            string  `json:"codec" yaml:"codec"`
+	WindowSeconds    float64 `json:"window_seconds" yaml:"window_seconds"`
+	TimeToKeyframeMS float64 `json:"time_to_keyframe_ms,omitempty" yaml:"time_to_keyframe_ms,omitempty"`
+	Frames           int     `json:"frames" yaml:"frames"`
+	Keyframes        int     `json:"keyframes" yaml:"keyframes"`
+	GOPFrames        float64 `json:"gop_frames,omitempty" yaml:"gop_frames,omitempty"`
+	GOPSeconds       float64 `json:"gop_seconds,omitempty" yaml:"gop_seconds,omitempty"`
+	FPS              float64 `json:"fps" yaml:"fps"`
+	BitrateKbps      float64 `json:"bitrate_kbps" yaml:"bitrate_kbps"`
+	Width            int     `json:"width,omitempty" yaml:"width,omitempty"`
+	Height           int     `json:"height,omitempty" yaml:"height,omitempty"`
+}
+
+func newProbeCmd() *cobra.Command {
+	var window time.Duration
+	var timeout time.Duration
+	var transport string
+	var codec string
+	var output string
+	cmd := &cobra.Command{
+		Use:   "probe <camera>",
+		Short: "Inspect a camera's RTSP stream: SDP, codecs, keyframe timing, GOP, fps and bitrate",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, err := parseOutput(output)
+			if err != nil {
+				return err
+			}
+			cfg, _, err := loadConfigFromFlag(cmd)
+			if err != nil {
+				return err
+			}
+			cam, ok := findCamera(cfg, args[0])
+			if !ok {
+				return fmt.Errorf("camera %q not found", args[0])
+			}
+			if httpcam.IsHTTPSource(cam) {
+				return fmt.Errorf("camera %q is an HTTP %s source; probe inspects RTSP streams", cam.Name, cam.Source)
+			}
+			url, err := streamURL(cam)
+			if err != nil {
+				return err
+			}
+			xport, _ := effectiveRTSP(cam, transport)
+			if _, ok := transportFlag(xport); !ok {
+				return fmt.Errorf("invalid --rtsp-transport (use tcp|udp)")
+			}
+			if codec == "" {
+				codec = cam.RTSPCodec
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), window+timeout)
+			defer cancel()
+			report, err := rtspclient.Probe(ctx, url, xport, codec, window)
+			if err != nil && report.SDP == "" {
+				return err
+			}
+			rec := newProbeRecord(cam.Name, url, xport, report, cam.Password)
+			if err != nil {
+				// DESCRIBE worked; keep the description and report why sampling failed
+				rec.Error = redactSecret(err.Error(), cam.Password)
+			}
+			if format != outputTable {
+				if err := writeStructured(cmd.OutOrStdout(), format, rec); err != nil {
+					return err
+				}
+			} else {
+				printProbe(cmd, newStyler(cmd.OutOrStdout()), rec)
+			}
+			if rec.Error != "" {
+				return fmt.Errorf("probe %s: sampling failed", cam.Name)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().DurationVar(&window, "window", 5*time.Second, "How long to sample the video track (0 = DESCRIBE only)")
+	cmd.Flags().DurationVar(&timeout, "timeout", 10*time.Second, "Connect and DESCRIBE budget on top of --window")
+	cmd.Flags().StringVar(&transport, "rtsp-transport", "", "RTSP transport: tcp|udp (default: the camera's, else tcp)")
+	cmd.Flags().StringVar(&codec, "rtsp-codec", "", "Video track to sample when several are offered: auto|h264|h265|mjpeg")
+	addOutputFlag(cmd, &output)
+	return cmd
+}
+
+// newProbeRecord converts a report; password is scrubbed from the URL, SDP and control URLs, which
+// some cameras echo with credentials.
+func newProbeRecord(name, url, transport string, r rtspclient.ProbeReport, password string) probeRecord {
+	rec := probeRecord{
+		Camera:    name,
+		URL:       redactSecret(url, password),
+		Transport: transport,
+		SDP:       redactSecret(r.SDP, password),
+		Medias:    []probeMedia{},
+	}
+	for _, m := range r.Medias {
+		pm := probeMedia{Type: m.Type, Control: redactSecret(m.Control, password), BackChannel: m.BackChannel, Formats: []probeFormat{}}
+		for _, f := range m.Formats {
+			pm.Formats = append(pm.Formats, probeFormat{
+				PayloadType: f.PayloadType,
+				Codec:       f.Codec,
+				ClockRate:   f.ClockRate,
+				RTPMap:      f.RTPMap,
+				FMTP:        f.FMTP,
+				Width:       f.Width,
+				Height:      f.Height,
+				Profile:     f.Profile,
+				Level:       f.Level,
+				SampleRate:  f.SampleRate,
+				Channels:    f.Channels,
+			})
+		}
+		rec.Medias = append(rec.Medias, pm)
+	}
+	if v := r.Video; v != nil {
+		rec.Video = &probeVideo{
+			Codec:            v.Codec,
+			WindowSeconds:    v.Window.Round(10 * time.Millisecond).Seconds(),
+			TimeToKeyframeMS: milliseconds(v.TimeToKeyframe),
+			Frames:           v.Frames,
+			Keyframes:        v.Keyframes,
+			GOPFrames:        v.GOPFrames,
+			GOPSeconds:       v.GOP.Round(10 * time.Millisecond).Seconds(),
+			FPS:              v.FPS,
+			BitrateKbps:      v.BitrateKbps,
+			Width:            v.Width,
+			Height:           v.Height,
+		}
+	}
+	return rec
+}
+
+func printProbe(cmd *cobra.Command, sty styler, rec probeRecord) {
+	cmd.Printf("%s %s (%s)\n", rec.Camera, rec.URL, rec.Transport)
+	cmd.Println("SDP:")
+	for _, line := range strings.Split(strings.TrimSpace(strings.ReplaceAll(rec.SDP, "\r\n", "\n")), "\n") {
+		cmd.Printf("  %s\n", line)
+	}
+	for i, m := range rec.Medias {
+		label := m.Type
+		if m.BackChannel {
+			label += " backchannel (camera speaker)"
+		}
+		if m.Control != "" {
+			label += " control=" + m.Control
+		}
+		cmd.Printf("Media %d: %s\n", i+1, label)
+		for _, f := range m.Formats {
+			cmd.Printf("  %s\n", describeProbeFormat(f))
+		}
+	}
+	if rec.Error != "" {
+		cmd.Printf("%s sampling failed: %s\n", sty.Err("✖"), rec.Error)
+		return
+	}
+	v := rec.Video
+	if v == nil {
+		return
+	}
+	if v.Frames == 0 {
+		cmd.Printf("%s no %s frames in %.1fs\n", sty.Err("✖"), v.Codec, v.WindowSeconds)
+		return
+	}
+	cmd.Printf("Video %s over %.1fs: %d frames, %d keyframes\n", v.Codec, v.WindowSeconds, v.Frames, v.Keyframes)
+	if v.Width > 0 {
+		cmd.Printf("  in-band SPS: %dx%d\n", v.Width, v.Height)
+	}
+	if v.Keyframes > 0 {
+		cmd.Printf("  first keyframe after %.0fms\n", v.TimeToKeyframeMS)
+	} else {
+		cmd.Printf("  %s no keyframe within the window (raise --window or lower the camera's I-frame interval)\n", sty.Warn("!"))
+	}
+	if v.GOPFrames > 0 {
+		cmd.Printf("  GOP %.0f frames (%.2fs)\n", v.GOPFrames, v.GOPSeconds)
+	} else {
+		cmd.Println("  GOP longer than the window")
+	}
+	cmd.Printf("  %.2f fps, %.0f kbps\n", v.FPS, v.BitrateKbps)
+}
+
+// describeProbeFormat renders one format: "96 H264 90000Hz 1920x1080 High@4.1 packetization-mode=1".
+func describeProbeFormat(f probeFormat) string {
+	parts := []string{fmt.Sprintf("%d %s", f.PayloadType, f.Codec)}
+	if f.SampleRate > 0 {
+		parts = append(parts, fmt.Sprintf("%dHz %dch", f.SampleRate, f.Channels))
+	} else if f.ClockRate > 0 {
+		parts = append(parts, fmt.Sprintf("%dHz", f.ClockRate))
+	}
+	if f.Width > 0 {
+		parts = append(parts, fmt.Sprintf("%dx%d", f.Width, f.Height))
+	}
+	if f.Profile != "" {
+		parts = append(parts, f.Profile+"@"+f.Level)
+	}
+	if f.RTPMap != "" {
+		parts = append(parts, "("+f.RTPMap+")")
+	}
+	// sprop parameter sets are long base64 blobs that the SDP above already shows
+	keys := make([]string, 0, len(f.FMTP))
+	for k := range f.FMTP {
+		if !strings.HasPrefix(k, "sprop-") {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		parts = append(parts, k+"="+f.FMTP[k])
+	}
+	return strings.Join(parts, " ")
+}
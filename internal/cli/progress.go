@@ -0,0 +1,164 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-isatty"
+	"github.com/steipete/camsnap/internal/exec"
+)
+
+// progressEvent is one --progress json line.
+type progressEvent struct {
+	Event       string  `json:"event"` // progress|done
+	Camera      string  `json:"camera"`
+	File        string  `json:"file,omitempty"`
+	Frame       int64   `json:"frame"`
+	FPS         float64 `json:"fps"`
+	BitrateKbps float64 `json:"bitrate_kbps"`
+	TotalSize   int64   `json:"total_size"`
+	OutTime     float64 `json:"out_time"` // seconds written
+	DupFrames   int64   `json:"dup_frames"`
+	DropFrames  int64   `json:"drop_frames"`
+	Speed       float64 `json:"speed"`
+}
+
+// progressPrinter shows ffmpeg -progress reports: a live line rewritten in place on a terminal,
+// JSON lines otherwise, and a summary once the run ends.
+type progressPrinter struct {
+	w      io.Writer
+	sty    styler
+	mode   string // line|json|none
+	camera string
+	file   string
+	total  time.Duration // expected out_time, 0 if open-ended
+	last   exec.Progress
+	seen   bool
+}
+
+// newProgressPrinter resolves --progress auto to a live line when w is a terminal and JSON
+// otherwise.
+func newProgressPrinter(w io.Writer, mode, camera, file string, total time.Duration) (*progressPrinter, error) {
+	switch mode {
+	case "auto":
+		mode = "json"
+		if f, ok := w.(*os.File); ok && isatty.IsTerminal(f.Fd()) {
+			mode = "line"
+		}
+	case "line", "json", "none":
+	default:
+		return nil, fmt.Errorf("invalid --progress %q (use auto|line|json|none)", mode)
+	}
+	return &progressPrinter{w: w, sty: newStyler(w), mode: mode, camera: camera, file: file, total: total}, nil
+}
+
+// update takes one report; pass it to exec.RunRTSP.
+func (p *progressPrinter) update(pr exec.Progress) {
+	p.last, p.seen = pr, true
+	switch p.mode {
+	case "line":
+		_, _ = fmt.Fprintf(p.w, "\r\033[K%s", p.line(pr))
+	case "json":
+		p.event("progress", pr)
+	}
+}
+
+func (p *progressPrinter) line(pr exec.Progress) string {
+	pos := formatClock(pr.OutTime)
+	if p.total > 0 {
+		pos += " / " + formatClock(p.total)
+	}
+	parts := []string{
+		p.sty.OK("●") + " " + pos,
+		fmt.Sprintf("%d frames", pr.Frame),
+		fmt.Sprintf("%.1f fps", pr.FPS),
+		fmt.Sprintf("%.0f kb/s", pr.BitrateKbps),
+	}
+	if pr.Speed > 0 {
+		parts = append(parts, fmt.Sprintf("%.2fx", pr.Speed))
+	}
+	if n := frameTrouble(pr); n != "" {
+		parts = append(parts, p.sty.Warn(n))
+	}
+	return strings.Join(parts, "  ")
+}
+
+func (p *progressPrinter) event(name string, pr exec.Progress) {
+	ev := progressEvent{
+		Event:       name,
+		Camera:      p.camera,
+		Frame:       pr.Frame,
+		FPS:         pr.FPS,
+		BitrateKbps: pr.BitrateKbps,
+		TotalSize:   pr.TotalSize,
+		OutTime:     pr.OutTime.Seconds(),
+		DupFrames:   pr.DupFrames,
+		DropFrames:  pr.DropFrames,
+		Speed:       pr.Speed,
+	}
+	if name == "done" {
+		ev.File = p.file
+	}
+	data, _ := json.Marshal(ev)
+	_, _ = fmt.Fprintf(p.w, "%s\n", data)
+}
+
+// abort leaves the last live line in place when the run fails.
+func (p *progressPrinter) abort() {
+	if p.mode == "line" && p.seen {
+		_, _ = fmt.Fprintln(p.w)
+	}
+}
+
+// finish ends the live line and writes the summary: a done event in json mode, else a line on out.
+func (p *progressPrinter) finish(out io.Writer) {
+	if !p.seen {
+		return
+	}
+	pr := p.last
+	switch p.mode {
+	case "json":
+		p.event("done", pr)
+		return
+	case "line":
+		_, _ = fmt.Fprint(p.w, "\r\033[K")
+	}
+	sty := newStyler(out)
+	summary := fmt.Sprintf("%s %s: %.1fs, %d frames at %.1f fps, %s, %.0f kb/s", sty.OK("✔"), p.file,
+		pr.OutTime.Seconds(), pr.Frame, pr.FPS, formatBytes(pr.TotalSize), pr.BitrateKbps)
+	if n := frameTrouble(pr); n != "" {
+		summary += ", " + sty.Warn(n)
+	}
+	_, _ = fmt.Fprintln(out, summary)
+}
+
+// frameTrouble names dropped and duplicated frames, or "" when there were none.
+func frameTrouble(pr exec.Progress) string {
+	var parts []string
+	if pr.DropFrames > 0 {
+		parts = append(parts, fmt.Sprintf("%d dropped", pr.DropFrames))
+	}
+	if pr.DupFrames > 0 {
+		parts = append(parts, fmt.Sprintf("%d duplicated", pr.DupFrames))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func formatClock(d time.Duration) string {
+	d = d.Round(100 * time.Millisecond)
+	return fmt.Sprintf("%02d:%04.1f", int(d.Minutes()), (d % time.Minute).Seconds())
+}
+
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.0f KiB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
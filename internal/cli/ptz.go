@@ -0,0 +1,240 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/steipete/camsnap/internal/config"
+	"github.com/steipete/camsnap/internal/discovery"
+)
+
+// ptzSettleTimeout bounds how long we wait for a preset move to finish.
+const ptzSettleTimeout = 30 * time.Second
+
+func newPTZCmd() *cobra.Command {
+	var mode string
+	var pan, tilt, zoom float64
+	var speed float64
+	var timeout time.Duration
+	var wait bool
+	var onvifPort int
+	var profile string
+
+	cmd := &cobra.Command{
+		Use:   "ptz <camera> move|zoom|stop|goto-preset|set-preset|list-presets [preset]",
+		Short: "Pan, tilt and zoom ONVIF cameras and manage their presets",
+		Args:  cobra.RangeArgs(2, 3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			sty := newStyler(cmd.OutOrStdout())
+			cfg, _, err := loadConfigFromFlag(cmd)
+			if err != nil {
+				return err
+			}
+			cam, ok := findCamera(cfg, args[0])
+			if !ok {
+				return fmt.Errorf("camera %q not found", args[0])
+			}
+			action := args[1]
+			presetArg := ""
+			if len(args) == 3 {
+				presetArg = args[2]
+			}
+			if (action == "goto-preset" || action == "set-preset") && presetArg == "" {
+				return fmt.Errorf("%s needs a preset name", action)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), ptzSettleTimeout+timeout)
+			defer cancel()
+			ptz, err := cameraPTZ(ctx, cam, onvifPort, profile)
+			if err != nil {
+				return err
+			}
+
+			var speedVec *discovery.PTZVector
+			if speed > 0 {
+				speedVec = &discovery.PTZVector{Pan: &speed, Tilt: &speed, Zoom: &speed}
+			}
+			flags := cmd.Flags()
+
+			switch action {
+			case "move", "zoom":
+				var v discovery.PTZVector
+				if action == "move" {
+					if !flags.Changed("pan") && !flags.Changed("tilt") {
+						return fmt.Errorf("move needs --pan and/or --tilt")
+					}
+					if flags.Changed("pan") {
+						v.Pan = &pan
+					}
+					if flags.Changed("tilt") {
+						v.Tilt = &tilt
+					}
+					if mode == "absolute" && flags.Changed("zoom") {
+						v.Zoom = &zoom
+					}
+				} else {
+					if !flags.Changed("zoom") {
+						return fmt.Errorf("zoom needs --zoom")
+					}
+					v.Zoom = &zoom
+				}
+				switch mode {
+				case "continuous":
+					if err := ptz.ContinuousMove(ctx, v, timeout); err != nil {
+						return err
+					}
+					// not every camera honours the ONVIF timeout, so stop explicitly
+					time.Sleep(timeout)
+					if err := ptz.Stop(ctx); err != nil {
+						return err
+					}
+				case "relative":
+					if err := ptz.RelativeMove(ctx, v, speedVec); err != nil {
+						return err
+					}
+				case "absolute":
+					if err := ptz.AbsoluteMove(ctx, v, speedVec); err != nil {
+						return err
+					}
+				default:
+					return fmt.Errorf("invalid --mode (use continuous|relative|absolute)")
+				}
+				if wait && mode != "continuous" {
+					if err := ptz.WaitIdle(ctx, 0); err != nil {
+						return err
+					}
+				}
+				cmd.Println(sty.OK(fmt.Sprintf("%s %s done", cam.Name, action)))
+			case "stop":
+				if err := ptz.Stop(ctx); err != nil {
+					return err
+				}
+				cmd.Println(sty.OK(cam.Name + " stopped"))
+			case "list-presets":
+				presets, err := ptz.Presets(ctx)
+				if err != nil {
+					return err
+				}
+				if len(presets) == 0 {
+					cmd.Println(sty.Warn("No presets stored."))
+					return nil
+				}
+				for _, p := range presets {
+					cmd.Printf("%s\t%s\n", p.Token, p.Name)
+				}
+			case "goto-preset":
+				p, err := lookupPTZPreset(ctx, ptz, presetArg)
+				if err != nil {
+					return err
+				}
+				if err := ptz.GotoPreset(ctx, p.Token, speedVec); err != nil {
+					return err
+				}
+				if wait {
+					if err := ptz.WaitIdle(ctx, 0); err != nil {
+						return err
+					}
+				}
+				cmd.Println(sty.OK(fmt.Sprintf("%s moved to preset %q", cam.Name, p.Name)))
+			case "set-preset":
+				presets, err := ptz.Presets(ctx)
+				if err != nil {
+					return err
+				}
+				existing, _ := findPTZPreset(presets, presetArg)
+				token, err := ptz.SetPreset(ctx, presetArg, existing.Token)
+				if err != nil {
+					return err
+				}
+				cmd.Println(sty.OK(fmt.Sprintf("Saved preset %q (token %s)", presetArg, token)))
+			default:
+				return fmt.Errorf("unknown ptz action %q (use move|zoom|stop|goto-preset|set-preset|list-presets)", action)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&mode, "mode", "continuous", "Move mode for move/zoom: continuous|relative|absolute")
+	cmd.Flags().Float64Var(&pan, "pan", 0, "Pan velocity, offset or position (-1..1)")
+	cmd.Flags().Float64Var(&tilt, "tilt", 0, "Tilt velocity, offset or position (-1..1)")
+	cmd.Flags().Float64Var(&zoom, "zoom", 0, "Zoom velocity, offset or position")
+	cmd.Flags().Float64Var(&speed, "speed", 0, "Speed for relative/absolute moves and presets (0-1, 0 = camera default)")
+	cmd.Flags().DurationVar(&timeout, "timeout", time.Second, "How long a continuous move runs before stopping")
+	cmd.Flags().BoolVar(&wait, "wait", false, "Wait until the camera stops moving")
+	cmd.Flags().IntVar(&onvifPort, "onvif-port", 80, "ONVIF port when the camera was not added with --onvif")
+	cmd.Flags().StringVar(&profile, "profile", "", "ONVIF media profile (name, token or 1-based index)")
+	return cmd
+}
+
+// cameraPTZ resolves the PTZ service for a camera, preferring the ONVIF address saved by add --onvif.
+func cameraPTZ(ctx context.Context, cam config.Camera, onvifPort int, selector string) (*discovery.PTZ, error) {
+	xaddr := cam.ONVIF
+	if xaddr == "" {
+		xaddr = onvifDeviceURL(cam.Host, onvifPort)
+	}
+	token := selector
+	if len(cam.Profiles) > 0 {
+		p, ok := findProfile(cam.Profiles, selector)
+		if !ok {
+			return nil, fmt.Errorf("profile %q not found", selector)
+		}
+		token = p.Token
+	}
+	ptz, err := discovery.NewPTZ(ctx, xaddr, cam.Username, cam.Password, token)
+	if err != nil {
+		return nil, fmt.Errorf("onvif ptz: %w", err)
+	}
+	return ptz, nil
+}
+
+// lookupPTZPreset finds a preset on the camera by name or token.
+func lookupPTZPreset(ctx context.Context, ptz *discovery.PTZ, selector string) (discovery.PTZPreset, error) {
+	presets, err := ptz.Presets(ctx)
+	if err != nil {
+		return discovery.PTZPreset{}, err
+	}
+	p, ok := findPTZPreset(presets, selector)
+	if !ok {
+		names := make([]string, 0, len(presets))
+		for _, p := range presets {
+			names = append(names, p.Name)
+		}
+		return discovery.PTZPreset{}, fmt.Errorf("preset %q not found (have %s)", selector, strings.Join(names, ", "))
+	}
+	return p, nil
+}
+
+func findPTZPreset(presets []discovery.PTZPreset, selector string) (discovery.PTZPreset, bool) {
+	for _, p := range presets {
+		if strings.EqualFold(p.Name, selector) {
+			return p, true
+		}
+	}
+	for _, p := range presets {
+		if p.Token == selector {
+			return p, true
+		}
+	}
+	return discovery.PTZPreset{}, false
+}
+
+// moveToPreset sends the camera to a preset and blocks until the move settles.
+func moveToPreset(cam config.Camera, onvifPort int, preset string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), ptzSettleTimeout)
+	defer cancel()
+	ptz, err := cameraPTZ(ctx, cam, onvifPort, "")
+	if err != nil {
+		return err
+	}
+	p, err := lookupPTZPreset(ctx, ptz, preset)
+	if err != nil {
+		return err
+	}
+	if err := ptz.GotoPreset(ctx, p.Token, nil); err != nil {
+		return err
+	}
+	return ptz.WaitIdle(ctx, 0)
+}
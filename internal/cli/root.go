@@ -29,8 +29,13 @@ func NewRootCommand(version string) *cobra.Command {
 		newListCmd(),
 		newSnapCmd(),
 		newClipCmd(),
+		newTimelapseCmd(),
 		newDiscoverCmd(),
+		newSetupCmd(),
 		newWatchCmd(),
+		newPTZCmd(),
+		newProbeCmd(),
+		newFakecamCmd(),
 		newDoctorCmd(),
 		newVersionCmd(version),
 	)
@@ -48,6 +53,7 @@ func configPathFlag(cmd *cobra.Command) (string, error) {
 
 func exampleText() string {
 	var b strings.Builder
+	b.WriteString("  camsnap setup\n")
 	b.WriteString("  camsnap add --name kitchen --host 192.168.0.175 --user tapo --pass secret --rtsp-transport udp --stream stream2\n")
 	b.WriteString("  camsnap snap kitchen --out shot.jpg\n")
 	b.WriteString("  camsnap clip kitchen --dur 5s --no-audio --out clip.mp4\n")
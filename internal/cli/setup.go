@@ -0,0 +1,285 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/steipete/camsnap/internal/camerr"
+	"github.com/steipete/camsnap/internal/config"
+	"github.com/steipete/camsnap/internal/discovery"
+	"github.com/steipete/camsnap/internal/exec"
+	"github.com/steipete/camsnap/internal/hostport"
+)
+
+// setupAttempt is one transport/client/stream combination tried by setup.
+type setupAttempt struct {
+	Transport string // tcp|udp
+	Client    string // ffmpeg|gortsplib
+	Stream    string // label: profile name, main/sub, or stream1/stream2
+	Camera    config.Camera
+}
+
+func (a setupAttempt) String() string {
+	return a.Transport + "/" + a.Client + "/" + a.Stream
+}
+
+func newSetupCmd() *cobra.Command {
+	var host string
+	var name string
+	var user string
+	var pass string
+	var timeout time.Duration
+	var attemptTimeout time.Duration
+	var ifaces []string
+	var onvifPort int
+	cmd := &cobra.Command{
+		Use:   "setup",
+		Short: "Discover a camera, ask for credentials and save the first stream settings that produce a snapshot",
+		Long: "setup walks from discovery to a working camera: pick a discovered device (or pass --host), enter its\n" +
+			"credentials, and setup tries each stream, transport (tcp/udp) and client (ffmpeg/gortsplib) until a\n" +
+			"snapshot succeeds. The winning combination is saved as the camera's defaults.\n" +
+			"Answers are read from stdin and the password is echoed; pass --pass to avoid typing it.",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			// gortsplib decodes H.264/H.265 frames through ffmpeg too, so no attempt can work without it
+			if !media.Available("ffmpeg") {
+				return fmt.Errorf("ffmpeg not found in PATH (install ffmpeg and retry)")
+			}
+			sty := newStyler(cmd.OutOrStdout())
+			in := bufio.NewReader(cmd.InOrStdin())
+
+			cfg, cfgPath, err := loadConfigFromFlag(cmd)
+			if err != nil {
+				return err
+			}
+
+			var d discovery.Device
+			if host != "" {
+				h, _ := hostport.Split(host)
+				d = discovery.Device{Host: h, Address: onvifDeviceURL(h, onvifPort)}
+			} else {
+				d, err = pickDevice(cmd, in, timeout, ifaces)
+				if err != nil {
+					return err
+				}
+			}
+
+			if user == "" {
+				def, _ := findCreds(cfg, d.Host)
+				if user, err = prompt(cmd, in, "Username", def); err != nil {
+					return err
+				}
+			}
+			if pass == "" && !cmd.Flags().Changed("pass") {
+				if pass, err = prompt(cmd, in, "Password", ""); err != nil {
+					return err
+				}
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+			info, vendor := fetchInfo(ctx, cfg, d, user, pass)
+			if s := infoSummary(info); s != "" {
+				cmd.Printf("Found %s\n", s)
+			}
+			cam, err := discoveredCamera(ctx, cfg, d, vendor, user, pass, "")
+			cancel()
+			if err != nil {
+				// no ONVIF (or it refused us): fall back to vendor or Tapo-style paths
+				cmd.Printf("%s %v; trying common stream paths\n", sty.Warn("!"), err)
+			}
+
+			if name == "" {
+				if name, err = prompt(cmd, in, "Camera name", cam.Name); err != nil {
+					return err
+				}
+			}
+			cam.Name = name
+
+			attempts := setupAttempts(cam)
+			if len(attempts) == 0 {
+				return fmt.Errorf("nothing to try: check the camera's ONVIF profiles")
+			}
+			tmp, err := os.CreateTemp("", "camsnap-setup-*.jpg")
+			if err != nil {
+				return fmt.Errorf("create temp file: %w", err)
+			}
+			_ = tmp.Close()
+			defer func() {
+				_ = os.Remove(tmp.Name())
+			}()
+
+			cmd.Printf("Trying %d combinations...\n", len(attempts))
+			won, ok := firstWorking(attempts, func(a setupAttempt) error {
+				err := trySnapshot(a, tmp.Name(), attemptTimeout)
+				if err != nil {
+					// ffmpeg errors carry the URL and its password, so only the class is shown
+					cmd.Printf("  %s %-28s %s\n", sty.Err("✖"), a, camerr.Class(err))
+					return err
+				}
+				cmd.Printf("  %s %s\n", sty.OK("✔"), a)
+				return nil
+			})
+			if !ok {
+				return fmt.Errorf("no combination produced a snapshot; check the credentials and that RTSP is enabled on the camera")
+			}
+
+			var created bool
+			cfg, created = config.UpsertCamera(cfg, won.Camera)
+			if err := saveConfig(cfgPath, cfg); err != nil {
+				return err
+			}
+			verb := "Updated"
+			if created {
+				verb = "Saved"
+			}
+			cmd.Println(sty.OK(fmt.Sprintf("%s %s (%s)", verb, won.Camera.Name, won)))
+			cmd.Printf("Try: camsnap snap %s --out %s.jpg\n", won.Camera.Name, won.Camera.Name)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&host, "host", "", "Camera host; skips discovery")
+	cmd.Flags().StringVar(&name, "name", "", "Camera name (default: prompt, suggesting cam-<host>)")
+	cmd.Flags().StringVar(&user, "user", "", "Camera username (default: prompt)")
+	cmd.Flags().StringVar(&pass, "pass", "", "Camera password (default: prompt)")
+	cmd.Flags().DurationVar(&timeout, "timeout", 3*time.Second, "Discovery timeout")
+	cmd.Flags().DurationVar(&attemptTimeout, "attempt-timeout", 10*time.Second, "Timeout for each snapshot attempt")
+	cmd.Flags().StringSliceVar(&ifaces, "iface", nil, "Probe only on these interfaces (repeatable)")
+	cmd.Flags().IntVar(&onvifPort, "onvif-port", 80, "ONVIF HTTP port when --host is set")
+	return cmd
+}
+
+// pickDevice runs discovery and lets the user choose a device; a single result is picked automatically.
+func pickDevice(cmd *cobra.Command, in *bufio.Reader, timeout time.Duration, ifaces []string) (discovery.Device, error) {
+	cmd.Println("Discovering cameras...")
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	devs, err := discovery.DiscoverWith(ctx, timeout, discovery.Options{Interfaces: ifaces, TTL: 1})
+	if err != nil {
+		return discovery.Device{}, err
+	}
+	if len(devs) == 0 {
+		return discovery.Device{}, fmt.Errorf("no cameras found; rerun with --host for cameras with ONVIF disabled")
+	}
+	if len(devs) == 1 {
+		cmd.Printf("Found %s%s\n", devs[0].Host, viaInterface(devs[0]))
+		return devs[0], nil
+	}
+	for i, d := range devs {
+		cmd.Printf("  %d) %s%s\n", i+1, d.Host, viaInterface(d))
+	}
+	for {
+		answer, err := prompt(cmd, in, fmt.Sprintf("Camera [1-%d]", len(devs)), "1")
+		if err != nil {
+			return discovery.Device{}, err
+		}
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(devs) {
+			return devs[n-1], nil
+		}
+		cmd.Printf("Enter a number between 1 and %d\n", len(devs))
+	}
+}
+
+// prompt asks for a line of input; an empty answer takes def.
+func prompt(cmd *cobra.Command, in *bufio.Reader, label, def string) (string, error) {
+	if def != "" {
+		cmd.Printf("%s [%s]: ", label, def)
+	} else {
+		cmd.Printf("%s: ", label)
+	}
+	line, err := in.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("read %s: %w", strings.ToLower(label), err)
+	}
+	if err == io.EOF {
+		// keep the next prompt on its own line when stdin is not a terminal
+		cmd.Println()
+	}
+	if line = strings.TrimSpace(line); line == "" {
+		return def, nil
+	}
+	return line, nil
+}
+
+// setupAttempts lists combinations in the order setup tries them: streams from best to worst, and for
+// each stream the camera's preferred transport first, ffmpeg before gortsplib.
+func setupAttempts(cam config.Camera) []setupAttempt {
+	transports := []string{"tcp", "udp"}
+	if cam.RTSPTransport == "udp" {
+		transports = []string{"udp", "tcp"}
+	}
+	clients := []string{"ffmpeg", "gortsplib"}
+	var attempts []setupAttempt
+	for _, s := range setupStreams(cam) {
+		for _, t := range transports {
+			for _, c := range clients {
+				next := s.Camera
+				next.RTSPTransport, next.RTSPClient = t, c
+				attempts = append(attempts, setupAttempt{Transport: t, Client: c, Stream: s.Stream, Camera: next})
+			}
+		}
+	}
+	return attempts
+}
+
+// maxSetupProfiles bounds how many ONVIF profiles setup tries; later profiles are sub and third streams.
+const maxSetupProfiles = 3
+
+// setupStreams returns the stream variants of cam: a custom path as is, its ONVIF profiles, else the
+// vendor preset's main and sub streams, else Tapo-style stream1/stream2.
+func setupStreams(cam config.Camera) []setupAttempt {
+	var out []setupAttempt
+	switch {
+	case cam.Path != "" && len(cam.Profiles) == 0:
+		// a tokenized path (e.g. UniFi Protect) is the only stream we know
+		out = append(out, setupAttempt{Stream: cam.Path, Camera: cam})
+	case len(cam.Profiles) > 0:
+		for i := range cam.Profiles {
+			if i == maxSetupProfiles {
+				break
+			}
+			c, err := selectProfile(cam, strconv.Itoa(i+1))
+			if err != nil {
+				continue
+			}
+			out = append(out, setupAttempt{Stream: cam.Profiles[i].Name, Camera: c})
+		}
+	case cam.Vendor != "":
+		main, sub := cam, cam
+		main.Substream, sub.Substream = false, true
+		out = append(out, setupAttempt{Stream: "main", Camera: main}, setupAttempt{Stream: "sub", Camera: sub})
+	default:
+		for _, stream := range []string{"stream1", "stream2"} {
+			c := cam
+			c.Stream = stream
+			out = append(out, setupAttempt{Stream: stream, Camera: c})
+		}
+	}
+	return out
+}
+
+// firstWorking runs try over attempts in order and returns the first that succeeds.
+func firstWorking(attempts []setupAttempt, try func(setupAttempt) error) (setupAttempt, bool) {
+	for _, a := range attempts {
+		if try(a) == nil {
+			return a, true
+		}
+	}
+	return setupAttempt{}, false
+}
+
+// trySnapshot grabs one frame with the attempt's settings.
+func trySnapshot(a setupAttempt, outPath string, timeout time.Duration) error {
+	url, err := streamURL(a.Camera)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := exec.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return grabFrame(ctx, url, a.Transport, a.Client, a.Camera.RTSPCodec, outPath, exec.Image{}, timeout, defaultRTSPTimeout)
+}
@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/steipete/camsnap/internal/config"
+)
+
+func TestSetupAttemptsOrder(t *testing.T) {
+	// no ONVIF, no vendor: Tapo-style paths, tcp first
+	got := setupAttempts(config.Camera{Name: "cam", Host: "10.0.0.2"})
+	var labels []string
+	for _, a := range got {
+		labels = append(labels, a.String())
+	}
+	want := "tcp/ffmpeg/stream1 tcp/gortsplib/stream1 udp/ffmpeg/stream1 udp/gortsplib/stream1 " +
+		"tcp/ffmpeg/stream2 tcp/gortsplib/stream2 udp/ffmpeg/stream2 udp/gortsplib/stream2"
+	if strings.Join(labels, " ") != want {
+		t.Fatalf("unexpected order:\n got %s\nwant %s", strings.Join(labels, " "), want)
+	}
+	if got[4].Camera.Stream != "stream2" || got[1].Camera.RTSPClient != "gortsplib" || got[2].Camera.RTSPTransport != "udp" {
+		t.Fatalf("attempt settings not applied: %+v", got[:5])
+	}
+
+	// a preset that prefers udp goes first
+	got = setupAttempts(config.Camera{Host: "10.0.0.2", Vendor: "tapo", RTSPTransport: "udp"})
+	if len(got) != 8 || got[0].String() != "udp/ffmpeg/main" || got[7].String() != "tcp/gortsplib/sub" || !got[7].Camera.Substream {
+		t.Fatalf("unexpected preset attempts: %+v", got)
+	}
+
+	// ONVIF profiles are tried in order with their paths
+	cam := config.Camera{Host: "10.0.0.2", Profiles: []config.Profile{
+		{Name: "main", Path: "/main", Codec: "h265"},
+		{Name: "sub", Path: "/sub"},
+	}}
+	got = setupAttempts(cam)
+	if len(got) != 8 || got[0].Camera.Path != "/main" || got[0].Camera.RTSPCodec != "h265" || got[7].Camera.Path != "/sub" {
+		t.Fatalf("unexpected profile attempts: %+v", got)
+	}
+}
+
+func TestFirstWorking(t *testing.T) {
+	attempts := setupAttempts(config.Camera{Host: "10.0.0.2"})
+	var tried []string
+	won, ok := firstWorking(attempts, func(a setupAttempt) error {
+		tried = append(tried, a.String())
+		if a.Transport == "udp" && a.Client == "gortsplib" {
+			return nil
+		}
+		return errors.New("401 Unauthorized")
+	})
+	if !ok || won.String() != "udp/gortsplib/stream1" || len(tried) != 4 {
+		t.Fatalf("won %v ok=%v after %v", won, ok, tried)
+	}
+	if _, ok := firstWorking(attempts, func(setupAttempt) error { return errors.New("refused") }); ok {
+		t.Fatal("expected no winner")
+	}
+}
+
+func TestPromptDefaults(t *testing.T) {
+	cmd := &cobra.Command{}
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	in := bufio.NewReader(strings.NewReader("admin\n\n"))
+
+	if got, _ := prompt(cmd, in, "Username", "tapo"); got != "admin" {
+		t.Fatalf("answer ignored: %q", got)
+	}
+	if got, _ := prompt(cmd, in, "Camera name", "cam-10.0.0.2"); got != "cam-10.0.0.2" {
+		t.Fatalf("default not used: %q", got)
+	}
+	// stdin exhausted
+	if got, err := prompt(cmd, in, "Password", ""); err != nil || got != "" {
+		t.Fatalf("eof: %q %v", got, err)
+	}
+	if !strings.Contains(out.String(), "Username [tapo]: ") {
+		t.Fatalf("prompt not shown: %q", out.String())
+	}
+}
+
+func TestSetupNeedsFFmpeg(t *testing.T) {
+	useFakeFFmpeg(t).SetMissing(true)
+	root := NewRootCommand("test")
+	root.SetArgs([]string{"--config", filepath.Join(t.TempDir(), "config.yaml"), "setup", "--host", "10.0.0.2", "--user", "u", "--pass", "p"})
+	if err := root.Execute(); err == nil || !strings.Contains(err.Error(), "install ffmpeg") {
+		t.Fatalf("expected an install ffmpeg error, got %v", err)
+	}
+}
@@ -3,12 +3,14 @@ package cli
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/steipete/camsnap/internal/config"
 	"github.com/steipete/camsnap/internal/exec"
-	"github.com/steipete/camsnap/internal/rtsp"
+	"github.com/steipete/camsnap/internal/httpcam"
 	"github.com/steipete/camsnap/internal/rtspclient"
 )
 
@@ -16,15 +18,13 @@ func newSnapCmd() *cobra.Command {
 	var cameraName string
 	var outPath string
 	var timeout time.Duration
-	var authMode string
-	var transport string
-	var stream string
-	var client string
-	var path string
+	var count int
+	var every time.Duration
+	var f captureFlags
 
 	cmd := &cobra.Command{
 		Use:   "snap",
-		Short: "Capture a single frame to a file",
+		Short: "Capture a single frame (or a burst of frames) to a file",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// allow positional camera name if --camera not set
 			if cameraName == "" && len(args) > 0 {
@@ -33,103 +33,290 @@ func newSnapCmd() *cobra.Command {
 			if cameraName == "" {
 				return fmt.Errorf("--camera is required")
 			}
-			if outPath == "" {
-				tmp, err := os.CreateTemp("", "camsnap-*.jpg")
-				if err != nil {
-					return fmt.Errorf("create temp file: %w", err)
-				}
-				if err := tmp.Close(); err != nil {
-					return fmt.Errorf("close temp file: %w", err)
-				}
-				outPath = tmp.Name()
-				cmd.Printf("No --out provided, writing snapshot to %s\n", outPath)
-			}
-			if !exec.HasBinary("ffmpeg") {
-				return fmt.Errorf("ffmpeg not found in PATH")
-			}
-
-			if stream != "" && path != "" {
-				return fmt.Errorf("use --path for custom RTSP token URLs; omit --stream")
-			}
-
-			cfgFlag, err := configPathFlag(cmd)
-			if err != nil {
-				return err
-			}
-			cfg, _, err := loadConfig(cfgFlag)
+			img, err := f.image()
 			if err != nil {
 				return err
 			}
-			cam, ok := findCamera(cfg, cameraName)
-			if !ok {
-				return fmt.Errorf("camera %q not found", cameraName)
+			if count < 1 {
+				return fmt.Errorf("--count must be at least 1")
 			}
-
-			if path == "" && cam.Path != "" {
-				path = cam.Path
+			if count > 1 && every <= 0 {
+				return fmt.Errorf("--every must be > 0")
 			}
-			if path != "" {
-				cam.Path = path
-				cam.Stream = ""
+			if count > 1 && outPath == "-" {
+				return fmt.Errorf("--out - takes a single frame; give --count a filename template such as shot-{seq}.jpg")
 			}
 
-			url, err := rtsp.BuildURL(cam)
+			src, err := f.source(cmd, cameraName)
 			if err != nil {
 				return err
 			}
-
-			// fall back to per-camera defaults
-			if transport == "" && cam.RTSPTransport != "" {
-				transport = cam.RTSPTransport
-			}
-			if stream == "" && cam.Stream != "" && path == "" {
-				stream = cam.Stream
-			}
-			if client == "" && cam.RTSPClient != "" {
-				client = cam.RTSPClient
-			}
-
-			if _, ok := parseRTSPAuth(authMode); !ok {
-				return fmt.Errorf("invalid --rtsp-auth (use auto|basic|digest)")
+			if f.preset != "" {
+				if err := moveToPreset(src.cam, f.onvifPort, f.preset); err != nil {
+					return err
+				}
 			}
-			xport, ok := transportFlag(transport)
-			if !ok {
-				return fmt.Errorf("invalid --rtsp-transport (use tcp|udp)")
+			if count > 1 {
+				return snapBurst(cmd, src, img, outPath, count, every, timeout, f.ioTimeout)
 			}
 
-			ctx, cancel := exec.WithTimeout(context.Background(), timeout)
-			defer cancel()
-
-			if path != "" {
-				url = appendPath(url, path)
+			toStdout := outPath == "-"
+			if outPath == "" || toStdout {
+				tmp, err := tempOutput("camsnap-*" + exec.ImageExt(img.Format))
+				if err != nil {
+					return err
+				}
+				outPath = tmp
+				if toStdout {
+					// every backend writes a file; it is streamed out once complete
+					defer func() {
+						_ = os.Remove(tmp)
+					}()
+				} else {
+					cmd.Printf("No --out provided, writing snapshot to %s\n", outPath)
+				}
 			} else {
-				url = appendStream(url, stream)
+				outPath = frameName(outPath, src.cam.Name, 1, time.Now())
 			}
-
-			if client == "gortsplib" {
-				return rtspclient.GrabFrameViaGort(ctx, url, xport, outPath, timeout)
+			emit := func(err error) error {
+				if err != nil || !toStdout {
+					return err
+				}
+				return copyToStdout(cmd, outPath)
 			}
 
-			ffArgs := []string{
-				"-y",
-				"-rtsp_transport", xport,
-				"-i", url,
-				"-frames:v", "1",
-				"-q:v", "2",
-				outPath,
+			if httpcam.IsHTTPSource(src.cam) {
+				return emit(snapHTTP(src.cam, outPath, img, timeout))
 			}
-			return exec.RunFFmpeg(ctx, ffArgs...)
+			ctx, cancel := exec.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			return emit(grabFrame(ctx, src.url, src.transport, src.client, src.codec, outPath, img, timeout, f.ioTimeout))
 		},
 	}
 
 	cmd.Flags().StringVar(&cameraName, "camera", "", "Camera name to use")
-	cmd.Flags().StringVar(&outPath, "out", "", "Output file (e.g., snap.jpg)")
-	cmd.Flags().DurationVar(&timeout, "timeout", 10*time.Second, "Timeout for ffmpeg invocation")
-	cmd.Flags().StringVar(&authMode, "rtsp-auth", "auto", "RTSP auth mode: auto|basic|digest")
-	cmd.Flags().StringVar(&transport, "rtsp-transport", "tcp", "RTSP transport: tcp|udp")
-	cmd.Flags().StringVar(&stream, "stream", "", "RTSP path segment (stream1 or stream2); ignored if --path is set")
-	cmd.Flags().StringVar(&path, "path", "", "Custom RTSP path (overrides --stream), e.g., /Bfy... from UniFi Protect")
-	cmd.Flags().StringVar(&client, "rtsp-client", "ffmpeg", "RTSP client: ffmpeg|gortsplib")
+	cmd.Flags().StringVar(&outPath, "out", "", "Output file (e.g., snap.jpg), or - for stdout; {camera}, {time} and {seq} are filled in")
+	cmd.Flags().DurationVar(&timeout, "timeout", 10*time.Second, "Timeout for ffmpeg invocation (for bursts, on top of the capture time)")
+	cmd.Flags().IntVar(&count, "count", 1, "Capture this many frames from one RTSP session (a {seq} is added to --out when missing)")
+	cmd.Flags().DurationVar(&every, "every", time.Second, "Interval between frames with --count")
+	f.register(cmd)
 
 	return cmd
 }
+
+// captureFlags are the camera and image flags snap and timelapse share.
+type captureFlags struct {
+	ioTimeout time.Duration
+	authMode  string
+	transport string
+	stream    string
+	path      string
+	client    string
+	codec     string
+	preset    string
+	onvifPort int
+	img       exec.Image
+	crop      string
+}
+
+func (f *captureFlags) register(cmd *cobra.Command) {
+	cmd.Flags().DurationVar(&f.ioTimeout, "rtsp-timeout", defaultRTSPTimeout, "Give up when the camera sends nothing for this long, in the handshake or the stream (0 = only --timeout)")
+	cmd.Flags().StringVar(&f.authMode, "rtsp-auth", "auto", "RTSP auth mode: auto|basic|digest")
+	cmd.Flags().StringVar(&f.transport, "rtsp-transport", "", "RTSP transport: tcp|udp (default: the camera's, else tcp)")
+	cmd.Flags().StringVar(&f.stream, "stream", "", "RTSP path segment (stream1 or stream2; main|sub for vendor presets); ignored if --path is set")
+	cmd.Flags().StringVar(&f.path, "path", "", "Custom RTSP path (overrides --stream), e.g., /Bfy... from UniFi Protect")
+	cmd.Flags().StringVar(&f.client, "rtsp-client", "", "RTSP client: ffmpeg|gortsplib (default: the camera's, else ffmpeg)")
+	cmd.Flags().StringVar(&f.codec, "rtsp-codec", "", "Video track for gortsplib when several are offered: auto|h264|h265|mjpeg")
+	cmd.Flags().StringVar(&f.img.Format, "format", "", "Image format: jpeg|png|webp|avif (default: from the --out extension, else jpeg)")
+	cmd.Flags().IntVar(&f.img.Width, "width", 0, "Resize to this width, keeping the aspect ratio (with --height: fit inside the box)")
+	cmd.Flags().IntVar(&f.img.Height, "height", 0, "Resize to this height, keeping the aspect ratio")
+	cmd.Flags().Float64Var(&f.img.Scale, "scale", 0, "Resize by this factor instead of --width/--height (e.g., 0.25)")
+	cmd.Flags().IntVar(&f.img.Quality, "quality", 0, "Quality 1-100, higher is better (default: per format; ignored for png)")
+	cmd.Flags().StringVar(&f.crop, "crop", "", "Crop x,y,w,h in camera pixels before resizing")
+	cmd.Flags().StringVar(&f.preset, "preset", "", "Move a PTZ camera to this ONVIF preset and wait for it to settle before capturing")
+	cmd.Flags().IntVar(&f.onvifPort, "onvif-port", 80, "ONVIF port for --preset when the camera was not added with --onvif")
+}
+
+// image validates the image flags.
+func (f *captureFlags) image() (exec.Image, error) {
+	img := f.img
+	format, ok := exec.ParseImageFormat(img.Format)
+	if !ok {
+		return img, fmt.Errorf("invalid --format (use jpeg|png|webp|avif)")
+	}
+	img.Format = format
+	if f.crop != "" {
+		rect, err := exec.ParseCrop(f.crop)
+		if err != nil {
+			return img, err
+		}
+		img.Crop = rect
+	}
+	return img, nil
+}
+
+// captureSource is a camera ready to capture from, with its per-camera defaults applied.
+type captureSource struct {
+	cam       config.Camera
+	url       string // RTSP URL with the stream or path; empty for HTTP sources
+	transport string
+	client    string
+	codec     string
+}
+
+// source loads the camera and resolves the connection flags against its saved defaults.
+func (f *captureFlags) source(cmd *cobra.Command, cameraName string) (captureSource, error) {
+	stream, path := f.stream, f.path
+	if stream != "" && path != "" {
+		return captureSource{}, fmt.Errorf("use --path for custom RTSP token URLs; omit --stream")
+	}
+
+	cfgFlag, err := configPathFlag(cmd)
+	if err != nil {
+		return captureSource{}, err
+	}
+	cfg, _, err := loadConfig(cfgFlag)
+	if err != nil {
+		return captureSource{}, err
+	}
+	cam, ok := findCamera(cfg, cameraName)
+	if !ok {
+		return captureSource{}, fmt.Errorf("camera %q not found", cameraName)
+	}
+
+	if path == "" && cam.Path != "" {
+		path = cam.Path
+	}
+	if path != "" {
+		cam.Path = path
+		cam.Stream = ""
+	}
+	src := captureSource{cam: cam, transport: f.transport, client: f.client, codec: f.codec}
+	if httpcam.IsHTTPSource(cam) {
+		return src, nil
+	}
+	if !media.Available("ffmpeg") {
+		return src, fmt.Errorf("ffmpeg not found in PATH")
+	}
+
+	// fall back to per-camera defaults
+	if src.transport == "" && cam.RTSPTransport != "" {
+		src.transport = cam.RTSPTransport
+	}
+	if stream == "" && cam.Stream != "" && path == "" {
+		stream = cam.Stream
+	}
+	if src.client == "" && cam.RTSPClient != "" {
+		src.client = cam.RTSPClient
+	}
+	if src.codec == "" && cam.RTSPCodec != "" {
+		src.codec = cam.RTSPCodec
+	}
+
+	if _, ok := parseRTSPAuth(f.authMode); !ok {
+		return src, fmt.Errorf("invalid --rtsp-auth (use auto|basic|digest)")
+	}
+	if src.transport, ok = transportFlag(src.transport); !ok {
+		return src, fmt.Errorf("invalid --rtsp-transport (use tcp|udp)")
+	}
+	if _, ok := rtspclient.ParseCodec(src.codec); !ok {
+		return src, fmt.Errorf("invalid --rtsp-codec (use auto|h264|h265|mjpeg)")
+	}
+
+	if path == "" {
+		src.cam.Stream = stream
+	}
+	if src.url, err = streamURL(src.cam); err != nil {
+		return src, err
+	}
+	return src, nil
+}
+
+// defaultRTSPTimeout is how long ffmpeg waits on a silent RTSP socket before giving up.
+const defaultRTSPTimeout = 5 * time.Second
+
+// grabFrame writes one frame of an RTSP stream to outPath as img with ffmpeg or the gortsplib client.
+// ioTimeout bounds each ffmpeg socket wait (see exec.Caps.RTSPTimeoutArgs).
+func grabFrame(ctx context.Context, url, transport, client, codec, outPath string, img exec.Image, timeout, ioTimeout time.Duration) error {
+	caps := media.Caps(ctx)
+	img, err := img.Resolve(outPath, caps)
+	if err != nil {
+		return err
+	}
+	if client == "gortsplib" {
+		return rtspclient.GrabFrameViaGort(ctx, media, url, transport, codec, outPath, img, timeout)
+	}
+	if err := caps.CheckRTSPTransport(transport); err != nil {
+		return err
+	}
+	ffArgs := []string{"-y", "-rtsp_transport", transport}
+	ffArgs = append(ffArgs, caps.RTSPTimeoutArgs(ioTimeout)...)
+	ffArgs = append(ffArgs, "-i", url)
+	ffArgs = append(ffArgs, img.Args()...)
+	ffArgs = append(ffArgs, outPath)
+	return exec.RunRTSP(ctx, media, caps, exec.RTSPOptions{}, ffArgs...)
+}
+
+// copyToStdout writes the finished file at path to the command's stdout.
+func copyToStdout(cmd *cobra.Command, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open snapshot: %w", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	if _, err := io.Copy(cmd.OutOrStdout(), f); err != nil {
+		return fmt.Errorf("write stdout: %w", err)
+	}
+	return nil
+}
+
+// snapHTTP fetches a JPEG directly from an HTTP snapshot or MJPEG source; ffmpeg only converts it
+// when img asks for another format, size or quality.
+func snapHTTP(cam config.Camera, outPath string, img exec.Image, timeout time.Duration) error {
+	url, err := httpcam.BuildURL(cam)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := exec.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	img, err = img.Resolve(outPath, media.Caps(ctx))
+	if err != nil {
+		return err
+	}
+	if !img.Passthrough() && !media.Available("ffmpeg") {
+		return fmt.Errorf("converting the %s snapshot needs ffmpeg, which is not in PATH", cam.Source)
+	}
+
+	client := httpcam.NewClient(cam.Username, cam.Password, timeout)
+	frame, err := client.FetchSnapshot(ctx, url)
+	if err != nil {
+		return fmt.Errorf("fetch %s snapshot: %w", cam.Source, err)
+	}
+	if img.Passthrough() {
+		if err := os.WriteFile(outPath, frame, 0o644); err != nil {
+			return fmt.Errorf("write snapshot: %w", err)
+		}
+		return nil
+	}
+
+	tmp, err := os.CreateTemp("", "camsnap-http-*.jpg")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+	if _, err := tmp.Write(frame); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("write temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close temp file: %w", err)
+	}
+	ffArgs := append([]string{"-y", "-hide_banner", "-loglevel", "error", "-i", tmp.Name()}, img.Args()...)
+	_, err = media.Run(ctx, append(ffArgs, outPath)...)
+	return err
+}
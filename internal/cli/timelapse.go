@@ -0,0 +1,158 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/steipete/camsnap/internal/camerr"
+	"github.com/steipete/camsnap/internal/exec"
+)
+
+func newTimelapseCmd() *cobra.Command {
+	var cameraName string
+	var every time.Duration
+	var span time.Duration
+	var dir string
+	var outPath string
+	var fps int
+	var keepFrames bool
+	var timeout time.Duration
+	var f captureFlags
+
+	cmd := &cobra.Command{
+		Use:   "timelapse",
+		Short: "Capture a frame at an interval and optionally assemble the frames into an MP4",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if cameraName == "" && len(args) > 0 {
+				cameraName = args[0]
+			}
+			if cameraName == "" {
+				return fmt.Errorf("--camera is required")
+			}
+			if every <= 0 || span < every {
+				return fmt.Errorf("--every must be > 0 and no longer than --for")
+			}
+			if fps <= 0 {
+				return fmt.Errorf("--fps must be > 0")
+			}
+			img, err := f.image()
+			if err != nil {
+				return err
+			}
+			src, err := f.source(cmd, cameraName)
+			if err != nil {
+				return err
+			}
+			// check the encoder now rather than after a day of capturing
+			if outPath != "" {
+				if !media.Available("ffmpeg") {
+					return fmt.Errorf("assembling --out needs ffmpeg, which is not in PATH")
+				}
+				if !media.Caps(context.Background()).HasEncoder("libx264") {
+					return fmt.Errorf("this ffmpeg build has no libx264 encoder to assemble --out; drop --out to keep just the frames")
+				}
+			}
+			if f.preset != "" {
+				if err := moveToPreset(src.cam, f.onvifPort, f.preset); err != nil {
+					return err
+				}
+			}
+
+			tempDir := dir == ""
+			if tempDir {
+				if dir, err = os.MkdirTemp("", "camsnap-timelapse-*"); err != nil {
+					return fmt.Errorf("create temp dir: %w", err)
+				}
+			}
+			ext := exec.ImageExt(img.Format)
+			series := frameSeries{tmpl: filepath.Join(dir, "{camera}-{seq}"+ext), every: every, count: int(span / every), first: 1}
+			cmd.Printf("Capturing %d frames every %s into %s\n", series.count, every, dir)
+
+			written, capErr := captureTimelapse(cmd, src, img, series, timeout, f.ioTimeout)
+			if outPath == "" || written == 0 {
+				return capErr
+			}
+			// a cut-short timelapse is still worth assembling
+			pattern := filepath.Join(dir, strings.ReplaceAll(src.cam.Name, "%", "%%")+"-%06d"+ext)
+			if err := assembleTimelapse(pattern, outPath, fps); err != nil {
+				return err
+			}
+			sty := newStyler(cmd.OutOrStdout())
+			cmd.Printf("%s %s: %d frames at %d fps (%.1fs)\n", sty.OK("✔"), outPath, written, fps, float64(written)/float64(fps))
+			if tempDir && !keepFrames && capErr == nil {
+				_ = os.RemoveAll(dir)
+			}
+			return capErr
+		},
+	}
+
+	cmd.Flags().StringVar(&cameraName, "camera", "", "Camera name to use")
+	cmd.Flags().DurationVar(&every, "every", time.Minute, "Capture a frame this often")
+	cmd.Flags().DurationVar(&span, "for", time.Hour, "Keep capturing this long")
+	cmd.Flags().StringVar(&dir, "dir", "", "Directory for the frames (default: a temp dir, removed once --out is assembled)")
+	cmd.Flags().StringVar(&outPath, "out", "", "Assemble the frames into this MP4 (H.264) at the end")
+	cmd.Flags().IntVar(&fps, "fps", 30, "Frame rate of the assembled MP4")
+	cmd.Flags().BoolVar(&keepFrames, "keep-frames", false, "Keep the temp frame dir after assembling --out")
+	cmd.Flags().DurationVar(&timeout, "timeout", 10*time.Second, "Slack on top of --for before giving up")
+	f.register(cmd)
+
+	return cmd
+}
+
+// captureTimelapse captures s until every frame is written or its time is up. A dropped session
+// (timeout, unreachable, session limit) reconnects after one interval and carries on numbering.
+func captureTimelapse(cmd *cobra.Command, src captureSource, img exec.Image, s frameSeries, timeout, ioTimeout time.Duration) (int, error) {
+	end := time.Now().Add(time.Duration(s.count) * s.every)
+	written := 0
+	for written < s.count {
+		part := s
+		part.first = s.first + written
+		part.count = min(s.count-written, int(time.Until(end)/s.every)+1)
+		if part.count <= 0 {
+			break
+		}
+		deadline := time.Duration(0)
+		if timeout > 0 {
+			deadline = time.Until(end) + timeout
+		}
+		ctx, cancel := exec.WithTimeout(context.Background(), deadline)
+		n, err := captureFrames(ctx, src, img, part, timeout, ioTimeout, func(path string) {
+			cmd.Println(path)
+		})
+		cancel()
+		written += n
+		if err == nil {
+			continue
+		}
+		if !reconnectable(err) || time.Until(end) < s.every {
+			return written, err
+		}
+		sty := newStyler(cmd.ErrOrStderr())
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "%s capture interrupted after %d frames, reconnecting: %v\n", sty.Warn("!"), written, err)
+		time.Sleep(s.every)
+	}
+	return written, nil
+}
+
+func reconnectable(err error) bool {
+	return errors.Is(err, camerr.ErrTimeout) || errors.Is(err, camerr.ErrUnreachable) || errors.Is(err, camerr.ErrSessionLimit)
+}
+
+// assembleTimelapse encodes the numbered frames matching pattern into an H.264 MP4.
+func assembleTimelapse(pattern, outPath string, fps int) error {
+	_, err := media.Run(context.Background(),
+		"-y", "-hide_banner", "-loglevel", "error",
+		"-framerate", fmt.Sprint(fps), "-start_number", "1", "-i", pattern,
+		// yuv420p needs even dimensions; a crop may leave odd ones
+		"-vf", "scale=trunc(iw/2)*2:trunc(ih/2)*2",
+		"-c:v", "libx264", "-pix_fmt", "yuv420p", "-movflags", "+faststart",
+		outPath,
+	)
+	return err
+}
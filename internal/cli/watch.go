@@ -4,14 +4,18 @@ import (
 	"bufio"
 	"context"
 	"fmt"
+	"io"
 	osexec "os/exec"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/steipete/camsnap/internal/camerr"
+	"github.com/steipete/camsnap/internal/config"
+	"github.com/steipete/camsnap/internal/discovery"
 	iexec "github.com/steipete/camsnap/internal/exec"
-	"github.com/steipete/camsnap/internal/rtsp"
+	"github.com/steipete/camsnap/internal/httpcam"
 )
 
 func newWatchCmd() *cobra.Command {
@@ -26,6 +30,9 @@ func newWatchCmd() *cobra.Command {
 	var transport string
 	var stream string
 	var path string
+	var source string
+	var topics []string
+	var onvifPort int
 
 	cmd := &cobra.Command{
 		Use:   "watch",
@@ -43,17 +50,15 @@ func newWatchCmd() *cobra.Command {
 			if threshold <= 0 || threshold >= 1 {
 				return fmt.Errorf("--threshold must be between 0 and 1 (e.g., 0.2)")
 			}
-			if !iexec.HasBinary("ffmpeg") {
+			if source != "scene" && source != "onvif" {
+				return fmt.Errorf("invalid --source (use scene|onvif)")
+			}
+			if source == "scene" && !media.Available("ffmpeg") {
 				return fmt.Errorf("ffmpeg not found in PATH")
 			}
 			if _, ok := parseRTSPAuth(authMode); !ok {
 				return fmt.Errorf("invalid --rtsp-auth (use auto|basic|digest)")
 			}
-			xport, ok := transportFlag(transport)
-			if !ok {
-				return fmt.Errorf("invalid --rtsp-transport (use tcp|udp)")
-			}
-
 			cfgFlag, err := configPathFlag(cmd)
 			if err != nil {
 				return err
@@ -66,6 +71,13 @@ func newWatchCmd() *cobra.Command {
 			if !ok {
 				return fmt.Errorf("camera %q not found", cameraName)
 			}
+			if transport == "" {
+				transport = cam.RTSPTransport
+			}
+			xport, ok := transportFlag(transport)
+			if !ok {
+				return fmt.Errorf("invalid --rtsp-transport (use tcp|udp)")
+			}
 			if stream != "" && path != "" {
 				return fmt.Errorf("use --path for custom RTSP token URLs; omit --stream")
 			}
@@ -76,11 +88,6 @@ func newWatchCmd() *cobra.Command {
 				cam.Path = path
 				cam.Stream = ""
 			}
-			url, err := rtsp.BuildURL(cam)
-			if err != nil {
-				return err
-			}
-
 			if tmpl != "" {
 				action, err = applyTemplate(tmpl, cam.Name, 0, time.Now())
 				if err != nil {
@@ -95,7 +102,15 @@ func newWatchCmd() *cobra.Command {
 				defer cancel()
 			}
 
-			return watchMotion(ctx, cameraName, url, threshold, cooldown, action, tmpl, jsonOutput, xport, stream, path, cmd)
+			if source == "onvif" {
+				return watchONVIF(ctx, cam, onvifPort, topics, cooldown, action, tmpl, jsonOutput, cmd)
+			}
+
+			input, err := motionInputFor(ctx, cam, xport, stream, path)
+			if err != nil {
+				return err
+			}
+			return watchMotion(ctx, cameraName, input, threshold, cooldown, action, tmpl, jsonOutput, cmd)
 		},
 	}
 
@@ -107,26 +122,59 @@ func newWatchCmd() *cobra.Command {
 	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Log motion events as JSON lines")
 	cmd.Flags().StringVar(&tmpl, "action-template", "", "Optional template to build action command (placeholders: {camera},{score},{time})")
 	cmd.Flags().StringVar(&authMode, "rtsp-auth", "auto", "RTSP auth mode: auto|basic|digest")
-	cmd.Flags().StringVar(&transport, "rtsp-transport", "tcp", "RTSP transport: tcp|udp")
-	cmd.Flags().StringVar(&stream, "stream", "", "RTSP path segment (stream1 or stream2); ignored if --path is set")
+	cmd.Flags().StringVar(&transport, "rtsp-transport", "", "RTSP transport: tcp|udp (default: the camera's, else tcp)")
+	cmd.Flags().StringVar(&stream, "stream", "", "RTSP path segment (stream1 or stream2; main|sub for vendor presets); ignored if --path is set")
 	cmd.Flags().StringVar(&path, "path", "", "Custom RTSP path (overrides --stream), e.g., /Bfy... from UniFi Protect")
+	cmd.Flags().StringVar(&source, "source", "scene", "Motion source: scene (ffmpeg scene detection) or onvif (camera events)")
+	cmd.Flags().StringSliceVar(&topics, "topic", nil, "With --source onvif, only react to event topics containing this text (repeatable, e.g. Motion, People; default: RuleEngine, VideoSource/MotionAlarm, VideoAnalytics)")
+	cmd.Flags().IntVar(&onvifPort, "onvif-port", 80, "ONVIF port when the camera was not added with --onvif")
 
 	return cmd
 }
 
-func watchMotion(ctx context.Context, cameraName, url string, threshold float64, cooldown time.Duration, action string, tmpl string, jsonOutput bool, transport string, stream string, path string, cmd *cobra.Command) error {
+// motionInput is the ffmpeg input section for watch: an RTSP URL, or MJPEG frames piped on stdin.
+type motionInput struct {
+	args      []string
+	stdin     io.Reader
+	transport string // RTSP transport; empty for MJPEG
+}
+
+func motionInputFor(ctx context.Context, cam config.Camera, transport, stream, path string) (motionInput, error) {
+	switch cam.Source {
+	case httpcam.SourceMJPEG:
+		url, err := httpcam.BuildURL(cam)
+		if err != nil {
+			return motionInput{}, err
+		}
+		client := httpcam.NewClient(cam.Username, cam.Password, 0)
+		// the MJPEG stream is long-lived; only the context bounds it
+		client.HTTP.Timeout = 0
+		pr, pw := io.Pipe()
+		go func() {
+			pw.CloseWithError(client.StreamTo(ctx, url, pw))
+		}()
+		return motionInput{args: []string{"-f", "mjpeg", "-i", "pipe:0"}, stdin: pr}, nil
+	case httpcam.SourceSnapshot:
+		return motionInput{}, fmt.Errorf("watch needs an RTSP or MJPEG source; camera %q is snapshot-only", cam.Name)
+	}
+
+	if path == "" {
+		cam.Stream = stream
+	}
+	url, err := streamURL(cam)
+	if err != nil {
+		return motionInput{}, err
+	}
+	return motionInput{args: []string{"-rtsp_transport", transport, "-i", url}, transport: transport}, nil
+}
+
+func watchMotion(ctx context.Context, cameraName string, input motionInput, threshold float64, cooldown time.Duration, action string, tmpl string, jsonOutput bool, cmd *cobra.Command) error {
 	ffArgs := []string{
 		"-hide_banner",
 		"-loglevel", "info",
-		"-rtsp_transport", transport,
-	}
-	if path != "" {
-		url = appendPath(url, path)
-	} else {
-		url = appendStream(url, stream)
 	}
+	ffArgs = append(ffArgs, input.args...)
 	ffArgs = append(ffArgs,
-		"-i", url,
 		"-an",
 		"-sn",
 		"-dn",
@@ -135,19 +183,26 @@ func watchMotion(ctx context.Context, cameraName, url string, threshold float64,
 		"-",
 	)
 
-	ff := osexec.CommandContext(ctx, "ffmpeg", ffArgs...)
-	stderr, err := ff.StderrPipe()
-	if err != nil {
-		return fmt.Errorf("stderr pipe: %w", err)
+	caps := media.Caps(ctx)
+	for _, f := range []string{"select", "metadata"} {
+		if !caps.HasFilter(f) {
+			return fmt.Errorf("this ffmpeg build has no %s filter, which scene detection needs (try --source onvif)", f)
+		}
+	}
+	if input.transport != "" {
+		if err := caps.CheckRTSPTransport(input.transport); err != nil {
+			return err
+		}
 	}
 
-	if err := ff.Start(); err != nil {
-		return fmt.Errorf("start ffmpeg: %w", err)
+	ff, err := media.Start(ctx, input.stdin, nil, ffArgs...)
+	if err != nil {
+		return err
 	}
 
 	lastTrigger := time.Time{}
 	var logBuf []string
-	scanner := bufio.NewScanner(stderr)
+	scanner := bufio.NewScanner(ff.Stderr())
 	for scanner.Scan() {
 		line := scanner.Text()
 		// keep last ~20 lines for error classification
@@ -159,18 +214,7 @@ func watchMotion(ctx context.Context, cameraName, url string, threshold float64,
 			now := time.Now()
 			if lastTrigger.IsZero() || now.Sub(lastTrigger) >= cooldown {
 				lastTrigger = now
-				if jsonOutput {
-					cmd.Printf(`{"event":"motion","camera":"%s","score":%.3f,"time":"%s"}\n`, cameraName, score, now.Format(time.RFC3339Nano))
-				} else {
-					cmd.Printf("event=motion camera=%s score=%.3f action=%q time=%s\n", cameraName, score, action, now.Format(time.RFC3339Nano))
-				}
-				act := action
-				if tmpl != "" {
-					if rendered, err := applyTemplate(tmpl, cameraName, score, now); err == nil {
-						act = rendered
-					}
-				}
-				runAction(ctx, act, score, now, cameraName)
+				triggerMotion(ctx, cmd, motionEvent{camera: cameraName, score: score, time: now}, action, tmpl, jsonOutput)
 			}
 		}
 	}
@@ -179,12 +223,42 @@ func watchMotion(ctx context.Context, cameraName, url string, threshold float64,
 	}
 
 	if err := ff.Wait(); err != nil && ctx.Err() == nil {
-		class := iexec.ClassifyError(strings.Join(logBuf, "\n"))
-		return fmt.Errorf("ffmpeg exited: %w (%s)", err, class)
+		kind := iexec.StderrKind(strings.Join(logBuf, "\n"))
+		return camerr.Wrap(kind, fmt.Errorf("ffmpeg exited: %w (%s)", err, camerr.Class(kind)))
 	}
 	return nil
 }
 
+// motionEvent is one detection, from ffmpeg scene scores or camera-native ONVIF events.
+type motionEvent struct {
+	camera string
+	score  float64
+	time   time.Time
+	topic  string // ONVIF topic; empty for scene detection
+}
+
+// triggerMotion logs an event and fires the action.
+func triggerMotion(ctx context.Context, cmd *cobra.Command, ev motionEvent, action, tmpl string, jsonOutput bool) {
+	ts := ev.time.Format(time.RFC3339Nano)
+	switch {
+	case jsonOutput && ev.topic != "":
+		cmd.Printf(`{"event":"motion","camera":"%s","score":%.3f,"time":"%s","topic":%q}`+"\n", ev.camera, ev.score, ts, ev.topic)
+	case jsonOutput:
+		cmd.Printf(`{"event":"motion","camera":"%s","score":%.3f,"time":"%s"}`+"\n", ev.camera, ev.score, ts)
+	case ev.topic != "":
+		cmd.Printf("event=motion camera=%s score=%.3f topic=%s action=%q time=%s\n", ev.camera, ev.score, ev.topic, action, ts)
+	default:
+		cmd.Printf("event=motion camera=%s score=%.3f action=%q time=%s\n", ev.camera, ev.score, action, ts)
+	}
+	act := action
+	if tmpl != "" {
+		if rendered, err := applyTemplate(tmpl, ev.camera, ev.score, ev.time); err == nil {
+			act = rendered
+		}
+	}
+	runAction(ctx, act, ev.score, ev.time, ev.camera)
+}
+
 func parseSceneScore(line string) (float64, bool) {
 	// looks like: "[Parsed_metadata_1 ...] scene_score=0.123"
 	if !strings.Contains(line, "scene_score=") {
@@ -232,3 +306,103 @@ func applyTemplate(tmpl, camera string, score float64, t time.Time) (string, err
 	}
 	return out, nil
 }
+
+// onvifSubscriptionTTL is how long a PullPoint subscription lives between renewals.
+const onvifSubscriptionTTL = time.Minute
+
+// Resubscribing after a lost subscription waits onvifResubscribeBackoff, doubling up to the max.
+var (
+	onvifResubscribeBackoff    = time.Second
+	onvifResubscribeMaxBackoff = 30 * time.Second
+)
+
+// watchONVIF turns camera-native ONVIF events into motion triggers; no ffmpeg or local decoding.
+func watchONVIF(ctx context.Context, cam config.Camera, onvifPort int, topics []string, cooldown time.Duration, action, tmpl string, jsonOutput bool, cmd *cobra.Command) error {
+	xaddr := cam.ONVIF
+	if xaddr == "" {
+		xaddr = onvifDeviceURL(cam.Host, onvifPort)
+	}
+	subscribe := func() (*discovery.Subscription, time.Time, error) {
+		sub, err := discovery.Subscribe(ctx, xaddr, cam.Username, cam.Password, onvifSubscriptionTTL)
+		if err != nil {
+			return nil, time.Time{}, fmt.Errorf("onvif events: %w", err)
+		}
+		// schedule renewals on our clock; the device's TerminationTime may be skewed
+		return sub, time.Now().Add(onvifSubscriptionTTL / 2), nil
+	}
+	sub, renewAt, err := subscribe()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if sub == nil {
+			return
+		}
+		uctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = sub.Unsubscribe(uctx)
+	}()
+
+	lastTrigger := time.Time{}
+	for ctx.Err() == nil {
+		if time.Now().After(renewAt) {
+			// devices that extend on every pull reject Renew; a failed pull below resubscribes
+			_ = sub.Renew(ctx, onvifSubscriptionTTL)
+			renewAt = time.Now().Add(onvifSubscriptionTTL / 2)
+		}
+		events, err := sub.Pull(ctx, 10*time.Second, 32)
+		if err != nil {
+			// a camera reboot or Wi-Fi drop should not end a long-running watch
+			backoff := onvifResubscribeBackoff
+			for ctx.Err() == nil {
+				sty := newStyler(cmd.ErrOrStderr())
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "%s onvif events: %v; resubscribing in %s\n", sty.Warn("!"), err, backoff)
+				select {
+				case <-ctx.Done():
+				case <-time.After(backoff):
+				}
+				if ctx.Err() != nil {
+					break
+				}
+				if sub, renewAt, err = subscribe(); err == nil {
+					break
+				}
+				backoff = min(backoff*2, onvifResubscribeMaxBackoff)
+			}
+			continue
+		}
+		for _, ev := range events {
+			if !onvifMotion(ev, topics) {
+				continue
+			}
+			now := time.Now()
+			if !lastTrigger.IsZero() && now.Sub(lastTrigger) < cooldown {
+				continue
+			}
+			lastTrigger = now
+			triggerMotion(ctx, cmd, motionEvent{camera: cam.Name, score: 1, time: now, topic: ev.Topic}, action, tmpl, jsonOutput)
+		}
+	}
+	return nil
+}
+
+// defaultMotionTopics are the detection topics watch reacts to without --topic; device topics
+// such as Monitoring/ProcessorUsage or Device/Trigger/DigitalInput are left out.
+var defaultMotionTopics = []string{"RuleEngine", "VideoSource/MotionAlarm", "VideoAnalytics"}
+
+// onvifMotion reports whether an event should fire the action: an active detection whose topic
+// matches one of the filters. Initialized events only report state at subscribe time and are skipped.
+func onvifMotion(ev discovery.Event, topics []string) bool {
+	if ev.Operation == "Initialized" || !ev.Active() {
+		return false
+	}
+	if len(topics) == 0 {
+		topics = defaultMotionTopics
+	}
+	for _, t := range topics {
+		if strings.Contains(strings.ToLower(ev.Topic), strings.ToLower(t)) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,6 +1,10 @@
 package cli
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/steipete/camsnap/internal/discovery"
+)
 
 func TestParseSceneScore(t *testing.T) {
 	line := "[Parsed_metadata_1] scene_score=0.321 something"
@@ -15,3 +19,17 @@ func TestParseSceneScore(t *testing.T) {
 		t.Fatalf("expected no match")
 	}
 }
+
+func TestONVIFMotionDefaultTopics(t *testing.T) {
+	motion := discovery.Event{Topic: "tns1:RuleEngine/CellMotionDetector/Motion", Operation: "Changed", Data: map[string]string{"IsMotion": "true"}}
+	input := discovery.Event{Topic: "tns1:Device/Trigger/DigitalInput", Operation: "Changed", Data: map[string]string{"LogicalState": "true"}}
+	if !onvifMotion(motion, nil) {
+		t.Fatalf("motion should fire by default")
+	}
+	if onvifMotion(input, nil) {
+		t.Fatalf("digital inputs should need --topic")
+	}
+	if !onvifMotion(input, []string{"DigitalInput"}) {
+		t.Fatalf("--topic should select digital inputs")
+	}
+}
@@ -12,18 +12,42 @@ import (
 
 // Camera represents a single camera entry stored on disk.
 type Camera struct {
-	Name          string `yaml:"name"`
-	Host          string `yaml:"host"`
-	Port          int    `yaml:"port"`
-	Protocol      string `yaml:"protocol"`
-	Username      string `yaml:"username"`
-	Password      string `yaml:"password"`
-	Path          string `yaml:"path,omitempty"`           // explicit RTSP path (e.g., /Bfy... from UniFi Protect)
-	RTSPTransport string `yaml:"rtsp_transport,omitempty"` // tcp|udp
-	Stream        string `yaml:"stream,omitempty"`         // stream1|stream2
-	RTSPClient    string `yaml:"rtsp_client,omitempty"`    // ffmpeg|gortsplib
-	NoAudio       bool   `yaml:"no_audio,omitempty"`
-	AudioCodec    string `yaml:"audio_codec,omitempty"` // e.g., aac
+	Name          string    `yaml:"name"`
+	Host          string    `yaml:"host"`
+	Port          int       `yaml:"port"`
+	Protocol      string    `yaml:"protocol"`
+	Source        string    `yaml:"source,omitempty"` // rtsp (default)|snapshot|mjpeg; the latter two use http/https
+	Username      string    `yaml:"username"`
+	Password      string    `yaml:"password"`
+	Path          string    `yaml:"path,omitempty"`           // explicit RTSP path (e.g., /Bfy... from UniFi Protect)
+	Query         string    `yaml:"query,omitempty"`          // raw RTSP query string (e.g., enableSrtp)
+	RTSPTransport string    `yaml:"rtsp_transport,omitempty"` // tcp|udp
+	Stream        string    `yaml:"stream,omitempty"`         // stream1|stream2
+	RTSPClient    string    `yaml:"rtsp_client,omitempty"`    // ffmpeg|gortsplib
+	RTSPCodec     string    `yaml:"rtsp_codec,omitempty"`     // h264|h265|mjpeg (gortsplib track preference)
+	NoAudio       bool      `yaml:"no_audio,omitempty"`
+	AudioCodec    string    `yaml:"audio_codec,omitempty"` // e.g., aac
+	Vendor        string    `yaml:"vendor,omitempty"`      // URL preset: tapo|hikvision|dahua|amcrest|reolink|axis
+	Channel       int       `yaml:"channel,omitempty"`     // NVR/encoder channel for vendor presets (default 1)
+	Substream     bool      `yaml:"substream,omitempty"`   // use the vendor preset's low-res stream
+	ONVIF         string    `yaml:"onvif,omitempty"`       // ONVIF device service XAddr
+	Endpoint      string    `yaml:"endpoint,omitempty"`    // WS-Discovery endpoint UUID, used to follow IP changes
+	Profiles      []Profile `yaml:"profiles,omitempty"`    // ONVIF media profiles, first is the default
+}
+
+// Profile is an ONVIF media profile resolved to an RTSP path.
+type Profile struct {
+	Name        string `yaml:"name"`
+	Token       string `yaml:"token"`
+	Port        int    `yaml:"port,omitempty"`
+	Protocol    string `yaml:"protocol,omitempty"` // rtsp|rtsps
+	Path        string `yaml:"path"`
+	Query       string `yaml:"query,omitempty"`
+	Codec       string `yaml:"codec,omitempty"` // h264|h265|mjpeg
+	Width       int    `yaml:"width,omitempty"`
+	Height      int    `yaml:"height,omitempty"`
+	FPS         int    `yaml:"fps,omitempty"`
+	SnapshotURL string `yaml:"snapshot_url,omitempty"`
 }
 
 // Config is the root configuration struct.
@@ -0,0 +1,130 @@
+package discovery
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/net/ipv4"
+)
+
+// Announcement kinds.
+const (
+	Hello = "hello"
+	Bye   = "bye"
+)
+
+// Announcement is a WS-Discovery Hello or Bye multicast by a device joining or leaving the network.
+type Announcement struct {
+	Kind   string // Hello or Bye
+	Device Device // Bye usually carries only the endpoint
+}
+
+// Listen joins the WS-Discovery multicast group on the selected interfaces and calls fn for each
+// camera Hello and each Bye until ctx is done. Hellos from non-camera devices (printers, PCs) are dropped.
+func Listen(ctx context.Context, opts Options, fn func(Announcement)) error {
+	ifaces, err := multicastInterfaces(opts.Interfaces)
+	if err != nil {
+		return err
+	}
+	group, err := net.ResolveUDPAddr("udp4", wsdAddr)
+	if err != nil {
+		return fmt.Errorf("resolve multicast: %w", err)
+	}
+	lc := net.ListenConfig{Control: reuseAddr}
+	pconn, err := lc.ListenPacket(ctx, "udp4", fmt.Sprintf("0.0.0.0:%d", wsdPort))
+	if err != nil {
+		return fmt.Errorf("listen %s: %w", wsdAddr, err)
+	}
+	defer func() {
+		_ = pconn.Close()
+	}()
+	pc := ipv4.NewPacketConn(pconn)
+	joined := 0
+	for i := range ifaces {
+		if err := pc.JoinGroup(&ifaces[i], group); err == nil {
+			joined++
+		}
+	}
+	if joined == 0 {
+		// no usable interface list (e.g. containers); let the kernel pick one
+		if err := pc.JoinGroup(nil, group); err != nil {
+			return fmt.Errorf("join %s: %w", wsdAddr, err)
+		}
+	}
+	// the control message tells us which interface each announcement arrived on
+	_ = pc.SetControlMessage(ipv4.FlagInterface, true)
+
+	buf := make([]byte, 8192)
+	for ctx.Err() == nil {
+		// wake up periodically to notice cancellation
+		if err := pc.SetReadDeadline(time.Now().Add(500 * time.Millisecond)); err != nil {
+			return fmt.Errorf("set deadline: %w", err)
+		}
+		n, cm, _, err := pc.ReadFrom(buf)
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				continue
+			}
+			return fmt.Errorf("read udp: %w", err)
+		}
+		a, ok := parseAnnouncement(buf[:n])
+		if !ok {
+			continue
+		}
+		a.Device.Host = hostPort(a.Device.Address)
+		if cm != nil {
+			if ifi, err := net.InterfaceByIndex(cm.IfIndex); err == nil {
+				a.Device.Interface = ifi.Name
+			}
+		}
+		fn(a)
+	}
+	return nil
+}
+
+type announcementEnvelope struct {
+	XMLName xml.Name `xml:"Envelope"`
+	Body    struct {
+		Hello *announcementBody `xml:"Hello"`
+		Bye   *announcementBody `xml:"Bye"`
+	} `xml:"Body"`
+}
+
+type announcementBody struct {
+	Endpoint string `xml:"EndpointReference>Address"`
+	Types    string `xml:"Types"`
+	XAddrs   string `xml:"XAddrs"`
+}
+
+// parseAnnouncement decodes a Hello or Bye; probes and probe matches are ignored.
+func parseAnnouncement(data []byte) (Announcement, bool) {
+	var env announcementEnvelope
+	if err := xml.Unmarshal(data, &env); err != nil {
+		return Announcement{}, false
+	}
+	var a Announcement
+	var body *announcementBody
+	switch {
+	case env.Body.Hello != nil:
+		a.Kind, body = Hello, env.Body.Hello
+		if body.Types != "" && !strings.Contains(body.Types, "NetworkVideoTransmitter") {
+			return Announcement{}, false
+		}
+	case env.Body.Bye != nil:
+		a.Kind, body = Bye, env.Body.Bye
+	default:
+		return Announcement{}, false
+	}
+	a.Device.Endpoint = EndpointID(body.Endpoint)
+	if addrs := strings.Fields(body.XAddrs); len(addrs) > 0 {
+		a.Device.Address = addrs[0]
+	}
+	if a.Device.Endpoint == "" && a.Device.Address == "" {
+		return Announcement{}, false
+	}
+	return a, true
+}
@@ -0,0 +1,92 @@
+package discovery
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// clockOffsets caches device clock offsets (device minus local) per host, so every service on a
+// device shares one GetSystemDateAndTime round trip.
+var clockOffsets sync.Map
+
+// clockRetry is how long a failed clock read counts the device as in sync before asking again.
+var clockRetry = time.Minute
+
+// clockEntry is a cached offset; retryAt is set when the read failed and the offset is a guess.
+type clockEntry struct {
+	offset  time.Duration
+	retryAt time.Time
+}
+
+// FetchSystemTime calls the unauthenticated GetSystemDateAndTime and returns the device's UTC clock.
+func FetchSystemTime(ctx context.Context, xaddr string) (time.Time, error) {
+	var resp struct {
+		XMLName xml.Name `xml:"GetSystemDateAndTimeResponse"`
+		UTC     struct {
+			Date struct {
+				Year  int `xml:"Year"`
+				Month int `xml:"Month"`
+				Day   int `xml:"Day"`
+			} `xml:"Date"`
+			Time struct {
+				Hour   int `xml:"Hour"`
+				Minute int `xml:"Minute"`
+				Second int `xml:"Second"`
+			} `xml:"Time"`
+		} `xml:"SystemDateAndTime>UTCDateTime"`
+	}
+	body := `<tds:GetSystemDateAndTime xmlns:tds="` + nsDevice + `"/>`
+	if err := callSOAP(ctx, xaddr, "", "", body, &resp); err != nil {
+		return time.Time{}, fmt.Errorf("get system date and time: %w", err)
+	}
+	d, t := resp.UTC.Date, resp.UTC.Time
+	if d.Year == 0 {
+		return time.Time{}, fmt.Errorf("get system date and time: device did not report UTC time")
+	}
+	return time.Date(d.Year, time.Month(d.Month), d.Day, t.Hour, t.Minute, t.Second, 0, time.UTC), nil
+}
+
+// ClockOffset measures how far the device clock is ahead of ours (negative when it lags).
+// The device reports whole seconds, so the result is accurate to about a second.
+func ClockOffset(ctx context.Context, xaddr string) (time.Duration, error) {
+	start := time.Now()
+	device, err := FetchSystemTime(ctx, xaddr)
+	if err != nil {
+		return 0, err
+	}
+	// compare against the middle of the round trip
+	local := start.Add(time.Since(start) / 2)
+	offset := device.Sub(local).Round(time.Second)
+	clockOffsets.Store(clockKey(xaddr), clockEntry{offset: offset})
+	return offset, nil
+}
+
+// deviceOffset returns the cached offset for a device, measuring it on first use.
+// Devices that do not answer GetSystemDateAndTime are treated as in sync until clockRetry has
+// passed, so a transient failure does not stick for the whole run.
+func deviceOffset(ctx context.Context, xaddr string) time.Duration {
+	key := clockKey(xaddr)
+	if v, ok := clockOffsets.Load(key); ok {
+		e := v.(clockEntry)
+		if e.retryAt.IsZero() || time.Now().Before(e.retryAt) {
+			return e.offset
+		}
+	}
+	offset, err := ClockOffset(ctx, xaddr)
+	if err != nil {
+		clockOffsets.Store(key, clockEntry{retryAt: time.Now().Add(clockRetry)})
+		return 0
+	}
+	return offset
+}
+
+func clockKey(xaddr string) string {
+	if u, err := url.Parse(xaddr); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return xaddr
+}
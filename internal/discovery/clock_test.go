@@ -0,0 +1,74 @@
+package discovery
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/steipete/camsnap/internal/discovery/onviftest"
+)
+
+func TestClockOffset(t *testing.T) {
+	srv := onviftest.NewServer()
+	defer srv.Close()
+	srv.SetClockOffset(-10 * time.Minute)
+
+	offset, err := ClockOffset(context.Background(), srv.DeviceURL())
+	if err != nil {
+		t.Fatalf("ClockOffset: %v", err)
+	}
+	if d := offset + 10*time.Minute; d < -2*time.Second || d > 2*time.Second {
+		t.Fatalf("expected about -10m, got %v", offset)
+	}
+}
+
+func TestWSSecurityUsesDeviceClock(t *testing.T) {
+	srv := onviftest.NewServer()
+	defer srv.Close()
+	srv.SetClockOffset(2 * time.Hour)
+
+	if _, err := FetchDeviceInfo(context.Background(), srv.DeviceURL(), "admin", "secret"); err != nil {
+		t.Fatalf("FetchDeviceInfo: %v", err)
+	}
+	calls := srv.Calls()
+	if len(calls) != 2 || calls[0] != "GetSystemDateAndTime" || calls[1] != "GetDeviceInformation" {
+		t.Fatalf("expected the clock to be read before the authenticated call, got %v", calls)
+	}
+	m := regexp.MustCompile(`<wsu:Created>([^<]+)</wsu:Created>`).FindStringSubmatch(srv.Requests()[1])
+	if m == nil {
+		t.Fatalf("no UsernameToken in request")
+	}
+	created, err := time.Parse(time.RFC3339Nano, m[1])
+	if err != nil {
+		t.Fatalf("parse Created: %v", err)
+	}
+	if d := time.Until(created) - 2*time.Hour; d < -2*time.Second || d > 2*time.Second {
+		t.Fatalf("Created %v is not in device time", created)
+	}
+}
+
+func TestDeviceOffsetRetriesAfterFailure(t *testing.T) {
+	srv := onviftest.NewServer()
+	defer srv.Close()
+	srv.SetClockOffset(time.Hour)
+	srv.SetResponses("GetSystemDateAndTime", "", onviftest.Recording("GetSystemDateAndTime"))
+
+	prev := clockRetry
+	clockRetry = 50 * time.Millisecond
+	defer func() { clockRetry = prev }()
+
+	if got := deviceOffset(context.Background(), srv.DeviceURL()); got != 0 {
+		t.Fatalf("failed read should count as in sync, got %v", got)
+	}
+	if got := deviceOffset(context.Background(), srv.DeviceURL()); got != 0 {
+		t.Fatalf("failure should be cached until the retry, got %v", got)
+	}
+	if n := len(srv.Calls()); n != 1 {
+		t.Fatalf("expected one clock read before the retry, got %d", n)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if got := deviceOffset(context.Background(), srv.DeviceURL()); got < 59*time.Minute {
+		t.Fatalf("expected the clock to be read again after the retry, got %v", got)
+	}
+}
@@ -10,10 +10,15 @@ import (
 	"net/url"
 	"strings"
 	"time"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
 )
 
 const (
 	wsdAddr       = "239.255.255.250:3702"
+	wsdAddr6      = "ff02::c"
+	wsdPort       = 3702
 	probeTemplate = `<?xml version="1.0" encoding="UTF-8"?>
 <e:Envelope xmlns:e="http://www.w3.org/2003/05/soap-envelope"
             xmlns:w="http://schemas.xmlsoap.org/ws/2004/08/addressing"
@@ -33,14 +38,29 @@ const (
 
 // Device represents a discovered device.
 type Device struct {
-	Address string // full XAddr
-	Host    string // host:port extracted from XAddr
-	Model   string
-	FW      string
+	Address   string // full XAddr
+	Host      string // host:port extracted from XAddr
+	Endpoint  string // endpoint reference UUID; stable across IP changes
+	Interface string // local interface the device answered on
+	Model     string
+	FW        string
+}
+
+// Options selects where probes go out.
+type Options struct {
+	Interfaces []string // interface names; empty means every up, multicast-capable, non-loopback interface
+	TTL        int      // multicast TTL / hop limit; 0 means 1 (local segment only)
 }
 
-// Discover performs a WS-Discovery probe for ONVIF devices.
+// Discover performs a WS-Discovery probe for ONVIF devices over IPv4 and IPv6 on every interface.
 func Discover(ctx context.Context, timeout time.Duration) ([]Device, error) {
+	return DiscoverWith(ctx, timeout, Options{})
+}
+
+// DiscoverWith probes on each selected interface with its own socket, so Docker bridges, VPNs and
+// VLANs do not swallow the probe via the default route. IPv6 probing is best-effort; hosts
+// without IPv6 multicast still get IPv4 results.
+func DiscoverWith(ctx context.Context, timeout time.Duration, opts Options) ([]Device, error) {
 	if timeout <= 0 {
 		timeout = 3 * time.Second
 	}
@@ -48,17 +68,121 @@ func Discover(ctx context.Context, timeout time.Duration) ([]Device, error) {
 	if dl, ok := ctx.Deadline(); ok && dl.Before(deadline) {
 		deadline = dl
 	}
+	ifaces, err := multicastInterfaces(opts.Interfaces)
+	if err != nil {
+		return nil, err
+	}
+	ttl := opts.TTL
+	if ttl <= 0 {
+		ttl = 1
+	}
 
-	localAddr, err := net.ResolveUDPAddr("udp4", ":0")
+	msgID := fmt.Sprintf("%08x-%04x-%04x-%04x-%012x",
+		rand.Uint32(),
+		rand.Uint32()&0xffff,
+		rand.Uint32()&0xffff,
+		rand.Uint32()&0xffff,
+		rand.Uint64()&0xffffffffffff,
+	)
+	probe := []byte(fmt.Sprintf(probeTemplate, msgID))
+
+	v6 := make(chan []Device, 1)
+	go func() {
+		devs, _ := probeIPv6(ctx, probe, deadline, ifaces, ttl)
+		v6 <- devs
+	}()
+
+	devices, err := probeIPv4(ctx, probe, deadline, ifaces, ttl, len(opts.Interfaces) == 0)
+	devices = append(devices, <-v6...)
+	if err != nil {
+		return uniqueDevices(devices), err
+	}
+	return uniqueDevices(devices), nil
+}
+
+// multicastInterfaces resolves interface names, or lists every usable interface when none are given.
+func multicastInterfaces(names []string) ([]net.Interface, error) {
+	if len(names) > 0 {
+		out := make([]net.Interface, 0, len(names))
+		for _, name := range names {
+			ifi, err := net.InterfaceByName(name)
+			if err != nil {
+				return nil, fmt.Errorf("interface %q: %w", name, err)
+			}
+			if ifi.Flags&net.FlagUp == 0 || ifi.Flags&net.FlagMulticast == 0 {
+				return nil, fmt.Errorf("interface %q is down or not multicast-capable", name)
+			}
+			out = append(out, *ifi)
+		}
+		return out, nil
+	}
+	all, err := net.Interfaces()
 	if err != nil {
-		return nil, fmt.Errorf("resolve udp: %w", err)
+		return nil, fmt.Errorf("list interfaces: %w", err)
 	}
+	var out []net.Interface
+	for _, ifi := range all {
+		if ifi.Flags&net.FlagUp == 0 || ifi.Flags&net.FlagMulticast == 0 || ifi.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		out = append(out, ifi)
+	}
+	return out, nil
+}
+
+// probeIPv4 sends one probe per interface that has an IPv4 address. With fallback set and no such
+// interface, it probes once via the default route.
+func probeIPv4(ctx context.Context, probe []byte, deadline time.Time, ifaces []net.Interface, ttl int, fallback bool) ([]Device, error) {
+	var targets []*net.Interface
+	for i := range ifaces {
+		if hasIPv4(ifaces[i]) {
+			targets = append(targets, &ifaces[i])
+		}
+	}
+	if len(targets) == 0 {
+		if !fallback {
+			return nil, fmt.Errorf("no IPv4 address on the selected interfaces")
+		}
+		targets = []*net.Interface{nil}
+	}
+
+	type result struct {
+		devices []Device
+		err     error
+	}
+	results := make(chan result, len(targets))
+	for _, ifi := range targets {
+		go func(ifi *net.Interface) {
+			devs, err := probeIPv4Iface(ctx, probe, deadline, ifi, ttl)
+			results <- result{devs, err}
+		}(ifi)
+	}
+	var devices []Device
+	var firstErr error
+	failed := 0
+	for range targets {
+		r := <-results
+		devices = append(devices, r.devices...)
+		if r.err != nil {
+			failed++
+			if firstErr == nil {
+				firstErr = r.err
+			}
+		}
+	}
+	if failed == len(targets) {
+		return devices, firstErr
+	}
+	return devices, nil
+}
+
+// probeIPv4Iface probes from a socket pinned to one interface (nil means the default route).
+func probeIPv4Iface(ctx context.Context, probe []byte, deadline time.Time, ifi *net.Interface, ttl int) ([]Device, error) {
 	remoteAddr, err := net.ResolveUDPAddr("udp4", wsdAddr)
 	if err != nil {
 		return nil, fmt.Errorf("resolve multicast: %w", err)
 	}
-
-	conn, err := net.ListenUDP("udp4", localAddr)
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4zero})
 	if err != nil {
 		return nil, fmt.Errorf("listen udp: %w", err)
 	}
@@ -66,19 +190,87 @@ func Discover(ctx context.Context, timeout time.Duration) ([]Device, error) {
 		_ = conn.Close()
 	}()
 
-	msgID := fmt.Sprintf("%08x-%04x-%04x-%04x-%012x",
-		rand.Uint32(),
-		rand.Uint32()&0xffff,
-		rand.Uint32()&0xffff,
-		rand.Uint32()&0xffff,
-		rand.Uint64()&0xffffffffffff,
-	)
-	probe := fmt.Sprintf(probeTemplate, msgID)
-
-	if _, err := conn.WriteToUDP([]byte(probe), remoteAddr); err != nil {
+	name := ""
+	pc := ipv4.NewPacketConn(conn)
+	if ifi != nil {
+		name = ifi.Name
+		if err := pc.SetMulticastInterface(ifi); err != nil {
+			return nil, fmt.Errorf("%s: set multicast interface: %w", name, err)
+		}
+	}
+	if err := pc.SetMulticastTTL(ttl); err != nil {
+		return nil, fmt.Errorf("set multicast ttl: %w", err)
+	}
+	if _, err := conn.WriteToUDP(probe, remoteAddr); err != nil {
+		if name != "" {
+			return nil, fmt.Errorf("%s: send probe: %w", name, err)
+		}
 		return nil, fmt.Errorf("send probe: %w", err)
 	}
+	devices, err := readMatches(ctx, conn, deadline)
+	for i := range devices {
+		devices[i].Interface = name
+	}
+	return devices, err
+}
+
+// probeIPv6 sends the probe to the link-local [FF02::C]:3702 group on every selected interface.
+func probeIPv6(ctx context.Context, probe []byte, deadline time.Time, ifaces []net.Interface, hops int) ([]Device, error) {
+	conn, err := net.ListenUDP("udp6", &net.UDPAddr{IP: net.IPv6unspecified})
+	if err != nil {
+		return nil, fmt.Errorf("listen udp6: %w", err)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	if err := ipv6.NewPacketConn(conn).SetMulticastHopLimit(hops); err != nil {
+		return nil, fmt.Errorf("set multicast hop limit: %w", err)
+	}
+
+	sent := false
+	for _, ifi := range ifaces {
+		group := &net.UDPAddr{IP: net.ParseIP(wsdAddr6), Port: wsdPort, Zone: ifi.Name}
+		if _, err := conn.WriteToUDP(probe, group); err == nil {
+			sent = true
+		}
+	}
+	if !sent {
+		return nil, fmt.Errorf("send probe: no IPv6 multicast interface")
+	}
+	devices, err := readMatches(ctx, conn, deadline)
+	for i := range devices {
+		// replies to a link-local probe arrive zoned with the receiving interface
+		if zone := zoneOf(devices[i].Host); zone != "" {
+			devices[i].Interface = zone
+		}
+	}
+	return devices, err
+}
+
+func hasIPv4(ifi net.Interface) bool {
+	addrs, err := ifi.Addrs()
+	if err != nil {
+		return false
+	}
+	for _, a := range addrs {
+		if ipn, ok := a.(*net.IPNet); ok && ipn.IP.To4() != nil {
+			return true
+		}
+	}
+	return false
+}
 
+func zoneOf(host string) string {
+	if i := strings.IndexByte(host, '%'); i >= 0 {
+		if j := strings.IndexByte(host[i:], ']'); j >= 0 {
+			return host[i+1 : i+j]
+		}
+	}
+	return ""
+}
+
+// readMatches collects ProbeMatch responses until the deadline.
+func readMatches(ctx context.Context, conn *net.UDPConn, deadline time.Time) ([]Device, error) {
 	if err := conn.SetReadDeadline(deadline); err != nil {
 		return nil, fmt.Errorf("set deadline: %w", err)
 	}
@@ -89,7 +281,7 @@ func Discover(ctx context.Context, timeout time.Duration) ([]Device, error) {
 		if ctx.Err() != nil {
 			break
 		}
-		n, _, err := conn.ReadFromUDP(buf)
+		n, src, err := conn.ReadFromUDP(buf)
 		if err != nil {
 			if ne, ok := err.(net.Error); ok && ne.Timeout() {
 				break
@@ -101,15 +293,13 @@ func Discover(ctx context.Context, timeout time.Duration) ([]Device, error) {
 			// ignore malformed responses
 			continue
 		}
-		for _, addr := range matches {
-			devices = append(devices, Device{
-				Address: addr,
-				Host:    hostPort(addr),
-			})
+		for _, d := range matches {
+			d.Address = withZone(d.Address, src.Zone)
+			d.Host = hostPort(d.Address)
+			devices = append(devices, d)
 		}
 	}
-
-	return uniqueDevices(devices), nil
+	return devices, nil
 }
 
 type probeMatches struct {
@@ -122,22 +312,34 @@ type probeMatchBody struct {
 }
 
 type singleMatch struct {
-	XAddrs string `xml:"XAddrs"`
+	Endpoint string `xml:"EndpointReference>Address"`
+	XAddrs   string `xml:"XAddrs"`
 }
 
-func parseProbeMatch(data []byte) ([]string, error) {
+// parseProbeMatch returns one device per advertised XAddr (Address and Endpoint set).
+func parseProbeMatch(data []byte) ([]Device, error) {
 	var env probeMatches
 	if err := xml.Unmarshal(data, &env); err != nil {
 		return nil, err
 	}
-	var addrs []string
+	var devices []Device
 	for _, m := range env.Body.Matches {
-		addrs = append(addrs, strings.Fields(m.XAddrs)...)
+		for _, addr := range strings.Fields(m.XAddrs) {
+			devices = append(devices, Device{Address: addr, Endpoint: EndpointID(m.Endpoint)})
+		}
 	}
-	if len(addrs) == 0 {
+	if len(devices) == 0 {
 		return nil, fmt.Errorf("no addresses")
 	}
-	return addrs, nil
+	return devices, nil
+}
+
+// EndpointID normalizes a WS-Discovery endpoint reference (urn:uuid:..., uuid:... or a bare GUID)
+// to a lowercase UUID so announcements and GetEndpointReference results compare equal.
+func EndpointID(ref string) string {
+	id := strings.ToLower(strings.TrimSpace(ref))
+	id = strings.TrimPrefix(id, "urn:")
+	return strings.TrimPrefix(id, "uuid:")
 }
 
 func uniqueDevices(devs []Device) []Device {
@@ -167,3 +369,25 @@ func hostPort(addr string) string {
 	}
 	return ""
 }
+
+// withZone adds the receiving interface zone to link-local IPv6 XAddrs, which are unusable without it.
+func withZone(addr, zone string) string {
+	if zone == "" {
+		return addr
+	}
+	u, err := url.Parse(addr)
+	if err != nil || !strings.HasPrefix(u.Host, "[") {
+		return addr
+	}
+	ip := net.ParseIP(u.Hostname())
+	if ip == nil || !ip.IsLinkLocalUnicast() || strings.Contains(u.Hostname(), "%") {
+		return addr
+	}
+	// url.URL.String escapes the zone separator as %25
+	host := "[" + u.Hostname() + "%" + zone + "]"
+	if p := u.Port(); p != "" {
+		host += ":" + p
+	}
+	u.Host = host
+	return u.String()
+}
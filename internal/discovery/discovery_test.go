@@ -69,3 +69,83 @@ func TestParseProbeMatchError(t *testing.T) {
 		t.Fatalf("expected error for bad xml")
 	}
 }
+
+func TestWithZone(t *testing.T) {
+	got := withZone("http://[fe80::1]:80/onvif/device_service", "eth0")
+	if hp := hostPort(got); hp != "[fe80::1%eth0]:80" {
+		t.Fatalf("expected zoned host, got %s (%s)", hp, got)
+	}
+	if got := withZone("http://192.168.1.50:2020/onvif", "eth0"); got != "http://192.168.1.50:2020/onvif" {
+		t.Fatalf("IPv4 address must be unchanged, got %s", got)
+	}
+	if got := withZone("http://[2001:db8::1]/onvif", "eth0"); got != "http://[2001:db8::1]/onvif" {
+		t.Fatalf("global IPv6 address must be unchanged, got %s", got)
+	}
+}
+
+const sampleHello = `<?xml version="1.0" encoding="UTF-8"?>
+<SOAP-ENV:Envelope xmlns:SOAP-ENV="http://www.w3.org/2003/05/soap-envelope"
+                   xmlns:wsa="http://schemas.xmlsoap.org/ws/2004/08/addressing"
+                   xmlns:wsdd="http://schemas.xmlsoap.org/ws/2005/04/discovery"
+                   xmlns:dn="http://www.onvif.org/ver10/network/wsdl">
+  <SOAP-ENV:Header>
+    <wsa:Action>http://schemas.xmlsoap.org/ws/2005/04/discovery/Hello</wsa:Action>
+  </SOAP-ENV:Header>
+  <SOAP-ENV:Body>
+    <wsdd:Hello>
+      <wsa:EndpointReference><wsa:Address>urn:uuid:3FA1C2D4-0000-1111-2222-6C8A2F30AABB</wsa:Address></wsa:EndpointReference>
+      <wsdd:Types>dn:NetworkVideoTransmitter</wsdd:Types>
+      <wsdd:XAddrs>http://192.168.1.77:2020/onvif/device_service http://[fe80::1]:2020/onvif/device_service</wsdd:XAddrs>
+      <wsdd:MetadataVersion>1</wsdd:MetadataVersion>
+    </wsdd:Hello>
+  </SOAP-ENV:Body>
+</SOAP-ENV:Envelope>`
+
+func TestParseAnnouncement(t *testing.T) {
+	a, ok := parseAnnouncement([]byte(sampleHello))
+	if !ok {
+		t.Fatalf("expected hello to parse")
+	}
+	if a.Kind != Hello || a.Device.Endpoint != "3fa1c2d4-0000-1111-2222-6c8a2f30aabb" ||
+		a.Device.Address != "http://192.168.1.77:2020/onvif/device_service" {
+		t.Fatalf("unexpected announcement: %+v", a)
+	}
+
+	bye := strings.Replace(strings.Replace(sampleHello, "wsdd:Hello>", "wsdd:Bye>", 2), "discovery/Hello", "discovery/Bye", 1)
+	a, ok = parseAnnouncement([]byte(bye))
+	if !ok || a.Kind != Bye {
+		t.Fatalf("expected bye, got %+v", a)
+	}
+
+	printer := strings.Replace(sampleHello, "dn:NetworkVideoTransmitter", "wprt:PrintDeviceType", 1)
+	if _, ok := parseAnnouncement([]byte(printer)); ok {
+		t.Fatalf("non-camera hello should be ignored")
+	}
+	if _, ok := parseAnnouncement([]byte(sampleProbeMatch)); ok {
+		t.Fatalf("probe matches are not announcements")
+	}
+}
+
+func TestEndpointID(t *testing.T) {
+	for _, in := range []string{"urn:uuid:ABC-123", "uuid:abc-123", " abc-123 "} {
+		if got := EndpointID(in); got != "abc-123" {
+			t.Fatalf("EndpointID(%q) = %q", in, got)
+		}
+	}
+}
+
+func TestDiscoverUnknownInterface(t *testing.T) {
+	_, err := DiscoverWith(context.Background(), 10*time.Millisecond, Options{Interfaces: []string{"camsnap-nope0"}})
+	if err == nil || !strings.Contains(err.Error(), "camsnap-nope0") {
+		t.Fatalf("expected unknown interface error, got %v", err)
+	}
+}
+
+func TestZoneOf(t *testing.T) {
+	if z := zoneOf("[fe80::1%eth0]:80"); z != "eth0" {
+		t.Fatalf("expected eth0, got %q", z)
+	}
+	if z := zoneOf("192.168.1.50:2020"); z != "" {
+		t.Fatalf("expected no zone, got %q", z)
+	}
+}
@@ -0,0 +1,180 @@
+package discovery
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	actionPullMessages = "http://www.onvif.org/ver10/events/wsdl/PullPointSubscription/PullMessagesRequest"
+	actionRenew        = "http://docs.oasis-open.org/wsn/bw-2/SubscriptionManager/RenewRequest"
+	actionUnsubscribe  = "http://docs.oasis-open.org/wsn/bw-2/SubscriptionManager/UnsubscribeRequest"
+	nsNotification     = "http://docs.oasis-open.org/wsn/b-2"
+)
+
+// Event is one ONVIF notification, e.g. topic tns1:RuleEngine/CellMotionDetector/Motion with IsMotion=true.
+type Event struct {
+	Topic     string
+	Time      time.Time
+	Operation string // Initialized, Changed or Deleted
+	Source    map[string]string
+	Data      map[string]string
+}
+
+// pulseTopics are detectors that fire without a boolean state, e.g. a line crossing.
+var pulseTopics = []string{"LineDetector/Crossed", "TripWire", "LineCrossing"}
+
+// Active reports whether the event signals a detection. State events carry a boolean data item
+// (IsMotion, State, IsPeople...) and are active when it is true; events without one count only
+// when their topic is a known pulse detector, so CPU usage or counter reports never do.
+func (e Event) Active() bool {
+	for _, v := range e.Data {
+		if strings.EqualFold(strings.TrimSpace(v), "true") {
+			return true
+		}
+	}
+	for _, v := range e.Data {
+		if strings.EqualFold(strings.TrimSpace(v), "false") {
+			return false
+		}
+	}
+	for _, t := range pulseTopics {
+		if strings.Contains(e.Topic, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Subscription is an ONVIF PullPoint subscription.
+type Subscription struct {
+	Addr        string // subscription manager address
+	Username    string
+	Password    string
+	Termination time.Time
+}
+
+// Subscribe creates a PullPoint subscription on the device's event service.
+func Subscribe(ctx context.Context, xaddr, user, pass string, ttl time.Duration) (*Subscription, error) {
+	services, err := FetchServices(ctx, xaddr, user, pass)
+	if err != nil {
+		return nil, err
+	}
+	addr := services[nsEvents]
+	if addr == "" {
+		addr = xaddr
+	}
+	body := fmt.Sprintf(`<tev:CreatePullPointSubscription xmlns:tev="%s"><tev:InitialTerminationTime>%s</tev:InitialTerminationTime></tev:CreatePullPointSubscription>`,
+		nsEvents, xsdDuration(ttl))
+	var resp struct {
+		XMLName         xml.Name `xml:"CreatePullPointSubscriptionResponse"`
+		Address         string   `xml:"SubscriptionReference>Address"`
+		TerminationTime string   `xml:"TerminationTime"`
+	}
+	if err := callSOAP(ctx, addr, user, pass, body, &resp); err != nil {
+		return nil, fmt.Errorf("create pull point subscription: %w", err)
+	}
+	sub := &Subscription{
+		Addr:        strings.TrimSpace(resp.Address),
+		Username:    user,
+		Password:    pass,
+		Termination: parseTermination(resp.TerminationTime, ttl),
+	}
+	if sub.Addr == "" {
+		return nil, fmt.Errorf("create pull point subscription: no subscription address")
+	}
+	return sub, nil
+}
+
+// Pull waits up to timeout for at most limit events.
+func (s *Subscription) Pull(ctx context.Context, timeout time.Duration, limit int) ([]Event, error) {
+	body := fmt.Sprintf(`<tev:PullMessages xmlns:tev="%s"><tev:Timeout>%s</tev:Timeout><tev:MessageLimit>%d</tev:MessageLimit></tev:PullMessages>`,
+		nsEvents, xsdDuration(timeout), limit)
+	var resp pullMessagesResponse
+	// the device holds the request open for up to timeout, so allow for that on top of the usual budget
+	if err := callSOAPWith(ctx, s.Addr, s.Username, s.Password, wsaHeader(actionPullMessages, s.Addr), body, timeout+soapTimeout, &resp); err != nil {
+		return nil, fmt.Errorf("pull messages: %w", err)
+	}
+	if t := strings.TrimSpace(resp.TerminationTime); t != "" {
+		s.Termination = parseTermination(t, 0)
+	}
+	events := make([]Event, 0, len(resp.Messages))
+	for _, m := range resp.Messages {
+		ev := Event{
+			Topic:     strings.TrimSpace(m.Topic),
+			Operation: m.Message.Operation,
+			Source:    simpleItems(m.Message.Source),
+			Data:      simpleItems(m.Message.Data),
+		}
+		if t, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(m.Message.UTCTime)); err == nil {
+			ev.Time = t
+		}
+		events = append(events, ev)
+	}
+	return events, nil
+}
+
+// Renew extends the subscription by ttl.
+func (s *Subscription) Renew(ctx context.Context, ttl time.Duration) error {
+	body := fmt.Sprintf(`<wsnt:Renew xmlns:wsnt="%s"><wsnt:TerminationTime>%s</wsnt:TerminationTime></wsnt:Renew>`,
+		nsNotification, xsdDuration(ttl))
+	var resp struct {
+		XMLName         xml.Name `xml:"RenewResponse"`
+		TerminationTime string   `xml:"TerminationTime"`
+	}
+	if err := callSOAPWith(ctx, s.Addr, s.Username, s.Password, wsaHeader(actionRenew, s.Addr), body, soapTimeout, &resp); err != nil {
+		return fmt.Errorf("renew subscription: %w", err)
+	}
+	s.Termination = parseTermination(resp.TerminationTime, ttl)
+	return nil
+}
+
+// Unsubscribe releases the subscription on the device.
+func (s *Subscription) Unsubscribe(ctx context.Context) error {
+	body := `<wsnt:Unsubscribe xmlns:wsnt="` + nsNotification + `"/>`
+	if err := callSOAPWith(ctx, s.Addr, s.Username, s.Password, wsaHeader(actionUnsubscribe, s.Addr), body, soapTimeout, nil); err != nil {
+		return fmt.Errorf("unsubscribe: %w", err)
+	}
+	return nil
+}
+
+type simpleItem struct {
+	Name  string `xml:"Name,attr"`
+	Value string `xml:"Value,attr"`
+}
+
+type pullMessagesResponse struct {
+	XMLName         xml.Name `xml:"PullMessagesResponse"`
+	TerminationTime string   `xml:"TerminationTime"`
+	Messages        []struct {
+		Topic   string `xml:"Topic"`
+		Message struct {
+			UTCTime   string       `xml:"UtcTime,attr"`
+			Operation string       `xml:"PropertyOperation,attr"`
+			Source    []simpleItem `xml:"Source>SimpleItem"`
+			Data      []simpleItem `xml:"Data>SimpleItem"`
+		} `xml:"Message>Message"`
+	} `xml:"NotificationMessage"`
+}
+
+func simpleItems(items []simpleItem) map[string]string {
+	out := make(map[string]string, len(items))
+	for _, it := range items {
+		out[it.Name] = it.Value
+	}
+	return out
+}
+
+// parseTermination reads a TerminationTime, falling back to now+ttl when the device omits it.
+func parseTermination(v string, ttl time.Duration) time.Time {
+	if t, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(v)); err == nil {
+		return t
+	}
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(ttl)
+}
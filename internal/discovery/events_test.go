@@ -0,0 +1,71 @@
+package discovery
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/steipete/camsnap/internal/discovery/onviftest"
+)
+
+func TestPullPointSubscription(t *testing.T) {
+	srv := onviftest.NewServer()
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	sub, err := Subscribe(ctx, srv.DeviceURL(), "", "", time.Minute)
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	if sub.Addr != srv.URL+"/event-1_2020" {
+		t.Fatalf("unexpected subscription address %q", sub.Addr)
+	}
+
+	events, err := sub.Pull(ctx, time.Second, 10)
+	if err != nil {
+		t.Fatalf("Pull: %v", err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events, got %d", len(events))
+	}
+	initial, motion, people := events[0], events[1], events[2]
+	if initial.Operation != "Initialized" || initial.Active() {
+		t.Fatalf("initial state should be inactive: %+v", initial)
+	}
+	if motion.Topic != "tns1:RuleEngine/CellMotionDetector/Motion" || !motion.Active() || motion.Source["Rule"] != "MyMotionDetectorRule" {
+		t.Fatalf("unexpected motion event: %+v", motion)
+	}
+	if !motion.Time.Equal(time.Date(2024, 5, 4, 10, 0, 4, 0, time.UTC)) {
+		t.Fatalf("unexpected event time %v", motion.Time)
+	}
+	if people.Data["IsPeople"] != "true" {
+		t.Fatalf("unexpected people event: %+v", people)
+	}
+
+	events, err = sub.Pull(ctx, time.Second, 10)
+	if err != nil || len(events) != 0 {
+		t.Fatalf("expected empty pull, got %v (%v)", events, err)
+	}
+	if err := sub.Renew(ctx, time.Minute); err != nil {
+		t.Fatalf("Renew: %v", err)
+	}
+	if !sub.Termination.Equal(time.Date(2024, 5, 4, 10, 2, 0, 0, time.UTC)) {
+		t.Fatalf("termination not updated: %v", sub.Termination)
+	}
+	if err := sub.Unsubscribe(ctx); err != nil {
+		t.Fatalf("Unsubscribe: %v", err)
+	}
+}
+
+func TestEventActive(t *testing.T) {
+	if !(Event{Topic: "tns1:RuleEngine/LineDetector/Crossed", Data: map[string]string{"ObjectId": "4"}}).Active() {
+		t.Fatalf("pulse events without a boolean should be active")
+	}
+	if (Event{Data: map[string]string{"State": "false"}}).Active() {
+		t.Fatalf("State=false should be inactive")
+	}
+	if (Event{Topic: "tns1:Monitoring/ProcessorUsage", Data: map[string]string{"Value": "42.5"}}).Active() {
+		t.Fatalf("events without a boolean are only active for pulse detectors")
+	}
+}
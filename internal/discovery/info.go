@@ -1,15 +1,12 @@
 package discovery
 
 import (
-	"bytes"
 	"context"
 	"crypto/rand"
 	"crypto/sha1" //nolint:gosec
 	"encoding/base64"
 	"encoding/xml"
 	"fmt"
-	"io"
-	"net/http"
 	"strings"
 	"time"
 )
@@ -25,99 +22,35 @@ type DeviceInfo struct {
 
 // FetchDeviceInfo tries WS-Security UsernameToken first, then falls back to HTTP Basic.
 func FetchDeviceInfo(ctx context.Context, xaddr, user, pass string) (DeviceInfo, error) {
-	if xaddr == "" {
-		return DeviceInfo{}, fmt.Errorf("xaddr required")
-	}
-	soapBody := `<?xml version="1.0" encoding="UTF-8"?>
-<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope">
-  <s:Header>%s</s:Header>
-  <s:Body>
-    <tds:GetDeviceInformation xmlns:tds="http://www.onvif.org/ver10/device/wsdl"/>
-  </s:Body>
-</s:Envelope>`
-
-	client := &http.Client{Timeout: 5 * time.Second}
-
-	// WS-Security attempt when creds present.
-	if user != "" {
-		header := wsSecurityHeader(user, pass)
-		body := fmt.Sprintf(soapBody, header)
-		info, err := doInfoRequest(ctx, client, xaddr, body, "")
-		if err == nil {
-			return info, nil
-		}
-		if !isAuthError(err) {
-			return info, err
-		}
-	}
-
-	// Basic or anonymous
-	body := fmt.Sprintf(soapBody, "")
-	return doInfoRequest(ctx, client, xaddr, body, basicAuth(user, pass))
-}
-
-type infoEnvelope struct {
-	XMLName xml.Name `xml:"Envelope"`
-	Body    infoBody `xml:"Body"`
-}
-
-type infoBody struct {
-	DeviceInformation deviceInformation `xml:"GetDeviceInformationResponse"`
-}
-
-type deviceInformation struct {
-	Manufacturer    string `xml:"Manufacturer"`
-	Model           string `xml:"Model"`
-	FirmwareVersion string `xml:"FirmwareVersion"`
-	SerialNumber    string `xml:"SerialNumber"`
-	HardwareID      string `xml:"HardwareId"`
-}
-
-func doInfoRequest(ctx context.Context, client *http.Client, url, body, authHeader string) (DeviceInfo, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBufferString(body))
-	if err != nil {
+	var resp deviceInformation
+	body := `<tds:GetDeviceInformation xmlns:tds="http://www.onvif.org/ver10/device/wsdl"/>`
+	if err := callSOAP(ctx, xaddr, user, pass, body, &resp); err != nil {
 		return DeviceInfo{}, err
 	}
-	req.Header.Set("Content-Type", "application/soap+xml; charset=utf-8")
-	if authHeader != "" {
-		req.Header.Set("Authorization", authHeader)
-	}
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return DeviceInfo{}, err
-	}
-	defer func() {
-		_ = resp.Body.Close()
-	}()
-
-	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
-		return DeviceInfo{}, fmt.Errorf("auth failed (status %d)", resp.StatusCode)
-	}
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
-		return DeviceInfo{}, fmt.Errorf("device info status %d: %s", resp.StatusCode, strings.TrimSpace(string(bodyBytes)))
-	}
-
-	var envelope infoEnvelope
-	if err := xml.NewDecoder(resp.Body).Decode(&envelope); err != nil {
-		return DeviceInfo{}, fmt.Errorf("decode device info: %w", err)
-	}
-
 	return DeviceInfo{
-		Manufacturer: strings.TrimSpace(envelope.Body.DeviceInformation.Manufacturer),
-		Model:        strings.TrimSpace(envelope.Body.DeviceInformation.Model),
-		Firmware:     strings.TrimSpace(envelope.Body.DeviceInformation.FirmwareVersion),
-		Serial:       strings.TrimSpace(envelope.Body.DeviceInformation.SerialNumber),
-		HardwareID:   strings.TrimSpace(envelope.Body.DeviceInformation.HardwareID),
+		Manufacturer: strings.TrimSpace(resp.Manufacturer),
+		Model:        strings.TrimSpace(resp.Model),
+		Firmware:     strings.TrimSpace(resp.FirmwareVersion),
+		Serial:       strings.TrimSpace(resp.SerialNumber),
+		HardwareID:   strings.TrimSpace(resp.HardwareID),
 	}, nil
 }
 
-func wsSecurityHeader(user, pass string) string {
+type deviceInformation struct {
+	XMLName         xml.Name `xml:"GetDeviceInformationResponse"`
+	Manufacturer    string   `xml:"Manufacturer"`
+	Model           string   `xml:"Model"`
+	FirmwareVersion string   `xml:"FirmwareVersion"`
+	SerialNumber    string   `xml:"SerialNumber"`
+	HardwareID      string   `xml:"HardwareId"`
+}
+
+// wsSecurityHeader builds a UsernameToken digest stamped with createdAt, which should be in device time.
+func wsSecurityHeader(user, pass string, createdAt time.Time) string {
 	nonce := make([]byte, 16)
 	_, _ = rand.Read(nonce)
 	nonceB64 := base64.StdEncoding.EncodeToString(nonce)
-	created := time.Now().UTC().Format(time.RFC3339Nano)
+	created := createdAt.UTC().Format(time.RFC3339Nano)
 
 	h := sha1.New() //nolint:gosec
 	h.Write(nonce)
@@ -158,3 +91,16 @@ func escapeXML(s string) string {
 func isAuthError(err error) bool {
 	return strings.Contains(err.Error(), "auth failed")
 }
+
+// FetchEndpointReference returns the device's WS-Discovery endpoint UUID via GetEndpointReference.
+func FetchEndpointReference(ctx context.Context, xaddr, user, pass string) (string, error) {
+	var resp struct {
+		XMLName xml.Name `xml:"GetEndpointReferenceResponse"`
+		GUID    string   `xml:"GUID"`
+	}
+	body := `<tds:GetEndpointReference xmlns:tds="` + nsDevice + `"/>`
+	if err := callSOAP(ctx, xaddr, user, pass, body, &resp); err != nil {
+		return "", fmt.Errorf("get endpoint reference: %w", err)
+	}
+	return EndpointID(resp.GUID), nil
+}
@@ -0,0 +1,186 @@
+package discovery
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+const (
+	nsDevice = "http://www.onvif.org/ver10/device/wsdl"
+	nsMedia  = "http://www.onvif.org/ver10/media/wsdl"
+	nsPTZ    = "http://www.onvif.org/ver20/ptz/wsdl"
+	nsEvents = "http://www.onvif.org/ver10/events/wsdl"
+	nsSchema = "http://www.onvif.org/ver10/schema"
+)
+
+// MediaProfile is an ONVIF media profile with its resolved stream and snapshot URIs.
+type MediaProfile struct {
+	Token       string
+	Name        string
+	Encoding    string // H264, H265, JPEG
+	Width       int
+	Height      int
+	FPS         int
+	BitrateKbps int
+	StreamURI   string
+	SnapshotURI string
+}
+
+// Services lists the service endpoints a device advertises, keyed by ONVIF namespace.
+type Services map[string]string
+
+// Media returns the media (ver10) service address, if advertised.
+func (s Services) Media() string { return s[nsMedia] }
+
+// FetchServices calls GetServices, falling back to GetCapabilities for older devices.
+func FetchServices(ctx context.Context, xaddr, user, pass string) (Services, error) {
+	var svc getServicesResponse
+	body := `<tds:GetServices xmlns:tds="` + nsDevice + `"><tds:IncludeCapability>false</tds:IncludeCapability></tds:GetServices>`
+	err := callSOAP(ctx, xaddr, user, pass, body, &svc)
+	if err == nil && len(svc.Services) > 0 {
+		out := Services{}
+		for _, s := range svc.Services {
+			out[strings.TrimSpace(s.Namespace)] = strings.TrimSpace(s.XAddr)
+		}
+		return out, nil
+	}
+	if err != nil && isAuthError(err) {
+		return nil, err
+	}
+
+	var caps getCapabilitiesResponse
+	body = `<tds:GetCapabilities xmlns:tds="` + nsDevice + `"><tds:Category>All</tds:Category></tds:GetCapabilities>`
+	if err := callSOAP(ctx, xaddr, user, pass, body, &caps); err != nil {
+		return nil, fmt.Errorf("get capabilities: %w", err)
+	}
+	out := Services{nsDevice: xaddr}
+	if x := strings.TrimSpace(caps.Capabilities.Media.XAddr); x != "" {
+		out[nsMedia] = x
+	}
+	if x := strings.TrimSpace(caps.Capabilities.PTZ.XAddr); x != "" {
+		out[nsPTZ] = x
+	}
+	if x := strings.TrimSpace(caps.Capabilities.Events.XAddr); x != "" {
+		out[nsEvents] = x
+	}
+	return out, nil
+}
+
+// FetchProfiles resolves every media profile with its RTSP stream URI and snapshot URI.
+// Profiles whose stream URI cannot be resolved (metadata-only or audio profiles) are skipped.
+// Snapshot URIs are optional; devices without JPEG support leave SnapshotURI empty.
+func FetchProfiles(ctx context.Context, xaddr, user, pass string) ([]MediaProfile, error) {
+	services, err := FetchServices(ctx, xaddr, user, pass)
+	if err != nil {
+		return nil, err
+	}
+	mediaAddr := services.Media()
+	if mediaAddr == "" {
+		// many cameras serve every service on the device endpoint
+		mediaAddr = xaddr
+	}
+
+	var resp getProfilesResponse
+	if err := callSOAP(ctx, mediaAddr, user, pass, `<trt:GetProfiles xmlns:trt="`+nsMedia+`"/>`, &resp); err != nil {
+		return nil, fmt.Errorf("get profiles: %w", err)
+	}
+
+	profiles := make([]MediaProfile, 0, len(resp.Profiles))
+	var uriErr error
+	for _, p := range resp.Profiles {
+		vec := p.VideoEncoder
+		mp := MediaProfile{
+			Token:       p.Token,
+			Name:        strings.TrimSpace(p.Name),
+			Encoding:    strings.ToUpper(strings.TrimSpace(vec.Encoding)),
+			Width:       vec.Resolution.Width,
+			Height:      vec.Resolution.Height,
+			FPS:         vec.RateControl.FrameRateLimit,
+			BitrateKbps: vec.RateControl.BitrateLimit,
+		}
+		uri, err := fetchStreamURI(ctx, mediaAddr, user, pass, p.Token)
+		if err != nil {
+			if uriErr == nil {
+				uriErr = fmt.Errorf("get stream uri (%s): %w", p.Token, err)
+			}
+			continue
+		}
+		mp.StreamURI = uri
+		if snap, err := fetchSnapshotURI(ctx, mediaAddr, user, pass, p.Token); err == nil {
+			mp.SnapshotURI = snap
+		}
+		profiles = append(profiles, mp)
+	}
+	if len(profiles) == 0 && uriErr != nil {
+		return nil, uriErr
+	}
+	return profiles, nil
+}
+
+func fetchStreamURI(ctx context.Context, mediaAddr, user, pass, token string) (string, error) {
+	body := fmt.Sprintf(`<trt:GetStreamUri xmlns:trt="%s" xmlns:tt="%s">
+      <trt:StreamSetup>
+        <tt:Stream>RTP-Unicast</tt:Stream>
+        <tt:Transport><tt:Protocol>RTSP</tt:Protocol></tt:Transport>
+      </trt:StreamSetup>
+      <trt:ProfileToken>%s</trt:ProfileToken>
+    </trt:GetStreamUri>`, nsMedia, nsSchema, escapeXML(token))
+	var resp mediaURIResponse
+	if err := callSOAP(ctx, mediaAddr, user, pass, body, &resp); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(resp.URI), nil
+}
+
+func fetchSnapshotURI(ctx context.Context, mediaAddr, user, pass, token string) (string, error) {
+	body := fmt.Sprintf(`<trt:GetSnapshotUri xmlns:trt="%s"><trt:ProfileToken>%s</trt:ProfileToken></trt:GetSnapshotUri>`,
+		nsMedia, escapeXML(token))
+	var resp mediaURIResponse
+	if err := callSOAP(ctx, mediaAddr, user, pass, body, &resp); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(resp.URI), nil
+}
+
+type getServicesResponse struct {
+	XMLName  xml.Name `xml:"GetServicesResponse"`
+	Services []struct {
+		Namespace string `xml:"Namespace"`
+		XAddr     string `xml:"XAddr"`
+	} `xml:"Service"`
+}
+
+type getCapabilitiesResponse struct {
+	XMLName      xml.Name `xml:"GetCapabilitiesResponse"`
+	Capabilities struct {
+		Media  struct{ XAddr string } `xml:"Media"`
+		PTZ    struct{ XAddr string } `xml:"PTZ"`
+		Events struct{ XAddr string } `xml:"Events"`
+	} `xml:"Capabilities"`
+}
+
+type getProfilesResponse struct {
+	XMLName  xml.Name `xml:"GetProfilesResponse"`
+	Profiles []struct {
+		Token        string `xml:"token,attr"`
+		Name         string `xml:"Name"`
+		VideoEncoder struct {
+			Encoding   string `xml:"Encoding"`
+			Resolution struct {
+				Width  int `xml:"Width"`
+				Height int `xml:"Height"`
+			} `xml:"Resolution"`
+			RateControl struct {
+				FrameRateLimit int `xml:"FrameRateLimit"`
+				BitrateLimit   int `xml:"BitrateLimit"`
+			} `xml:"RateControl"`
+		} `xml:"VideoEncoderConfiguration"`
+	} `xml:"Profiles"`
+}
+
+// mediaURIResponse matches both GetStreamUriResponse and GetSnapshotUriResponse.
+type mediaURIResponse struct {
+	URI string `xml:"MediaUri>Uri"`
+}
@@ -0,0 +1,169 @@
+package discovery
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/steipete/camsnap/internal/discovery/onviftest"
+)
+
+const soapResponse = `<?xml version="1.0" encoding="UTF-8"?>
+<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope"
+            xmlns:tds="http://www.onvif.org/ver10/device/wsdl"
+            xmlns:trt="http://www.onvif.org/ver10/media/wsdl"
+            xmlns:tt="http://www.onvif.org/ver10/schema">
+  <s:Body>%s</s:Body>
+</s:Envelope>`
+
+// fakeONVIF answers GetServices, GetProfiles, GetStreamUri and GetSnapshotUri for two profiles.
+func fakeONVIF(t *testing.T) *httptest.Server {
+	t.Helper()
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		req := string(data)
+		var body string
+		switch {
+		case strings.Contains(req, "GetServices"):
+			body = `<tds:GetServicesResponse>
+  <tds:Service><tds:Namespace>http://www.onvif.org/ver10/device/wsdl</tds:Namespace><tds:XAddr>` + srv.URL + `/onvif/device_service</tds:XAddr></tds:Service>
+  <tds:Service><tds:Namespace>http://www.onvif.org/ver10/media/wsdl</tds:Namespace><tds:XAddr>` + srv.URL + `/onvif/media</tds:XAddr></tds:Service>
+</tds:GetServicesResponse>`
+		case strings.Contains(req, "GetProfiles"):
+			if r.URL.Path != "/onvif/media" {
+				t.Errorf("GetProfiles sent to %s, want media service", r.URL.Path)
+			}
+			body = `<trt:GetProfilesResponse>
+  <trt:Profiles token="main" fixed="true">
+    <tt:Name>mainStream</tt:Name>
+    <tt:VideoEncoderConfiguration token="v1">
+      <tt:Encoding>H265</tt:Encoding>
+      <tt:Resolution><tt:Width>2560</tt:Width><tt:Height>1440</tt:Height></tt:Resolution>
+      <tt:RateControl><tt:FrameRateLimit>15</tt:FrameRateLimit><tt:BitrateLimit>4096</tt:BitrateLimit></tt:RateControl>
+    </tt:VideoEncoderConfiguration>
+  </trt:Profiles>
+  <trt:Profiles token="sub" fixed="true">
+    <tt:Name>minorStream</tt:Name>
+    <tt:VideoEncoderConfiguration token="v2">
+      <tt:Encoding>H264</tt:Encoding>
+      <tt:Resolution><tt:Width>640</tt:Width><tt:Height>360</tt:Height></tt:Resolution>
+      <tt:RateControl><tt:FrameRateLimit>15</tt:FrameRateLimit><tt:BitrateLimit>512</tt:BitrateLimit></tt:RateControl>
+    </tt:VideoEncoderConfiguration>
+  </trt:Profiles>
+</trt:GetProfilesResponse>`
+		case strings.Contains(req, "GetStreamUri"):
+			path := "/stream1"
+			if strings.Contains(req, ">sub<") {
+				path = "/stream2"
+			}
+			body = `<trt:GetStreamUriResponse><trt:MediaUri><tt:Uri>rtsp://192.168.1.50:8554` + path + `</tt:Uri></trt:MediaUri></trt:GetStreamUriResponse>`
+		case strings.Contains(req, "GetSnapshotUri"):
+			if strings.Contains(req, ">sub<") {
+				// not every profile offers a still
+				w.WriteHeader(http.StatusInternalServerError)
+				body = `<s:Fault><s:Code><s:Value>s:Receiver</s:Value></s:Code><s:Reason><s:Text>no snapshot</s:Text></s:Reason></s:Fault>`
+				break
+			}
+			body = `<trt:GetSnapshotUriResponse><trt:MediaUri><tt:Uri>http://192.168.1.50/snap.jpg</tt:Uri></trt:MediaUri></trt:GetSnapshotUriResponse>`
+		default:
+			t.Errorf("unexpected request: %s", req)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/soap+xml")
+		_, _ = io.WriteString(w, strings.Replace(soapResponse, "%s", body, 1))
+	}))
+	return srv
+}
+
+func TestFetchProfiles(t *testing.T) {
+	srv := fakeONVIF(t)
+	defer srv.Close()
+
+	profiles, err := FetchProfiles(context.Background(), srv.URL+"/onvif/device_service", "", "")
+	if err != nil {
+		t.Fatalf("FetchProfiles: %v", err)
+	}
+	if len(profiles) != 2 {
+		t.Fatalf("expected 2 profiles, got %d", len(profiles))
+	}
+	main := profiles[0]
+	if main.Token != "main" || main.Name != "mainStream" || main.Encoding != "H265" {
+		t.Fatalf("unexpected main profile: %+v", main)
+	}
+	if main.Width != 2560 || main.Height != 1440 || main.FPS != 15 || main.BitrateKbps != 4096 {
+		t.Fatalf("unexpected main encoder settings: %+v", main)
+	}
+	if main.StreamURI != "rtsp://192.168.1.50:8554/stream1" || main.SnapshotURI != "http://192.168.1.50/snap.jpg" {
+		t.Fatalf("unexpected main uris: %+v", main)
+	}
+	sub := profiles[1]
+	if sub.StreamURI != "rtsp://192.168.1.50:8554/stream2" || sub.SnapshotURI != "" {
+		t.Fatalf("unexpected sub uris: %+v", sub)
+	}
+}
+
+func TestCallSOAPFallsBackToBasicAuth(t *testing.T) {
+	var sawBasic bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(data), "UsernameToken") {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = io.WriteString(w, strings.Replace(soapResponse, "%s",
+				`<s:Fault><s:Code><s:Value>s:Sender</s:Value><s:Subcode><s:Value>ter:NotAuthorized</s:Value></s:Subcode></s:Code><s:Reason><s:Text>denied</s:Text></s:Reason></s:Fault>`, 1))
+			return
+		}
+		if user, pass, ok := r.BasicAuth(); !ok || user != "admin" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		sawBasic = true
+		_, _ = io.WriteString(w, strings.Replace(soapResponse, "%s",
+			`<tds:GetDeviceInformationResponse><tds:Manufacturer>Acme</tds:Manufacturer></tds:GetDeviceInformationResponse>`, 1))
+	}))
+	defer srv.Close()
+
+	info, err := FetchDeviceInfo(context.Background(), srv.URL, "admin", "secret")
+	if err != nil {
+		t.Fatalf("FetchDeviceInfo: %v", err)
+	}
+	if !sawBasic || info.Manufacturer != "Acme" {
+		t.Fatalf("expected basic fallback, got %+v (basic=%v)", info, sawBasic)
+	}
+}
+
+func TestCallSOAPReportsAuthFault(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	_, err := FetchProfiles(context.Background(), srv.URL, "admin", "wrong")
+	if err == nil || !isAuthError(err) {
+		t.Fatalf("expected auth error, got %v", err)
+	}
+}
+
+func TestFetchProfilesSkipsProfileWithoutStreamURI(t *testing.T) {
+	srv := onviftest.NewServer()
+	defer srv.Close()
+
+	// the first profile (e.g. metadata-only) has no stream URI
+	srv.SetResponses("GetStreamUri", "", onviftest.Recording("GetStreamUri"))
+	profiles, err := FetchProfiles(context.Background(), srv.DeviceURL(), "", "")
+	if err != nil {
+		t.Fatalf("FetchProfiles: %v", err)
+	}
+	if len(profiles) != 1 || profiles[0].StreamURI == "" {
+		t.Fatalf("expected only the profile with a stream URI, got %+v", profiles)
+	}
+
+	srv.SetResponses("GetStreamUri", "")
+	if _, err := FetchProfiles(context.Background(), srv.DeviceURL(), "", ""); err == nil || !strings.Contains(err.Error(), "get stream uri") {
+		t.Fatalf("expected stream uri error when no profile has one, got %v", err)
+	}
+}
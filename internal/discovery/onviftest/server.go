@@ -0,0 +1,155 @@
+// Package onviftest serves recorded ONVIF SOAP responses for tests.
+package onviftest
+
+import (
+	"bytes"
+	"embed"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//go:embed recordings/*.xml
+var recordings embed.FS
+
+// Server is a fake ONVIF device. Every operation answers with its recording from recordings/;
+// {{URL}} and {{HOST}} in a recording expand to the server's base URL and host, {{UTC_*}} to the
+// device clock.
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	responses map[string][]string
+	calls     []string
+	bodies    []string
+	clock     time.Duration
+}
+
+// NewServer starts a fake device. PullMessages returns the recorded events once, then empty pulls.
+func NewServer() *Server {
+	s := &Server{responses: map[string][]string{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	s.SetResponses("PullMessages", Recording("PullMessages"), Recording("PullMessages-empty"))
+	return s
+}
+
+// DeviceURL is the device service address to pass to the discovery functions.
+func (s *Server) DeviceURL() string { return s.URL + "/onvif/device_service" }
+
+// SetResponses replaces the replies for an operation; they are served in order and the last repeats.
+func (s *Server) SetResponses(op string, envelopes ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses[op] = envelopes
+}
+
+// SetClockOffset makes the device clock run ahead of (or, when negative, behind) the local one.
+func (s *Server) SetClockOffset(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = d
+}
+
+// Requests returns the raw request envelopes received so far.
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.bodies...)
+}
+
+// Calls lists the operations received so far.
+func (s *Server) Calls() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.calls...)
+}
+
+// Recording returns a recorded envelope by operation name, or "" if none exists.
+func Recording(name string) string {
+	data, err := recordings.ReadFile("recordings/" + name + ".xml")
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	data, _ := io.ReadAll(r.Body)
+	op := operation(data)
+
+	s.mu.Lock()
+	s.calls = append(s.calls, op)
+	s.bodies = append(s.bodies, string(data))
+	now := time.Now().Add(s.clock).UTC()
+	envelope := ""
+	if queued := s.responses[op]; len(queued) > 0 {
+		envelope = queued[0]
+		if len(queued) > 1 {
+			s.responses[op] = queued[1:]
+		}
+	} else {
+		envelope = Recording(op)
+	}
+	s.mu.Unlock()
+
+	if envelope == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = io.WriteString(w, fault("ter:ActionNotSupported", op+" is not recorded"))
+		return
+	}
+	if op == "PullMessages" && !strings.Contains(envelope, "NotificationMessage") {
+		// a real device holds an empty pull open; a short pause keeps callers from spinning
+		time.Sleep(20 * time.Millisecond)
+	}
+	u, _ := url.Parse(s.URL)
+	envelope = strings.NewReplacer(
+		"{{URL}}", s.URL,
+		"{{HOST}}", u.Hostname(),
+		"{{UTC_YEAR}}", strconv.Itoa(now.Year()),
+		"{{UTC_MONTH}}", strconv.Itoa(int(now.Month())),
+		"{{UTC_DAY}}", strconv.Itoa(now.Day()),
+		"{{UTC_HOUR}}", strconv.Itoa(now.Hour()),
+		"{{UTC_MINUTE}}", strconv.Itoa(now.Minute()),
+		"{{UTC_SECOND}}", strconv.Itoa(now.Second()),
+	).Replace(envelope)
+	w.Header().Set("Content-Type", "application/soap+xml; charset=utf-8")
+	_, _ = io.WriteString(w, envelope)
+}
+
+// operation returns the local name of the first element in the SOAP body.
+func operation(envelope []byte) string {
+	dec := xml.NewDecoder(bytes.NewReader(envelope))
+	inBody := false
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return ""
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if inBody {
+			return start.Name.Local
+		}
+		inBody = start.Name.Local == "Body"
+	}
+}
+
+func fault(subcode, reason string) string {
+	return `<?xml version="1.0" encoding="UTF-8"?>
+<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope" xmlns:ter="http://www.onvif.org/ver10/error">
+  <env:Body>
+    <env:Fault>
+      <env:Code><env:Value>env:Sender</env:Value><env:Subcode><env:Value>` + subcode + `</env:Value></env:Subcode></env:Code>
+      <env:Reason><env:Text xml:lang="en">` + reason + `</env:Text></env:Reason>
+    </env:Fault>
+  </env:Body>
+</env:Envelope>`
+}
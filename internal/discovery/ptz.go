@@ -0,0 +1,246 @@
+package discovery
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PTZVector is a pan/tilt/zoom value in the camera's normalized space (-1..1, zoom 0..1).
+// Nil components are left out of the request so the other axes are untouched.
+type PTZVector struct {
+	Pan  *float64
+	Tilt *float64
+	Zoom *float64
+}
+
+// PTZPreset is a stored camera position.
+type PTZPreset struct {
+	Token string
+	Name  string
+}
+
+// PTZStatus reports the current position and whether the camera is still moving.
+type PTZStatus struct {
+	Pan, Tilt, Zoom float64
+	PanTiltMoving   bool
+	ZoomMoving      bool
+}
+
+// Moving reports whether any axis is still in motion.
+func (s PTZStatus) Moving() bool { return s.PanTiltMoving || s.ZoomMoving }
+
+// PTZ drives the ONVIF PTZ service for one media profile.
+type PTZ struct {
+	Addr     string // PTZ service address
+	Profile  string // media profile token
+	Username string
+	Password string
+}
+
+// NewPTZ resolves the PTZ service of a device. An empty profile token picks the first media profile.
+func NewPTZ(ctx context.Context, xaddr, user, pass, profile string) (*PTZ, error) {
+	services, err := FetchServices(ctx, xaddr, user, pass)
+	if err != nil {
+		return nil, err
+	}
+	addr := services[nsPTZ]
+	if addr == "" {
+		return nil, fmt.Errorf("device does not advertise a PTZ service")
+	}
+	if profile == "" {
+		mediaAddr := services.Media()
+		if mediaAddr == "" {
+			mediaAddr = xaddr
+		}
+		var resp getProfilesResponse
+		if err := callSOAP(ctx, mediaAddr, user, pass, `<trt:GetProfiles xmlns:trt="`+nsMedia+`"/>`, &resp); err != nil {
+			return nil, fmt.Errorf("get profiles: %w", err)
+		}
+		if len(resp.Profiles) == 0 {
+			return nil, fmt.Errorf("device reports no media profiles")
+		}
+		profile = resp.Profiles[0].Token
+	}
+	return &PTZ{Addr: addr, Profile: profile, Username: user, Password: pass}, nil
+}
+
+// ContinuousMove starts moving at the given velocity. A positive timeout asks the camera to stop on its own.
+func (p *PTZ) ContinuousMove(ctx context.Context, velocity PTZVector, timeout time.Duration) error {
+	extra := ""
+	if timeout > 0 {
+		extra = "<tptz:Timeout>" + xsdDuration(timeout) + "</tptz:Timeout>"
+	}
+	return p.call(ctx, "ContinuousMove", ptzVectorXML("Velocity", velocity)+extra, nil)
+}
+
+// RelativeMove moves by a translation; a nil speed uses the camera default.
+func (p *PTZ) RelativeMove(ctx context.Context, translation PTZVector, speed *PTZVector) error {
+	return p.call(ctx, "RelativeMove", ptzVectorXML("Translation", translation)+ptzSpeedXML(speed), nil)
+}
+
+// AbsoluteMove moves to a position; a nil speed uses the camera default.
+func (p *PTZ) AbsoluteMove(ctx context.Context, position PTZVector, speed *PTZVector) error {
+	return p.call(ctx, "AbsoluteMove", ptzVectorXML("Position", position)+ptzSpeedXML(speed), nil)
+}
+
+// Stop halts pan, tilt and zoom.
+func (p *PTZ) Stop(ctx context.Context) error {
+	return p.call(ctx, "Stop", "<tptz:PanTilt>true</tptz:PanTilt><tptz:Zoom>true</tptz:Zoom>", nil)
+}
+
+// Presets lists the stored presets.
+func (p *PTZ) Presets(ctx context.Context) ([]PTZPreset, error) {
+	var resp struct {
+		XMLName xml.Name `xml:"GetPresetsResponse"`
+		Presets []struct {
+			Token string `xml:"token,attr"`
+			Name  string `xml:"Name"`
+		} `xml:"Preset"`
+	}
+	if err := p.call(ctx, "GetPresets", "", &resp); err != nil {
+		return nil, err
+	}
+	out := make([]PTZPreset, 0, len(resp.Presets))
+	for _, pr := range resp.Presets {
+		out = append(out, PTZPreset{Token: pr.Token, Name: strings.TrimSpace(pr.Name)})
+	}
+	return out, nil
+}
+
+// GotoPreset moves to a preset by token; a nil speed uses the camera default.
+func (p *PTZ) GotoPreset(ctx context.Context, token string, speed *PTZVector) error {
+	return p.call(ctx, "GotoPreset", "<tptz:PresetToken>"+escapeXML(token)+"</tptz:PresetToken>"+ptzSpeedXML(speed), nil)
+}
+
+// SetPreset stores the current position under name and returns the preset token.
+// An existing token overwrites that preset instead of creating a new one.
+func (p *PTZ) SetPreset(ctx context.Context, name, token string) (string, error) {
+	body := "<tptz:PresetName>" + escapeXML(name) + "</tptz:PresetName>"
+	if token != "" {
+		body += "<tptz:PresetToken>" + escapeXML(token) + "</tptz:PresetToken>"
+	}
+	var resp struct {
+		XMLName xml.Name `xml:"SetPresetResponse"`
+		Token   string   `xml:"PresetToken"`
+	}
+	if err := p.call(ctx, "SetPreset", body, &resp); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(resp.Token), nil
+}
+
+// Status returns the current position and move state.
+func (p *PTZ) Status(ctx context.Context) (PTZStatus, error) {
+	var resp struct {
+		XMLName xml.Name `xml:"GetStatusResponse"`
+		Status  struct {
+			Position struct {
+				PanTilt struct {
+					X float64 `xml:"x,attr"`
+					Y float64 `xml:"y,attr"`
+				} `xml:"PanTilt"`
+				Zoom struct {
+					X float64 `xml:"x,attr"`
+				} `xml:"Zoom"`
+			} `xml:"Position"`
+			MoveStatus struct {
+				PanTilt string `xml:"PanTilt"`
+				Zoom    string `xml:"Zoom"`
+			} `xml:"MoveStatus"`
+		} `xml:"PTZStatus"`
+	}
+	if err := p.call(ctx, "GetStatus", "", &resp); err != nil {
+		return PTZStatus{}, err
+	}
+	st := resp.Status
+	return PTZStatus{
+		Pan:           st.Position.PanTilt.X,
+		Tilt:          st.Position.PanTilt.Y,
+		Zoom:          st.Position.Zoom.X,
+		PanTiltMoving: strings.EqualFold(strings.TrimSpace(st.MoveStatus.PanTilt), "MOVING"),
+		ZoomMoving:    strings.EqualFold(strings.TrimSpace(st.MoveStatus.Zoom), "MOVING"),
+	}, nil
+}
+
+// WaitIdle polls GetStatus until the camera stops moving and its position holds still.
+// Cameras that never report MoveStatus settle once two polls return the same position.
+func (p *PTZ) WaitIdle(ctx context.Context, poll time.Duration) error {
+	if poll <= 0 {
+		poll = 250 * time.Millisecond
+	}
+	var last *PTZStatus
+	for {
+		st, err := p.Status(ctx)
+		if err != nil {
+			return err
+		}
+		if !st.Moving() && last != nil && samePosition(*last, st) {
+			return nil
+		}
+		last = &st
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("ptz did not settle: %w", ctx.Err())
+		case <-time.After(poll):
+		}
+	}
+}
+
+func (p *PTZ) call(ctx context.Context, op, inner string, out interface{}) error {
+	body := fmt.Sprintf(`<tptz:%s xmlns:tptz="%s" xmlns:tt="%s"><tptz:ProfileToken>%s</tptz:ProfileToken>%s</tptz:%s>`,
+		op, nsPTZ, nsSchema, escapeXML(p.Profile), inner, op)
+	if err := callSOAP(ctx, p.Addr, p.Username, p.Password, body, out); err != nil {
+		return fmt.Errorf("ptz %s: %w", op, err)
+	}
+	return nil
+}
+
+func samePosition(a, b PTZStatus) bool {
+	const eps = 1e-3
+	near := func(x, y float64) bool { return x-y < eps && y-x < eps }
+	return near(a.Pan, b.Pan) && near(a.Tilt, b.Tilt) && near(a.Zoom, b.Zoom)
+}
+
+func ptzVectorXML(elem string, v PTZVector) string {
+	var b strings.Builder
+	b.WriteString("<tptz:" + elem + ">")
+	// an axis left nil is left out, so the camera keeps it where it is
+	if v.Pan != nil || v.Tilt != nil {
+		b.WriteString("<tt:PanTilt")
+		if v.Pan != nil {
+			fmt.Fprintf(&b, ` x="%s"`, formatFloat(v.Pan))
+		}
+		if v.Tilt != nil {
+			fmt.Fprintf(&b, ` y="%s"`, formatFloat(v.Tilt))
+		}
+		b.WriteString("/>")
+	}
+	if v.Zoom != nil {
+		fmt.Fprintf(&b, `<tt:Zoom x="%s"/>`, formatFloat(v.Zoom))
+	}
+	b.WriteString("</tptz:" + elem + ">")
+	return b.String()
+}
+
+func ptzSpeedXML(speed *PTZVector) string {
+	if speed == nil {
+		return ""
+	}
+	return ptzVectorXML("Speed", *speed)
+}
+
+func formatFloat(v *float64) string {
+	if v == nil {
+		return "0"
+	}
+	return strconv.FormatFloat(*v, 'f', -1, 64)
+}
+
+// xsdDuration renders a duration as an xs:duration (PT1.5S).
+func xsdDuration(d time.Duration) string {
+	return "PT" + strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + "S"
+}
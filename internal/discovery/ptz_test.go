@@ -0,0 +1,108 @@
+package discovery
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPTZPresetMoveAndWait(t *testing.T) {
+	var mu sync.Mutex
+	statusPolls := 0
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		req := string(data)
+		mu.Lock()
+		defer mu.Unlock()
+		var body string
+		switch {
+		case strings.Contains(req, "GetServices"):
+			body = `<tds:GetServicesResponse>
+  <tds:Service><tds:Namespace>http://www.onvif.org/ver20/ptz/wsdl</tds:Namespace><tds:XAddr>` + srv.URL + `/onvif/ptz</tds:XAddr></tds:Service>
+</tds:GetServicesResponse>`
+		case strings.Contains(req, "GetProfiles"):
+			body = `<trt:GetProfilesResponse><trt:Profiles token="prof0"><tt:Name>main</tt:Name></trt:Profiles></trt:GetProfilesResponse>`
+		case strings.Contains(req, "GetPresets"):
+			body = `<tptz:GetPresetsResponse xmlns:tptz="http://www.onvif.org/ver20/ptz/wsdl">
+  <tptz:Preset token="1"><tt:Name>Home</tt:Name></tptz:Preset>
+  <tptz:Preset token="2"><tt:Name>Gate</tt:Name></tptz:Preset>
+</tptz:GetPresetsResponse>`
+		case strings.Contains(req, "GotoPreset"):
+			if r.URL.Path != "/onvif/ptz" || !strings.Contains(req, "<tptz:ProfileToken>prof0</tptz:ProfileToken>") ||
+				!strings.Contains(req, "<tptz:PresetToken>2</tptz:PresetToken>") {
+				t.Errorf("unexpected GotoPreset request to %s: %s", r.URL.Path, req)
+			}
+			body = `<tptz:GotoPresetResponse xmlns:tptz="http://www.onvif.org/ver20/ptz/wsdl"/>`
+		case strings.Contains(req, "GetStatus"):
+			statusPolls++
+			state, x := "MOVING", "0.1"
+			if statusPolls > 2 {
+				state, x = "IDLE", "0.5"
+			}
+			body = `<tptz:GetStatusResponse xmlns:tptz="http://www.onvif.org/ver20/ptz/wsdl"><tptz:PTZStatus>
+  <tt:Position><tt:PanTilt x="` + x + `" y="-0.25"/><tt:Zoom x="0"/></tt:Position>
+  <tt:MoveStatus><tt:PanTilt>` + state + `</tt:PanTilt><tt:Zoom>IDLE</tt:Zoom></tt:MoveStatus>
+</tptz:PTZStatus></tptz:GetStatusResponse>`
+		default:
+			t.Errorf("unexpected request: %s", req)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = io.WriteString(w, strings.Replace(soapResponse, "%s", body, 1))
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ptz, err := NewPTZ(ctx, srv.URL+"/onvif/device_service", "", "", "")
+	if err != nil {
+		t.Fatalf("NewPTZ: %v", err)
+	}
+	if ptz.Profile != "prof0" || ptz.Addr != srv.URL+"/onvif/ptz" {
+		t.Fatalf("unexpected ptz: %+v", ptz)
+	}
+	presets, err := ptz.Presets(ctx)
+	if err != nil {
+		t.Fatalf("Presets: %v", err)
+	}
+	if len(presets) != 2 || presets[1] != (PTZPreset{Token: "2", Name: "Gate"}) {
+		t.Fatalf("unexpected presets: %+v", presets)
+	}
+	if err := ptz.GotoPreset(ctx, "2", nil); err != nil {
+		t.Fatalf("GotoPreset: %v", err)
+	}
+	if err := ptz.WaitIdle(ctx, time.Millisecond); err != nil {
+		t.Fatalf("WaitIdle: %v", err)
+	}
+	st, err := ptz.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if st.Moving() || st.Pan != 0.5 || st.Tilt != -0.25 {
+		t.Fatalf("unexpected status: %+v", st)
+	}
+	if statusPolls < 4 {
+		t.Fatalf("expected WaitIdle to poll until idle, got %d polls", statusPolls)
+	}
+}
+
+func TestPTZVectorXML(t *testing.T) {
+	pan, zoom := 0.5, 1.0
+	got := ptzVectorXML("Velocity", PTZVector{Pan: &pan})
+	if got != `<tptz:Velocity><tt:PanTilt x="0.5"/></tptz:Velocity>` {
+		t.Fatalf("pan only: %s", got)
+	}
+	got = ptzVectorXML("Position", PTZVector{Zoom: &zoom})
+	if got != `<tptz:Position><tt:Zoom x="1"/></tptz:Position>` {
+		t.Fatalf("zoom only: %s", got)
+	}
+	if d := xsdDuration(1500 * time.Millisecond); d != "PT1.5S" {
+		t.Fatalf("xsdDuration: %s", d)
+	}
+}
@@ -0,0 +1,19 @@
+//go:build windows
+
+package discovery
+
+import (
+	"syscall"
+)
+
+// reuseAddr lets several listeners share port 3702.
+func reuseAddr(_, _ string, c syscall.RawConn) error {
+	var serr error
+	err := c.Control(func(fd uintptr) {
+		serr = syscall.SetsockoptInt(syscall.Handle(fd), syscall.SOL_SOCKET, syscall.SO_REUSEADDR, 1)
+	})
+	if err != nil {
+		return err
+	}
+	return serr
+}
@@ -0,0 +1,256 @@
+package discovery
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/steipete/camsnap/internal/hostport"
+)
+
+// ScanPorts are the RTSP ports probed by Scan: standard, alternate, and UniFi Protect RTSP/RTSPS.
+var ScanPorts = []int{554, 8554, 7447, 7441}
+
+// tlsPorts speak RTSPS.
+var tlsPorts = map[int]bool{7441: true, 322: true}
+
+// maxScanHosts caps a scan at a /16.
+const maxScanHosts = 1 << 16
+
+// ScanResult is an open port that answered like an RTSP server.
+type ScanResult struct {
+	Host         string
+	Port         int
+	TLS          bool   // rtsps
+	Server       string // Server header, e.g. "Hikvision/1.0"
+	Status       int    // DESCRIBE status code
+	AuthRequired bool
+	AuthScheme   string // Digest or Basic when AuthRequired
+}
+
+// ScanOptions tunes Scan; zero values pick the defaults.
+type ScanOptions struct {
+	Ports       []int         // default ScanPorts
+	Concurrency int           // parallel connections, default 64
+	Rate        int           // new connections per second, default 200
+	DialTimeout time.Duration // default 700ms
+}
+
+// Scan connects to each host and port in cidr and issues unauthenticated RTSP OPTIONS and DESCRIBE
+// requests. It finds cameras that never answer WS-Discovery. Results are sorted by host, then port.
+func Scan(ctx context.Context, cidr string, opts ScanOptions) ([]ScanResult, error) {
+	hosts, err := hostsInCIDR(cidr)
+	if err != nil {
+		return nil, err
+	}
+	ports := opts.Ports
+	if len(ports) == 0 {
+		ports = ScanPorts
+	}
+	workers := opts.Concurrency
+	if workers <= 0 {
+		workers = 64
+	}
+	rate := opts.Rate
+	if rate <= 0 {
+		rate = 200
+	}
+	dialTimeout := opts.DialTimeout
+	if dialTimeout <= 0 {
+		dialTimeout = 700 * time.Millisecond
+	}
+
+	type target struct {
+		host string
+		port int
+	}
+	targets := make(chan target)
+	var mu sync.Mutex
+	var results []ScanResult
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range targets {
+				if r, ok := probeRTSP(ctx, t.host, t.port, dialTimeout); ok {
+					mu.Lock()
+					results = append(results, r)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	tick := time.NewTicker(time.Second / time.Duration(rate))
+	defer tick.Stop()
+feed:
+	for _, h := range hosts {
+		for _, p := range ports {
+			select {
+			case <-ctx.Done():
+				break feed
+			case <-tick.C:
+			}
+			select {
+			case <-ctx.Done():
+				break feed
+			case targets <- target{h, p}:
+			}
+		}
+	}
+	close(targets)
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool {
+		a, b := net.ParseIP(results[i].Host), net.ParseIP(results[j].Host)
+		if c := compareIP(a, b); c != 0 {
+			return c < 0
+		}
+		return results[i].Port < results[j].Port
+	})
+	return results, nil
+}
+
+// hostsInCIDR lists the host addresses of a network, skipping the IPv4 network and broadcast addresses.
+func hostsInCIDR(cidr string) ([]string, error) {
+	ip, ipnet, err := net.ParseCIDR(strings.TrimSpace(cidr))
+	if err != nil {
+		if single := net.ParseIP(strings.TrimSpace(cidr)); single != nil {
+			return []string{single.String()}, nil
+		}
+		return nil, fmt.Errorf("invalid --scan range %q (use CIDR like 192.168.1.0/24)", cidr)
+	}
+	ones, bits := ipnet.Mask.Size()
+	if bits-ones > 16 {
+		return nil, fmt.Errorf("scan range %s is too large (max /%d)", cidr, bits-16)
+	}
+	start := ip.Mask(ipnet.Mask)
+	if v4 := start.To4(); v4 != nil {
+		start = v4
+	}
+	count := 1 << (bits - ones)
+	hosts := make([]string, 0, count)
+	cur := append(net.IP(nil), start...)
+	for i := 0; i < count && len(hosts) < maxScanHosts; i++ {
+		skip := len(cur) == net.IPv4len && count > 2 && (i == 0 || i == count-1)
+		if !skip {
+			hosts = append(hosts, cur.String())
+		}
+		incIP(cur)
+	}
+	return hosts, nil
+}
+
+func incIP(ip net.IP) {
+	for i := len(ip) - 1; i >= 0; i-- {
+		ip[i]++
+		if ip[i] != 0 {
+			return
+		}
+	}
+}
+
+func compareIP(a, b net.IP) int {
+	if a4, b4 := a.To4(), b.To4(); a4 != nil && b4 != nil {
+		a, b = a4, b4
+	}
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return len(a) - len(b)
+}
+
+// probeRTSP checks one host:port. Ports that accept TCP but do not speak RTSP are dropped.
+func probeRTSP(ctx context.Context, host string, port int, timeout time.Duration) (ScanResult, bool) {
+	addr := hostport.Join(host, port)
+	d := net.Dialer{Timeout: timeout}
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return ScanResult{}, false
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	res := ScanResult{Host: host, Port: port, TLS: tlsPorts[port]}
+	scheme := "rtsp"
+	if res.TLS {
+		// Protect and most NVRs use self-signed certificates; we only read headers
+		tc := tls.Client(conn, &tls.Config{InsecureSkipVerify: true, ServerName: host}) //nolint:gosec
+		conn = tc
+		scheme = "rtsps"
+	}
+	// answering RTSP servers respond quickly; don't let silent ports hold a worker
+	_ = conn.SetDeadline(time.Now().Add(3 * timeout))
+
+	url := scheme + "://" + hostport.URLHost(host, port) + "/"
+	r := bufio.NewReader(conn)
+	status, hdr, err := rtspRequest(conn, r, "OPTIONS", url, 1)
+	if err != nil {
+		return ScanResult{}, false
+	}
+	res.Server = hdr.Get("Server")
+	res.Status = status
+	status, hdr, err = rtspRequest(conn, r, "DESCRIBE", url, 2)
+	if err == nil {
+		res.Status = status
+		if s := hdr.Get("Server"); s != "" {
+			res.Server = s
+		}
+	}
+	if res.Status == 401 {
+		res.AuthRequired = true
+		if challenge := hdr.Get("WWW-Authenticate"); challenge != "" {
+			res.AuthScheme, _, _ = strings.Cut(challenge, " ")
+		}
+	}
+	return res, true
+}
+
+// rtspRequest sends a bodiless request and reads the status and headers, discarding any body.
+func rtspRequest(conn net.Conn, r *bufio.Reader, method, url string, cseq int) (int, textproto.MIMEHeader, error) {
+	req := fmt.Sprintf("%s %s RTSP/1.0\r\nCSeq: %d\r\nUser-Agent: camsnap\r\n", method, url, cseq)
+	if method == "DESCRIBE" {
+		req += "Accept: application/sdp\r\n"
+	}
+	if _, err := conn.Write([]byte(req + "\r\n")); err != nil {
+		return 0, nil, err
+	}
+	tp := textproto.NewReader(r)
+	line, err := tp.ReadLine()
+	if err != nil {
+		return 0, nil, err
+	}
+	proto, rest, _ := strings.Cut(line, " ")
+	if !strings.HasPrefix(proto, "RTSP/") {
+		return 0, nil, fmt.Errorf("not an RTSP response: %q", line)
+	}
+	codeStr, _, _ := strings.Cut(rest, " ")
+	code, err := strconv.Atoi(codeStr)
+	if err != nil {
+		return 0, nil, fmt.Errorf("bad status line %q", line)
+	}
+	hdr, err := tp.ReadMIMEHeader()
+	if err != nil && len(hdr) == 0 {
+		return 0, nil, err
+	}
+	if n, _ := strconv.Atoi(hdr.Get("Content-Length")); n > 0 {
+		if _, err := r.Discard(n); err != nil {
+			return code, hdr, err
+		}
+	}
+	return code, hdr, nil
+}
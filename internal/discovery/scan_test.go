@@ -0,0 +1,108 @@
+package discovery
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+// fakeRTSPServer answers OPTIONS with 200 and DESCRIBE with a digest challenge.
+func fakeRTSPServer(t *testing.T) (int, func()) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer func() {
+					_ = conn.Close()
+				}()
+				tp := textproto.NewReader(bufio.NewReader(conn))
+				for {
+					line, err := tp.ReadLine()
+					if err != nil {
+						return
+					}
+					hdr, _ := tp.ReadMIMEHeader()
+					cseq := hdr.Get("Cseq")
+					switch {
+					case strings.HasPrefix(line, "OPTIONS "):
+						fmt.Fprintf(conn, "RTSP/1.0 200 OK\r\nCSeq: %s\r\nServer: FakeCam/1.0\r\nPublic: OPTIONS, DESCRIBE, SETUP, PLAY\r\n\r\n", cseq)
+					case strings.HasPrefix(line, "DESCRIBE "):
+						fmt.Fprintf(conn, "RTSP/1.0 401 Unauthorized\r\nCSeq: %s\r\nWWW-Authenticate: Digest realm=\"cam\", nonce=\"abc\"\r\nContent-Length: 5\r\n\r\nnope!", cseq)
+					default:
+						return
+					}
+				}
+			}(conn)
+		}
+	}()
+	return ln.Addr().(*net.TCPAddr).Port, func() { _ = ln.Close() }
+}
+
+func TestScanFindsRTSPServers(t *testing.T) {
+	rtspPort, stop := fakeRTSPServer(t)
+	defer stop()
+
+	// a port that accepts TCP but does not speak RTSP
+	httpLn, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer func() {
+		_ = httpLn.Close()
+	}()
+	go func() {
+		for {
+			conn, err := httpLn.Accept()
+			if err != nil {
+				return
+			}
+			_, _ = conn.Write([]byte("HTTP/1.1 400 Bad Request\r\n\r\n"))
+			_ = conn.Close()
+		}
+	}()
+	httpPort := httpLn.Addr().(*net.TCPAddr).Port
+
+	results, err := Scan(context.Background(), "127.0.0.1/32", ScanOptions{Ports: []int{rtspPort, httpPort}})
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected only the RTSP port, got %+v", results)
+	}
+	r := results[0]
+	if r.Host != "127.0.0.1" || r.Port != rtspPort || r.Server != "FakeCam/1.0" || r.Status != 401 ||
+		!r.AuthRequired || r.AuthScheme != "Digest" {
+		t.Fatalf("unexpected result: %+v", r)
+	}
+}
+
+func TestHostsInCIDR(t *testing.T) {
+	hosts, err := hostsInCIDR("192.168.1.0/30")
+	if err != nil {
+		t.Fatalf("hostsInCIDR: %v", err)
+	}
+	if strings.Join(hosts, ",") != "192.168.1.1,192.168.1.2" {
+		t.Fatalf("unexpected hosts %v", hosts)
+	}
+	if hosts, _ := hostsInCIDR("10.0.0.5"); len(hosts) != 1 || hosts[0] != "10.0.0.5" {
+		t.Fatalf("single address: %v", hosts)
+	}
+	if _, err := hostsInCIDR("10.0.0.0/8"); err == nil {
+		t.Fatalf("expected /8 to be rejected")
+	}
+	if _, err := hostsInCIDR("not-a-range"); err == nil {
+		t.Fatalf("expected parse error")
+	}
+}
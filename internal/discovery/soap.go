@@ -0,0 +1,136 @@
+package discovery
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const soapEnvelopeTemplate = `<?xml version="1.0" encoding="UTF-8"?>
+<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope">
+  <s:Header>%s</s:Header>
+  <s:Body>
+    %s
+  </s:Body>
+</s:Envelope>`
+
+// soapTimeout bounds a single ONVIF request.
+const soapTimeout = 5 * time.Second
+
+type soapEnvelope struct {
+	XMLName xml.Name `xml:"Envelope"`
+	Body    soapBody `xml:"Body"`
+}
+
+type soapBody struct {
+	Fault *soapFault `xml:"Fault"`
+	Inner []byte     `xml:",innerxml"`
+}
+
+type soapFault struct {
+	Code    string `xml:"Code>Value"`
+	Subcode string `xml:"Code>Subcode>Value"`
+	Reason  string `xml:"Reason>Text"`
+}
+
+func (f *soapFault) Error() string {
+	code := f.Subcode
+	if code == "" {
+		code = f.Code
+	}
+	return fmt.Sprintf("soap fault %s: %s", strings.TrimSpace(code), strings.TrimSpace(f.Reason))
+}
+
+// callSOAP posts an ONVIF request body and decodes the first element of the response body into out.
+// With credentials it tries WS-Security UsernameToken first (stamped with the device's clock),
+// then falls back to HTTP Basic.
+func callSOAP(ctx context.Context, xaddr, user, pass, body string, out interface{}) error {
+	return callSOAPWith(ctx, xaddr, user, pass, "", body, soapTimeout, out)
+}
+
+// callSOAPWith is callSOAP with extra header elements (WS-Addressing) and a custom request timeout
+// for long-polling calls such as PullMessages.
+func callSOAPWith(ctx context.Context, xaddr, user, pass, header, body string, timeout time.Duration, out interface{}) error {
+	if xaddr == "" {
+		return fmt.Errorf("xaddr required")
+	}
+	client := &http.Client{Timeout: timeout}
+
+	if user != "" {
+		// cameras reject UsernameTokens whose Created stamp is off from their own clock
+		created := time.Now().Add(deviceOffset(ctx, xaddr))
+		secured := wsSecurityHeader(user, pass, created) + header
+		err := doSOAP(ctx, client, xaddr, fmt.Sprintf(soapEnvelopeTemplate, secured, body), "", out)
+		if err == nil || !isAuthError(err) {
+			return err
+		}
+	}
+	return doSOAP(ctx, client, xaddr, fmt.Sprintf(soapEnvelopeTemplate, header, body), basicAuth(user, pass), out)
+}
+
+// wsaHeader returns WS-Addressing Action and To elements; subscription managers route on them.
+func wsaHeader(action, to string) string {
+	return fmt.Sprintf(`<wsa:Action xmlns:wsa="http://www.w3.org/2005/08/addressing">%s</wsa:Action><wsa:To xmlns:wsa="http://www.w3.org/2005/08/addressing">%s</wsa:To>`,
+		escapeXML(action), escapeXML(to))
+}
+
+func doSOAP(ctx context.Context, client *http.Client, url, envelope, authHeader string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBufferString(envelope))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/soap+xml; charset=utf-8")
+	if authHeader != "" {
+		req.Header.Set("Authorization", authHeader)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return fmt.Errorf("auth failed (status %d)", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return fmt.Errorf("read soap response: %w", err)
+	}
+
+	var env soapEnvelope
+	if err := xml.Unmarshal(data, &env); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			snippet := string(data)
+			if len(snippet) > 256 {
+				snippet = snippet[:256]
+			}
+			return fmt.Errorf("soap status %d: %s", resp.StatusCode, strings.TrimSpace(snippet))
+		}
+		return fmt.Errorf("decode soap envelope: %w", err)
+	}
+	if env.Body.Fault != nil {
+		// ONVIF devices report bad credentials as a ter:NotAuthorized fault, often with HTTP 400/500.
+		if strings.Contains(env.Body.Fault.Subcode, "NotAuthorized") {
+			return fmt.Errorf("auth failed (%s)", env.Body.Fault.Error())
+		}
+		return env.Body.Fault
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("soap status %d", resp.StatusCode)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := xml.Unmarshal(env.Body.Inner, out); err != nil {
+		return fmt.Errorf("decode soap body: %w", err)
+	}
+	return nil
+}
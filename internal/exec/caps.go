@@ -0,0 +1,311 @@
+package exec
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Caps is what an ffmpeg build supports, parsed from -version, -encoders, -protocols and -filters.
+// A nil list means that part could not be detected; the Has* checks then assume support rather
+// than refuse to run.
+type Caps struct {
+	Version   string   `json:"version"`
+	Major     int      `json:"major"` // 0 for builds without a release number (git snapshots)
+	Minor     int      `json:"minor"`
+	Encoders  []string `json:"encoders"`
+	Protocols []string `json:"protocols"` // input protocols
+	Filters   []string `json:"filters"`
+}
+
+// HasEncoder reports whether the build can encode with name (e.g. mjpeg, libx264, libwebp).
+func (c Caps) HasEncoder(name string) bool {
+	return c.Encoders == nil || slices.Contains(c.Encoders, name)
+}
+
+// HasProtocol reports whether the build can read from the name protocol (e.g. tcp, udp, tls).
+func (c Caps) HasProtocol(name string) bool {
+	return c.Protocols == nil || slices.Contains(c.Protocols, name)
+}
+
+// HasFilter reports whether the build has the name filter.
+func (c Caps) HasFilter(name string) bool {
+	return c.Filters == nil || slices.Contains(c.Filters, name)
+}
+
+// AtLeast reports whether the build is release major.minor or newer. Builds without a release
+// number are git snapshots and count as new.
+func (c Caps) AtLeast(major, minor int) bool {
+	if c.Major == 0 {
+		return true
+	}
+	return c.Major > major || c.Major == major && c.Minor >= minor
+}
+
+// RTSPTimeoutOption names the RTSP socket I/O timeout option (microseconds). ffmpeg 5 renamed
+// -stimeout to -timeout; before that -timeout made the RTSP demuxer listen for a connection.
+func (c Caps) RTSPTimeoutOption() string {
+	if c.AtLeast(5, 0) {
+		return "-timeout"
+	}
+	return "-stimeout"
+}
+
+// RTSPTimeoutArgs are the input options that make ffmpeg give up on an RTSP camera that stops
+// answering for d, whether it stalls in the handshake or mid-stream; none when d is 0.
+func (c Caps) RTSPTimeoutArgs(d time.Duration) []string {
+	if d <= 0 {
+		return nil
+	}
+	return []string{c.RTSPTimeoutOption(), strconv.FormatInt(d.Microseconds(), 10)}
+}
+
+// CheckRTSPTransport returns an error when the build cannot carry RTSP over transport (tcp|udp).
+func (c Caps) CheckRTSPTransport(transport string) error {
+	needs := []string{"tcp"}
+	if transport == "udp" {
+		needs = append(needs, "udp", "rtp")
+	}
+	for _, p := range needs {
+		if !c.HasProtocol(p) {
+			return fmt.Errorf("this ffmpeg build has no %s protocol, so it cannot read RTSP over %s", p, transport)
+		}
+	}
+	return nil
+}
+
+// Feature is something camsnap uses that not every ffmpeg build has.
+type Feature struct {
+	Kind string `json:"kind" yaml:"kind"` // encoder|protocol|filter
+	Name string `json:"name" yaml:"name"`
+	For  string `json:"for" yaml:"for"` // what needs it
+}
+
+func (f Feature) String() string { return fmt.Sprintf("%s %s (%s)", f.Name, f.Kind, f.For) }
+
+// Features lists what doctor checks builds for.
+var Features = []Feature{
+	{"protocol", "tcp", "RTSP over TCP"},
+	{"protocol", "udp", "RTSP over UDP"},
+	{"protocol", "rtp", "RTSP over UDP"},
+	{"protocol", "tls", "rtsps:// URLs"},
+	{"encoder", "mjpeg", "JPEG snapshots"},
+	{"encoder", "png", "PNG snapshots"},
+	{"encoder", "libwebp", "WebP snapshots"},
+	{"encoder", "aac", "clip audio"},
+	{"encoder", "libx264", "H.264 encoding"},
+	{"filter", "select", "watch"},
+	{"filter", "metadata", "watch"},
+	{"filter", "scale", "resizing"},
+}
+
+// Missing returns the Features the build lacks.
+func (c Caps) Missing() []Feature {
+	var out []Feature
+	for _, f := range Features {
+		var ok bool
+		switch f.Kind {
+		case "encoder":
+			ok = c.HasEncoder(f.Name)
+		case "protocol":
+			ok = c.HasProtocol(f.Name)
+		case "filter":
+			ok = c.HasFilter(f.Name)
+		}
+		if !ok {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// DetectCaps runs ffmpeg's listing commands through run and parses them. Listings that fail stay
+// nil (unknown).
+func DetectCaps(ctx context.Context, run func(ctx context.Context, args ...string) (string, error)) Caps {
+	var c Caps
+	if out, err := run(ctx, "-hide_banner", "-version"); err == nil {
+		c.Version, c.Major, c.Minor = parseVersion(out)
+	}
+	if out, err := run(ctx, "-hide_banner", "-encoders"); err == nil {
+		c.Encoders = parseEncoders(out)
+	}
+	if out, err := run(ctx, "-hide_banner", "-protocols"); err == nil {
+		c.Protocols = parseProtocols(out)
+	}
+	if out, err := run(ctx, "-hide_banner", "-filters"); err == nil {
+		c.Filters = parseFilters(out)
+	}
+	return c
+}
+
+var (
+	versionLine   = regexp.MustCompile(`(?m)^ffmpeg version (\S+)`)
+	versionNumber = regexp.MustCompile(`^n?(\d+)\.(\d+)`)
+)
+
+// parseVersion reads the first line of ffmpeg -version, e.g. "ffmpeg version 6.1.1-3ubuntu5",
+// "ffmpeg version n7.0" or "ffmpeg version N-113072-g1b2c3d4" (no release number).
+func parseVersion(out string) (string, int, int) {
+	m := versionLine.FindStringSubmatch(out)
+	if m == nil {
+		return "", 0, 0
+	}
+	n := versionNumber.FindStringSubmatch(m[1])
+	if n == nil {
+		return m[1], 0, 0
+	}
+	major, _ := strconv.Atoi(n[1])
+	minor, _ := strconv.Atoi(n[2])
+	return m[1], major, minor
+}
+
+// parseEncoders reads the names below the " ------" rule of ffmpeg -encoders.
+func parseEncoders(out string) []string {
+	names := []string{}
+	listing := false
+	sc := bufio.NewScanner(strings.NewReader(out))
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		switch {
+		case len(fields) == 1 && strings.HasPrefix(fields[0], "---"):
+			listing = true
+		case listing && len(fields) >= 2:
+			names = append(names, fields[1])
+		}
+	}
+	return names
+}
+
+// parseProtocols reads the Input: section of ffmpeg -protocols.
+func parseProtocols(out string) []string {
+	names := []string{}
+	input := false
+	sc := bufio.NewScanner(strings.NewReader(out))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		switch {
+		case line == "Input:":
+			input = true
+		case line == "Output:":
+			input = false
+		case input && line != "":
+			names = append(names, line)
+		}
+	}
+	return names
+}
+
+// parseFilters reads ffmpeg -filters rows: flags, name, then the pad types like "V->V".
+func parseFilters(out string) []string {
+	names := []string{}
+	sc := bufio.NewScanner(strings.NewReader(out))
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) >= 3 && strings.Contains(fields[2], "->") {
+			names = append(names, fields[1])
+		}
+	}
+	return names
+}
+
+var (
+	capsMu    sync.Mutex
+	capsCache = map[string]Caps{} // by binary path
+)
+
+// capsKey identifies one ffmpeg binary; a reinstall changes its size or modification time.
+type capsKey struct {
+	Path    string `json:"path"`
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"mod_time"`
+}
+
+type capsFile struct {
+	Key  capsKey `json:"key"`
+	Caps Caps    `json:"caps"`
+}
+
+// Caps implements Runner. Detection runs once per process and is cached on disk
+// ($XDG_CACHE_HOME/camsnap/ffmpeg-caps.json) until the binary changes. It never fails: without
+// ffmpeg, or when detection breaks, everything reads as unknown.
+func (FFmpeg) Caps(ctx context.Context) Caps {
+	path, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return Caps{}
+	}
+	capsMu.Lock()
+	defer capsMu.Unlock()
+	if c, ok := capsCache[path]; ok {
+		return c
+	}
+	key, keyErr := binaryKey(path)
+	file := capsCachePath()
+	if keyErr == nil && file != "" {
+		if c, ok := loadCaps(file, key); ok {
+			capsCache[path] = c
+			return c
+		}
+	}
+	c := DetectCaps(ctx, func(ctx context.Context, args ...string) (string, error) {
+		out, err := exec.CommandContext(ctx, path, args...).Output()
+		return string(out), err
+	})
+	capsCache[path] = c
+	if keyErr == nil && file != "" && c.Version != "" {
+		_ = saveCaps(file, key, c) // the cache is only an optimisation
+	}
+	return c
+}
+
+func binaryKey(path string) (capsKey, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return capsKey{}, fmt.Errorf("stat ffmpeg: %w", err)
+	}
+	return capsKey{Path: path, Size: info.Size(), ModTime: info.ModTime().UnixNano()}, nil
+}
+
+func capsCachePath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "camsnap", "ffmpeg-caps.json")
+}
+
+// loadCaps returns the cached caps if file holds them for key.
+func loadCaps(file string, key capsKey) (Caps, bool) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return Caps{}, false
+	}
+	var cf capsFile
+	if err := json.Unmarshal(data, &cf); err != nil || cf.Key != key {
+		return Caps{}, false
+	}
+	return cf.Caps, true
+}
+
+func saveCaps(file string, key capsKey, c Caps) error {
+	data, err := json.Marshal(capsFile{Key: key, Caps: c})
+	if err != nil {
+		return fmt.Errorf("encode ffmpeg caps: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return fmt.Errorf("create cache dir: %w", err)
+	}
+	if err := os.WriteFile(file, data, 0o644); err != nil {
+		return fmt.Errorf("write ffmpeg caps: %w", err)
+	}
+	return nil
+}
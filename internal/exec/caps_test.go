@@ -0,0 +1,131 @@
+package exec
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+const (
+	versionOut = `ffmpeg version 4.4.2-0ubuntu0.22.04.1 Copyright (c) 2000-2021 the FFmpeg developers
+built with gcc 11 (Ubuntu 11.2.0-19ubuntu1)
+libavutil      56. 70.100 / 56. 70.100
+`
+	encodersOut = `Encoders:
+ V..... = Video
+ A..... = Audio
+ S..... = Subtitle
+ .F.... = Frame-level multithreading
+ ------
+ V....D mjpeg                MJPEG (Motion JPEG)
+ V....D png                  PNG (Portable Network Graphics) image
+ V....D libx264              libx264 H.264 / AVC / MPEG-4 AVC / MPEG-4 part 10 (codec h264)
+ A....D aac                  AAC (Advanced Audio Coding)
+`
+	protocolsOut = `Supported file protocols:
+Input:
+  file
+  http
+  rtp
+  tcp
+  udp
+Output:
+  file
+  tls
+`
+	filtersOut = `Filters:
+  T.. = Timeline support
+  .S. = Slice threading
+  ..C = Command support
+  A = Audio input/output
+  | = Source or sink filter
+ ..C metadata          V->V       Manipulate video frame metadata.
+ TSC scale             V->V       Scale the input video size and/or convert the image format.
+ ... select            V->N       Select video frames to pass in output.
+`
+)
+
+func TestDetectCaps(t *testing.T) {
+	outputs := map[string]string{"-version": versionOut, "-encoders": encodersOut, "-protocols": protocolsOut, "-filters": filtersOut}
+	c := DetectCaps(context.Background(), func(_ context.Context, args ...string) (string, error) {
+		return outputs[args[len(args)-1]], nil
+	})
+	if c.Version != "4.4.2-0ubuntu0.22.04.1" || c.Major != 4 || c.Minor != 4 {
+		t.Fatalf("version %q %d.%d", c.Version, c.Major, c.Minor)
+	}
+	if !slices.Equal(c.Encoders, []string{"mjpeg", "png", "libx264", "aac"}) {
+		t.Fatalf("encoders %v", c.Encoders)
+	}
+	if !slices.Equal(c.Protocols, []string{"file", "http", "rtp", "tcp", "udp"}) {
+		t.Fatalf("protocols %v", c.Protocols)
+	}
+	if !slices.Equal(c.Filters, []string{"metadata", "scale", "select"}) {
+		t.Fatalf("filters %v", c.Filters)
+	}
+	if c.RTSPTimeoutOption() != "-stimeout" {
+		t.Fatalf("ffmpeg 4 timeout option %s", c.RTSPTimeoutOption())
+	}
+	var missing []string
+	for _, f := range c.Missing() {
+		missing = append(missing, f.Name)
+	}
+	if !slices.Equal(missing, []string{"tls", "libwebp"}) {
+		t.Fatalf("missing %v", missing)
+	}
+}
+
+func TestCapsUnknown(t *testing.T) {
+	c := DetectCaps(context.Background(), func(context.Context, ...string) (string, error) {
+		return "", errors.New("exit status 1")
+	})
+	if !c.HasEncoder("libwebp") || !c.HasFilter("select") || c.CheckRTSPTransport("udp") != nil || len(c.Missing()) != 0 {
+		t.Fatalf("undetected caps should not block anything: %+v", c)
+	}
+	for _, v := range []struct {
+		version string
+		want    string
+	}{
+		{"ffmpeg version n7.0 Copyright", "-timeout"},
+		{"ffmpeg version 5.1.4 Copyright", "-timeout"},
+		{"ffmpeg version N-113072-g1b2c3d4 Copyright", "-timeout"},
+		{"ffmpeg version 3.4.11 Copyright", "-stimeout"},
+	} {
+		var c Caps
+		c.Version, c.Major, c.Minor = parseVersion(v.version)
+		if got := c.RTSPTimeoutOption(); got != v.want {
+			t.Fatalf("%s: %s", v.version, got)
+		}
+	}
+}
+
+func TestCheckRTSPTransport(t *testing.T) {
+	c := Caps{Protocols: []string{"file", "tcp"}}
+	if err := c.CheckRTSPTransport("tcp"); err != nil {
+		t.Fatalf("tcp: %v", err)
+	}
+	if err := c.CheckRTSPTransport("udp"); err == nil {
+		t.Fatal("udp without the udp protocol should fail")
+	}
+}
+
+func TestCapsCache(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "camsnap", "ffmpeg-caps.json")
+	key := capsKey{Path: "/usr/bin/ffmpeg", Size: 300000, ModTime: 1}
+	if _, ok := loadCaps(file, key); ok {
+		t.Fatal("empty cache hit")
+	}
+	want := Caps{Version: "6.1.1", Major: 6, Minor: 1, Encoders: []string{"mjpeg"}}
+	if err := saveCaps(file, key, want); err != nil {
+		t.Fatal(err)
+	}
+	got, ok := loadCaps(file, key)
+	if !ok || got.Version != want.Version || !slices.Equal(got.Encoders, want.Encoders) {
+		t.Fatalf("cached %+v %v", got, ok)
+	}
+	key.ModTime = 2
+	if _, ok := loadCaps(file, key); ok {
+		t.Fatal("a changed binary must miss the cache")
+	}
+}
@@ -0,0 +1,173 @@
+// Package exectest provides a scripted ffmpeg for tests of commands that shell out to it.
+package exectest
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	iexec "github.com/steipete/camsnap/internal/exec"
+)
+
+//go:embed fixtures/*.log
+var fixtures embed.FS
+
+// Reply scripts one ffmpeg run.
+type Reply struct {
+	Stderr string // combined output for Run, the log stream for Start
+	Exit   int    // non-zero fails the run with this exit status
+	Output []byte // written to the output file (last argument), or stdout for pipe:1, when the run succeeds
+}
+
+// Fake is an iexec.Runner that records every call and answers with scripted replies. Replies are
+// served in order and the last repeats; without any, runs succeed with no output.
+type Fake struct {
+	mu      sync.Mutex
+	missing bool
+	caps    iexec.Caps
+	replies []Reply
+	calls   [][]string
+}
+
+var _ iexec.Runner = (*Fake)(nil)
+
+// NewFake returns a fake whose runs succeed silently.
+func NewFake() *Fake { return &Fake{} }
+
+// SetReplies replaces the scripted replies.
+func (f *Fake) SetReplies(replies ...Reply) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.replies = replies
+}
+
+// SetMissing makes Available report ffmpeg as not installed.
+func (f *Fake) SetMissing(missing bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.missing = missing
+}
+
+// SetCaps sets what Caps reports; the default is an unknown build, which supports everything.
+func (f *Fake) SetCaps(c iexec.Caps) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.caps = c
+}
+
+// Calls returns the argument lists of the runs so far.
+func (f *Fake) Calls() [][]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := make([][]string, len(f.calls))
+	for i, c := range f.calls {
+		out[i] = append([]string(nil), c...)
+	}
+	return out
+}
+
+// Available implements iexec.Runner.
+func (f *Fake) Available(string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return !f.missing
+}
+
+// Caps implements iexec.Runner.
+func (f *Fake) Caps(context.Context) iexec.Caps {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.caps
+}
+
+// Run implements iexec.Runner. Failures go through iexec.Failure, so they classify like real ones.
+func (f *Fake) Run(ctx context.Context, args ...string) (string, error) {
+	r := f.next(args)
+	if err := ctx.Err(); err != nil {
+		return "", iexec.Failure(ctx, args, err, "")
+	}
+	if err := finish(r, args); err != nil {
+		return r.Stderr, iexec.Failure(ctx, args, err, r.Stderr)
+	}
+	return r.Stderr, nil
+}
+
+// Start implements iexec.Runner; stdin is drained so producers never block. A successful run
+// writes Output to stdout when the output is pipe:1 or "-".
+func (f *Fake) Start(_ context.Context, stdin io.Reader, stdout io.Writer, args ...string) (iexec.Process, error) {
+	r := f.next(args)
+	if stdin != nil {
+		go func() {
+			_, _ = io.Copy(io.Discard, stdin)
+		}()
+	}
+	return &process{reply: r, args: args, stdout: stdout, stderr: strings.NewReader(r.Stderr)}, nil
+}
+
+// Fixture returns a recorded ffmpeg log from fixtures/ by name (without .log), or "" if none exists.
+func Fixture(name string) string {
+	data, err := fixtures.ReadFile("fixtures/" + name + ".log")
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func (f *Fake) next(args []string) Reply {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, append([]string(nil), args...))
+	if len(f.replies) == 0 {
+		return Reply{}
+	}
+	r := f.replies[0]
+	if len(f.replies) > 1 {
+		f.replies = f.replies[1:]
+	}
+	return r
+}
+
+type process struct {
+	reply  Reply
+	args   []string
+	stdout io.Writer
+	stderr io.Reader
+}
+
+func (p *process) Stderr() io.Reader { return p.stderr }
+
+func (p *process) Wait() error {
+	if err := finish(p.reply, p.args); err != nil {
+		return err
+	}
+	if out := p.args[len(p.args)-1]; p.stdout != nil && (out == "pipe:1" || out == "-") {
+		if _, err := p.stdout.Write(p.reply.Output); err != nil {
+			return fmt.Errorf("write stdout: %w", err)
+		}
+	}
+	return nil
+}
+
+// ExitError is the error of a scripted non-zero exit.
+type ExitError struct{ Code int }
+
+func (e *ExitError) Error() string { return fmt.Sprintf("exit status %d", e.Code) }
+
+// finish writes the output file of a successful run, or returns the scripted exit.
+func finish(r Reply, args []string) error {
+	if r.Exit != 0 {
+		return &ExitError{Code: r.Exit}
+	}
+	if len(args) == 0 {
+		return nil
+	}
+	out := args[len(args)-1]
+	if out == "-" || strings.HasPrefix(out, "pipe:") || strings.HasPrefix(out, "-") {
+		return nil
+	}
+	return os.WriteFile(out, r.Output, 0o600)
+}
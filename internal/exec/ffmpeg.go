@@ -3,27 +3,14 @@ package exec
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"os/exec"
+	"regexp"
 	"strings"
 	"time"
-)
-
-// RunFFmpeg executes ffmpeg with a timeout, returning combined output on error.
-func RunFFmpeg(ctx context.Context, args ...string) error {
-	_, err := RunFFmpegWithOutput(ctx, args...)
-	return err
-}
 
-// RunFFmpegWithOutput returns combined stdout/stderr output alongside error.
-func RunFFmpegWithOutput(ctx context.Context, args ...string) (string, error) {
-	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return string(output), fmt.Errorf("ffmpeg %v failed: %w\n%s", args, err, string(output))
-	}
-	return string(output), nil
-}
+	"github.com/steipete/camsnap/internal/camerr"
+)
 
 // WithTimeout returns a derived context that times out.
 func WithTimeout(parent context.Context, d time.Duration) (context.Context, context.CancelFunc) {
@@ -39,19 +26,56 @@ func HasBinary(name string) bool {
 	return err == nil
 }
 
-// ClassifyError inspects ffmpeg stderr to differentiate auth vs network errors.
-func ClassifyError(stderr string) string {
-	lower := strings.ToLower(stderr)
-	switch {
-	case strings.Contains(lower, "401") || strings.Contains(lower, "unauthorized") || strings.Contains(lower, "auth"):
-		return "auth"
-	case strings.Contains(lower, "connection refused"):
-		return "network-refused"
-	case strings.Contains(lower, "timed out") || strings.Contains(lower, "timeout"):
-		return "network-timeout"
-	case strings.Contains(lower, "not found"):
-		return "not-found"
-	default:
-		return "unknown"
+// statusIn matches an RTSP/HTTP status code in the context ffmpeg reports it in; a bare number may
+// be a channel in a URL or a frame count.
+func statusIn(codes string) string {
+	return `(server returned|method \w+ failed:|http error)\s*(` + codes + `)\b`
+}
+
+// stderrKinds maps ffmpeg stderr messages to error kinds; first match wins, so auth beats the
+// generic network failures ffmpeg logs after a rejected DESCRIBE.
+var stderrKinds = []struct {
+	re   *regexp.Regexp
+	kind error
+}{
+	{regexp.MustCompile(`(?i)` + statusIn(`40[13]`) + `|unauthorized|forbidden|authentication failed|invalid credentials`), camerr.ErrAuth},
+	{regexp.MustCompile(`(?i)` + statusIn(`453|503`) + `|not enough bandwidth|too many (users|clients|connections|sessions)|service unavailable`), camerr.ErrSessionLimit},
+	{regexp.MustCompile(`(?i)` + statusIn(`404`) + `|stream not found|no such file`), camerr.ErrNotFound},
+	{regexp.MustCompile(`(?i)decoder .{0,40}not found|unsupported codec|could not find codec|` + statusIn(`415`)), camerr.ErrUnsupportedCodec},
+	{regexp.MustCompile(`(?i)connection refused|no route to host|(network|host) (is )?unreachable|name or service not known|failed to resolve|temporary failure in name resolution|connection reset`), camerr.ErrUnreachable},
+	{regexp.MustCompile(`(?i)timed out|\btimeout\b`), camerr.ErrTimeout},
+}
+
+var (
+	logPrefix = regexp.MustCompile(`^\s*(\[[^\]]*\]\s*)*`)
+	urlText   = regexp.MustCompile(`(?i)\b[a-z][a-z0-9+.-]*://\S*`)
+)
+
+// StderrKind returns the camerr kind ffmpeg's stderr points at, or nil. Stats and -progress lines
+// are skipped and URLs removed first: their numbers are not status codes.
+func StderrKind(stderr string) error {
+	var lines []string
+	for _, line := range strings.Split(stderr, "\n") {
+		body := logPrefix.ReplaceAllString(line, "")
+		if strings.HasPrefix(body, "frame=") || progressLine.MatchString(strings.TrimSpace(body)) {
+			continue
+		}
+		lines = append(lines, urlText.ReplaceAllString(line, "<url>"))
+	}
+	text := strings.Join(lines, "\n")
+	for _, k := range stderrKinds {
+		if k.re.MatchString(text) {
+			return k.kind
+		}
+	}
+	return nil
+}
+
+// ffmpegError tags a failed run with the kind its output reports; a ctx deadline counts as a timeout.
+func ffmpegError(ctx context.Context, err error, output string) error {
+	kind := StderrKind(output)
+	if kind == nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		kind = camerr.ErrTimeout
 	}
+	return camerr.Wrap(kind, err)
 }
@@ -2,24 +2,40 @@ package exec
 
 import (
 	"context"
+	"errors"
 	"testing"
+
+	"github.com/steipete/camsnap/internal/camerr"
 )
 
-func TestClassifyError(t *testing.T) {
+func TestStderrKind(t *testing.T) {
 	cases := []struct {
 		err  string
 		want string
 	}{
 		{"401 Unauthorized", "auth"},
-		{"Server returned 401 unauthorized", "auth"},
-		{"Connection refused", "network-refused"},
-		{"timed out", "network-timeout"},
-		{"not found", "not-found"},
+		{"method DESCRIBE failed: 401 Unauthorized\nConnection refused", "auth"},
+		{"Server returned 403 Forbidden (access denied)", "auth"},
+		{"method SETUP failed: 453 Not Enough Bandwidth", "session-limit"},
+		{"method DESCRIBE failed: 404 Stream Not Found", "not-found"},
+		{"Decoder (codec hevc) not found for input stream #0:0", "unsupported-codec"},
+		{"Connection refused", "unreachable"},
+		{"No route to host", "unreachable"},
+		{"Connection timed out", "timeout"},
+		{"author: the rtsp server", "unknown"},
+		{"frame 4010 size 1024", "unknown"},
+		{"HTTP error 401 Unauthorized", "auth"},
+		{"Server returned 404 Not Found", "not-found"},
+		// numbers in URLs and stats are not status codes
+		{"rtsp://host/Streaming/Channels/401: Connection refused", "unreachable"},
+		{"rtsp://u:p@host:554/Streaming/Channels/404: Server returned 404 Not Found", "not-found"},
+		{"[info] frame=  401 fps=0.0 q=-1.0 size=     453kB time=00:00:26.73 bitrate= 503.0kbits/s speed=1x", "unknown"},
+		{"total_size=401\nframe=404\n[rtsp @ 0x1] [error] Connection timed out", "timeout"},
 		{"weird message", "unknown"},
 	}
 	for _, c := range cases {
-		if got := ClassifyError(c.err); got != c.want {
-			t.Fatalf("ClassifyError(%q) got %s want %s", c.err, got, c.want)
+		if got := camerr.Class(camerr.Wrap(StderrKind(c.err), errors.New(c.err))); got != c.want {
+			t.Fatalf("StderrKind(%q) got %s want %s", c.err, got, c.want)
 		}
 	}
 }
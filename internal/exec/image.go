@@ -0,0 +1,205 @@
+package exec
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Image is how a snapshot is encoded: format, crop, size and quality. The zero value is a
+// full-size JPEG in the format the output extension implies.
+type Image struct {
+	Format  string  // jpeg|png|webp|avif; empty = from the output extension, else jpeg
+	Encoder string  // ffmpeg encoder, set by Resolve
+	Width   int     // 0 = follow Height, keeping the aspect ratio
+	Height  int     // 0 = follow Width; with both set the picture fits inside the box
+	Scale   float64 // resize factor, instead of Width/Height; 0 = none
+	Quality int     // 1-100, higher is better; 0 = the format's default (ignored for png)
+	Crop    *Rect   // cut out before resizing, in source pixels
+}
+
+// Rect is a crop rectangle.
+type Rect struct{ X, Y, W, H int }
+
+// ParseCrop reads "x,y,w,h".
+func ParseCrop(s string) (*Rect, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("invalid crop %q (use x,y,w,h)", s)
+	}
+	var v [4]int
+	for i, p := range parts {
+		n, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid crop %q (use x,y,w,h)", s)
+		}
+		v[i] = n
+	}
+	if v[2] == 0 || v[3] == 0 {
+		return nil, fmt.Errorf("invalid crop %q: width and height must be > 0", s)
+	}
+	return &Rect{X: v[0], Y: v[1], W: v[2], H: v[3]}, nil
+}
+
+// ParseImageFormat normalizes a format name; "" stays "" (decided by the output path).
+func ParseImageFormat(s string) (string, bool) {
+	switch strings.ToLower(s) {
+	case "":
+		return "", true
+	case "jpeg", "jpg":
+		return "jpeg", true
+	case "png", "webp", "avif":
+		return strings.ToLower(s), true
+	}
+	return "", false
+}
+
+// ImageExt is the file extension for format.
+func ImageExt(format string) string {
+	if format == "jpeg" || format == "" {
+		return ".jpg"
+	}
+	return "." + format
+}
+
+// imageEncoders are the ffmpeg encoders per format, best first.
+var imageEncoders = map[string][]string{
+	"jpeg": {"mjpeg"},
+	"png":  {"png"},
+	"webp": {"libwebp"},
+	"avif": {"libaom-av1", "libsvtav1", "librav1e"},
+}
+
+// Resolve validates im for outPath and the ffmpeg build and fills in Format and Encoder.
+func (im Image) Resolve(outPath string, caps Caps) (Image, error) {
+	if im.Format == "" {
+		im.Format, _ = ParseImageFormat(strings.TrimPrefix(filepath.Ext(outPath), "."))
+		if im.Format == "" {
+			im.Format = "jpeg"
+		}
+	}
+	switch {
+	case im.Width < 0 || im.Height < 0:
+		return im, fmt.Errorf("width and height must be >= 0")
+	case im.Scale < 0 || im.Scale > 8:
+		return im, fmt.Errorf("scale must be between 0 and 8")
+	case im.Scale > 0 && (im.Width > 0 || im.Height > 0):
+		return im, fmt.Errorf("use either a scale factor or width/height, not both")
+	case im.Quality < 0 || im.Quality > 100:
+		return im, fmt.Errorf("quality must be between 1 and 100")
+	}
+	if im.Format == "avif" && !caps.AtLeast(5, 1) {
+		return im, fmt.Errorf("AVIF needs ffmpeg 5.1 or newer (found %s)", caps.Version)
+	}
+	for _, enc := range imageEncoders[im.Format] {
+		if caps.HasEncoder(enc) {
+			im.Encoder = enc
+			break
+		}
+	}
+	if im.Encoder == "" {
+		return im, fmt.Errorf("this ffmpeg build has no %s encoder for %s; pick another format",
+			strings.Join(imageEncoders[im.Format], "/"), im.Format)
+	}
+	for _, f := range []string{"crop", "scale"} {
+		if im.needs(f) && !caps.HasFilter(f) {
+			return im, fmt.Errorf("this ffmpeg build has no %s filter", f)
+		}
+	}
+	return im, nil
+}
+
+func (im Image) needs(filter string) bool {
+	if filter == "crop" {
+		return im.Crop != nil
+	}
+	return im.Width > 0 || im.Height > 0 || im.Scale > 0
+}
+
+// Passthrough reports whether a JPEG frame can be written as it is.
+func (im Image) Passthrough() bool {
+	return im.Format == "jpeg" && im.Quality == 0 && !im.needs("crop") && !im.needs("scale")
+}
+
+// Args are the ffmpeg output options that encode one frame as im; Resolve first.
+func (im Image) Args() []string {
+	args := im.filterArgs()
+	args = append(args, "-frames:v", "1", "-c:v", im.Encoder)
+	args = append(args, im.qualityArgs()...)
+	// the extension may not name the format (temp files, --format overrides)
+	if im.Format == "avif" {
+		args = append(args, "-f", "avif")
+	} else {
+		args = append(args, "-f", "image2", "-update", "1")
+	}
+	return args
+}
+
+// BurstArgs are the ffmpeg output options that encode a frame every interval as im, count in all
+// (0 = until stopped), as concatenated images for pipe:1 (see ScanImages); Resolve first.
+func (im Image) BurstArgs(every time.Duration, count int) []string {
+	args := im.filterArgs(fmt.Sprintf("fps=1/%g", every.Seconds()))
+	if count > 0 {
+		args = append(args, "-frames:v", strconv.Itoa(count))
+	}
+	args = append(args, "-c:v", im.Encoder)
+	args = append(args, im.qualityArgs()...)
+	return append(args, "-f", "image2pipe")
+}
+
+// filterArgs is the -vf option for the filters in pre, then the crop and resize, if any.
+func (im Image) filterArgs(pre ...string) []string {
+	filters := pre
+	if c := im.Crop; c != nil {
+		filters = append(filters, fmt.Sprintf("crop=%d:%d:%d:%d", c.W, c.H, c.X, c.Y))
+	}
+	switch {
+	case im.Scale > 0:
+		filters = append(filters, fmt.Sprintf("scale=trunc(iw*%[1]g/2)*2:trunc(ih*%[1]g/2)*2", im.Scale))
+	case im.Width > 0 && im.Height > 0:
+		filters = append(filters, fmt.Sprintf("scale=%d:%d:force_original_aspect_ratio=decrease", im.Width, im.Height))
+	case im.Width > 0:
+		filters = append(filters, fmt.Sprintf("scale=%d:-2", im.Width))
+	case im.Height > 0:
+		filters = append(filters, fmt.Sprintf("scale=-2:%d", im.Height))
+	}
+	if len(filters) == 0 {
+		return nil
+	}
+	return []string{"-vf", strings.Join(filters, ",")}
+}
+
+// qualityArgs maps Quality (1-100) onto the encoder's own scale.
+func (im Image) qualityArgs() []string {
+	q := float64(im.Quality)
+	switch im.Encoder {
+	case "mjpeg":
+		if im.Quality == 0 {
+			return []string{"-q:v", "2"}
+		}
+		return []string{"-q:v", strconv.Itoa(2 + int(math.Round((100-q)*29/99)))} // 2 best .. 31 worst
+	case "libwebp":
+		if im.Quality == 0 {
+			q = 90
+		}
+		return []string{"-quality", strconv.Itoa(int(q))}
+	case "libaom-av1", "libsvtav1":
+		if im.Quality == 0 {
+			q = 60
+		}
+		args := []string{"-crf", strconv.Itoa(int(math.Round((100 - q) * 63 / 100)))} // 0 best .. 63 worst
+		if im.Encoder == "libaom-av1" {
+			args = append(args, "-b:v", "0", "-still-picture", "1")
+		}
+		return args
+	case "librav1e":
+		if im.Quality == 0 {
+			q = 60
+		}
+		return []string{"-qp", strconv.Itoa(int(math.Round((100 - q) * 255 / 100)))} // 0 best .. 255 worst
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package exec
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestImageArgs(t *testing.T) {
+	modern := Caps{Version: "7.0", Major: 7, Encoders: []string{"mjpeg", "png", "libwebp", "libsvtav1"}}
+	cases := []struct {
+		img  Image
+		out  string
+		want string
+	}{
+		{Image{}, "a.jpg", "-frames:v 1 -c:v mjpeg -q:v 2 -f image2 -update 1"},
+		{Image{}, "a.tmp", "-frames:v 1 -c:v mjpeg -q:v 2 -f image2 -update 1"},
+		{Image{Quality: 100}, "a.jpeg", "-frames:v 1 -c:v mjpeg -q:v 2 -f image2 -update 1"},
+		{Image{Quality: 1}, "a.jpeg", "-frames:v 1 -c:v mjpeg -q:v 31 -f image2 -update 1"},
+		{Image{Width: 640, Height: 480}, "a.png", "-vf scale=640:480:force_original_aspect_ratio=decrease -frames:v 1 -c:v png -f image2 -update 1"},
+		{Image{Height: 240}, "a.webp", "-vf scale=-2:240 -frames:v 1 -c:v libwebp -quality 90 -f image2 -update 1"},
+		// no libaom in this build, so AVIF falls back to SVT-AV1
+		{Image{Format: "avif", Quality: 50, Crop: &Rect{0, 0, 100, 50}}, "a.jpg", "-vf crop=100:50:0:0 -frames:v 1 -c:v libsvtav1 -crf 32 -f avif"},
+	}
+	for _, c := range cases {
+		img, err := c.img.Resolve(c.out, modern)
+		if err != nil {
+			t.Fatalf("%+v: %v", c.img, err)
+		}
+		if got := strings.Join(img.Args(), " "); got != c.want {
+			t.Fatalf("%+v %s:\n got %s\nwant %s", c.img, c.out, got, c.want)
+		}
+	}
+
+	old := Caps{Version: "4.4.2", Major: 4, Minor: 4}
+	if _, err := (Image{Format: "avif"}).Resolve("a.avif", old); err == nil {
+		t.Fatal("AVIF on ffmpeg 4.4 should fail")
+	}
+	if _, err := (Image{}).Resolve("a.webp", modern); err != nil {
+		t.Fatalf("webp: %v", err)
+	}
+	if _, err := (Image{}).Resolve("a.webp", Caps{Encoders: []string{"mjpeg"}}); err == nil {
+		t.Fatal("webp without libwebp should fail")
+	}
+	if img, _ := (Image{}).Resolve("a.JPG", modern); !img.Passthrough() {
+		t.Fatal("a plain JPEG should pass through")
+	}
+	if img, _ := (Image{Scale: 0.5}).Resolve("a.jpg", modern); img.Passthrough() {
+		t.Fatal("a resized JPEG cannot pass through")
+	}
+}
+
+func TestParseCrop(t *testing.T) {
+	r, err := ParseCrop("10, 20,300,200")
+	if err != nil || *r != (Rect{10, 20, 300, 200}) {
+		t.Fatalf("crop %+v %v", r, err)
+	}
+	for _, bad := range []string{"", "1,2,3", "a,b,c,d", "0,0,0,10", "-1,0,10,10"} {
+		if _, err := ParseCrop(bad); err == nil {
+			t.Fatalf("%q: expected an error", bad)
+		}
+	}
+}
+
+func TestImageBurstArgs(t *testing.T) {
+	img, err := (Image{Width: 640}).Resolve("a.jpg", Caps{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "-vf fps=1/2,scale=640:-2 -frames:v 3 -c:v mjpeg -q:v 2 -f image2pipe"
+	if got := strings.Join(img.BurstArgs(2*time.Second, 3), " "); got != want {
+		t.Fatalf("got %s\nwant %s", got, want)
+	}
+	want = "-vf fps=1/60 -c:v mjpeg -q:v 2 -f image2pipe"
+	if got := strings.Join((Image{Encoder: "mjpeg"}).BurstArgs(time.Minute, 0), " "); got != want {
+		t.Fatalf("got %s\nwant %s", got, want)
+	}
+}
@@ -0,0 +1,131 @@
+package exec
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// ScanImages is a bufio.SplitFunc for image2pipe output: it splits a stream of back-to-back jpeg,
+// png or webp files into single images (AVIF frames come out as bare AV1 and cannot be split).
+func ScanImages(format string) bufio.SplitFunc {
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		if atEOF && len(data) == 0 {
+			return 0, nil, nil
+		}
+		var n int
+		var err error
+		switch format {
+		case "jpeg":
+			n, err = jpegLen(data)
+		case "png":
+			n, err = pngLen(data)
+		case "webp":
+			n, err = webpLen(data)
+		default:
+			return 0, nil, fmt.Errorf("cannot split a stream of %s images", format)
+		}
+		switch {
+		case err != nil:
+			return 0, nil, err
+		case n > 0:
+			return n, data[:n], nil
+		case atEOF:
+			return 0, nil, fmt.Errorf("%s image cut short at the end of the stream", format)
+		}
+		return 0, nil, nil
+	}
+}
+
+// jpegLen is the length of the JPEG at the start of data, or 0 if it has not all arrived. It walks
+// the marker segments; inside scan data 0xFF is always followed by a stuffed 0x00 or a restart
+// marker, so the next real marker ends the scan.
+func jpegLen(data []byte) (int, error) {
+	if len(data) < 2 {
+		return 0, nil
+	}
+	if data[0] != 0xFF || data[1] != 0xD8 {
+		return 0, fmt.Errorf("not a JPEG image")
+	}
+	i := 2
+	for {
+		if i+2 > len(data) {
+			return 0, nil
+		}
+		if data[i] != 0xFF {
+			return 0, fmt.Errorf("bad JPEG marker at byte %d", i)
+		}
+		marker := data[i+1]
+		switch {
+		case marker == 0xFF: // fill byte
+			i++
+			continue
+		case marker == 0xD9: // EOI
+			return i + 2, nil
+		case marker >= 0xD0 && marker <= 0xD7 || marker == 0x01: // no length
+			i += 2
+			continue
+		}
+		if i+4 > len(data) {
+			return 0, nil
+		}
+		i += 2 + int(binary.BigEndian.Uint16(data[i+2:]))
+		if marker != 0xDA { // SOS
+			continue
+		}
+		for {
+			if i+1 >= len(data) {
+				return 0, nil
+			}
+			if data[i] == 0xFF && data[i+1] != 0 && (data[i+1] < 0xD0 || data[i+1] > 0xD7) {
+				break
+			}
+			i++
+		}
+	}
+}
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// pngLen is the length of the PNG at the start of data (through its IEND chunk), or 0 if it has
+// not all arrived.
+func pngLen(data []byte) (int, error) {
+	if len(data) < len(pngSignature) {
+		return 0, nil
+	}
+	if !bytes.HasPrefix(data, pngSignature) {
+		return 0, fmt.Errorf("not a PNG image")
+	}
+	i := len(pngSignature)
+	for {
+		if i+8 > len(data) {
+			return 0, nil
+		}
+		end := i + 12 + int(binary.BigEndian.Uint32(data[i:])) // length, type, data, crc
+		if string(data[i+4:i+8]) == "IEND" {
+			if end > len(data) {
+				return 0, nil
+			}
+			return end, nil
+		}
+		i = end
+	}
+}
+
+// webpLen is the length of the WebP at the start of data, from its RIFF header, or 0 if it has not
+// all arrived.
+func webpLen(data []byte) (int, error) {
+	if len(data) < 12 {
+		return 0, nil
+	}
+	if string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return 0, fmt.Errorf("not a WebP image")
+	}
+	size := int(binary.LittleEndian.Uint32(data[4:]))
+	n := 8 + size + size%2 // chunks are padded to even sizes
+	if n > len(data) {
+		return 0, nil
+	}
+	return n, nil
+}
@@ -0,0 +1,77 @@
+package exec
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func testPicture(shade uint8) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	for y := 0; y < 48; y++ {
+		for x := 0; x < 64; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x * 4), G: uint8(y * 5), B: shade, A: 255})
+		}
+	}
+	return img
+}
+
+func TestScanImages(t *testing.T) {
+	encode := map[string]func(*bytes.Buffer, image.Image) error{
+		"jpeg": func(b *bytes.Buffer, img image.Image) error { return jpeg.Encode(b, img, &jpeg.Options{Quality: 90}) },
+		"png":  func(b *bytes.Buffer, img image.Image) error { return png.Encode(b, img) },
+		"webp": func(b *bytes.Buffer, img image.Image) error {
+			payload := []byte{1, 2, 3, 4, 5} // odd size: the chunk is padded
+			_, _ = b.WriteString("RIFF")
+			_ = binary.Write(b, binary.LittleEndian, uint32(4+8+len(payload)+1))
+			_, _ = b.WriteString("WEBPVP8L")
+			_ = binary.Write(b, binary.LittleEndian, uint32(len(payload)))
+			_, _ = b.Write(append(payload, 0))
+			return nil
+		},
+	}
+	for format, enc := range encode {
+		var stream bytes.Buffer
+		var want [][]byte
+		for i := 0; i < 3; i++ {
+			var one bytes.Buffer
+			if err := enc(&one, testPicture(uint8(i*100))); err != nil {
+				t.Fatal(err)
+			}
+			want = append(want, one.Bytes())
+			stream.Write(one.Bytes())
+		}
+		// a small buffer makes the scanner feed partial images
+		sc := bufio.NewScanner(&stream)
+		sc.Buffer(make([]byte, 16), 1<<20)
+		sc.Split(ScanImages(format))
+		var got [][]byte
+		for sc.Scan() {
+			got = append(got, append([]byte(nil), sc.Bytes()...))
+		}
+		if err := sc.Err(); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if len(got) != len(want) {
+			t.Fatalf("%s: got %d images, want %d", format, len(got), len(want))
+		}
+		for i := range want {
+			if !bytes.Equal(got[i], want[i]) {
+				t.Fatalf("%s: image %d differs (%d vs %d bytes)", format, i, len(got[i]), len(want[i]))
+			}
+		}
+	}
+
+	sc := bufio.NewScanner(bytes.NewReader([]byte{0xFF, 0xD8, 0xFF, 0xDB, 0x00}))
+	sc.Split(ScanImages("jpeg"))
+	for sc.Scan() {
+	}
+	if sc.Err() == nil {
+		t.Fatal("a truncated JPEG should fail")
+	}
+}
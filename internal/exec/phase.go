@@ -0,0 +1,201 @@
+package exec
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/steipete/camsnap/internal/camerr"
+)
+
+// Phase is how far an ffmpeg run reading an RTSP input got.
+type Phase int
+
+// Phases in the order a run goes through them.
+const (
+	PhaseConnect    Phase = iota // TCP connect to the camera
+	PhaseDescribe                // connected, waiting for the DESCRIBE answer
+	PhaseFirstFrame              // got the SDP, waiting for SETUP/PLAY and the first frames
+	PhaseRecording               // frames are flowing to the output
+)
+
+var phaseNames = [...]string{"connect", "describe", "first-frame", "recording"}
+
+func (p Phase) String() string { return phaseNames[p] }
+
+// waiting describes a run stuck in p.
+func (p Phase) waiting() string {
+	switch p {
+	case PhaseConnect:
+		return "connecting to the camera"
+	case PhaseDescribe:
+		return "waiting for the DESCRIBE answer"
+	case PhaseFirstFrame:
+		return "waiting for the first frame"
+	}
+	return "while recording"
+}
+
+// phaseMarkers are the log lines that show a run reached a phase. "Successfully connected" is
+// logged at verbose level (ffmpeg 4.3+), the SDP at verbose or debug depending on the version.
+var phaseMarkers = []struct {
+	phase Phase
+	text  string
+}{
+	{PhaseDescribe, "Successfully connected to"},
+	{PhaseDescribe, "method DESCRIBE"},
+	{PhaseFirstFrame, "SDP:"},
+	{PhaseFirstFrame, "method SETUP"},
+	{PhaseFirstFrame, "method PLAY"},
+	{PhaseRecording, "Input #0"},
+	{PhaseRecording, "Output #0"},
+	{PhaseRecording, "Press [q] to stop"},
+}
+
+var (
+	logLevelTag = regexp.MustCompile(`\[(trace|debug|verbose|info|warning|error|fatal|panic)\] `)
+	stallLine   = regexp.MustCompile(`(?i)(connection|operation) timed out`)
+)
+
+// phaseLog follows an ffmpeg log: the phase reached and the last lines worth showing.
+type phaseLog struct {
+	phase   Phase
+	stalled bool // a socket timeout was logged once frames were flowing
+	tail    []string
+}
+
+func (l *phaseLog) line(line string) {
+	level := ""
+	if m := logLevelTag.FindStringSubmatch(line); m != nil {
+		level, line = m[1], strings.Replace(line, m[0], "", 1)
+	}
+	for _, m := range phaseMarkers {
+		if m.phase > l.phase && strings.Contains(line, m.text) {
+			l.phase = m.phase
+		}
+	}
+	if strings.HasPrefix(strings.TrimSpace(line), "frame=") {
+		l.phase = PhaseRecording
+	}
+	if l.phase == PhaseRecording && stallLine.MatchString(line) {
+		l.stalled = true
+	}
+	// debug/verbose lines only drive the phase; keep them out of error messages
+	switch level {
+	case "trace", "debug", "verbose":
+		return
+	}
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	l.tail = append(l.tail, line)
+	if len(l.tail) > 20 {
+		l.tail = l.tail[1:]
+	}
+}
+
+func (l *phaseLog) String() string { return strings.Join(l.tail, "\n") }
+
+// ScanLogLines is a bufio.SplitFunc for ffmpeg logs: lines end in \n, or in \r for the stats line
+// ffmpeg keeps rewriting.
+func ScanLogLines(data []byte, atEOF bool) (int, []byte, error) {
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		if data[i] == '\r' {
+			if i+1 == len(data) && !atEOF {
+				return 0, nil, nil // might be \r\n
+			}
+			if i+1 < len(data) && data[i+1] == '\n' {
+				return i + 2, data[:i], nil
+			}
+		}
+		return i + 1, data[:i], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// PhaseError is a failed RTSP run and the phase it failed in.
+type PhaseError struct {
+	Phase Phase
+	Err   error
+}
+
+func (e *PhaseError) Error() string {
+	if errors.Is(e.Err, camerr.ErrTimeout) {
+		return fmt.Sprintf("timed out %s: %v", e.Phase.waiting(), e.Err)
+	}
+	return fmt.Sprintf("failed during %s: %v", e.Phase, e.Err)
+}
+
+func (e *PhaseError) Unwrap() error { return e.Err }
+
+// RTSPLogLevel is the -loglevel RunRTSP needs to see the phase markers; the level tags (4.0+) let
+// it keep verbose lines out of error messages. Debug adds the RTSP requests, which older builds
+// need to tell connect from describe, at the cost of a much chattier log.
+func (c Caps) RTSPLogLevel(debug bool) string {
+	level := "verbose"
+	if debug {
+		level = "debug"
+	}
+	if c.AtLeast(4, 0) {
+		return "level+" + level
+	}
+	return level
+}
+
+// debugEnv opts RunRTSP into debug-level ffmpeg logs.
+const debugEnv = "CAMSNAP_FFMPEG_DEBUG"
+
+// RTSPOptions are the optional parts of RunRTSP.
+type RTSPOptions struct {
+	Stdout     io.Writer      // gets ffmpeg's stdout, for outputs written to pipe:1
+	OnProgress func(Progress) // gets every -progress report (see ProgressArgs)
+}
+
+// RunRTSP runs ffmpeg on args, which read an RTSP input, and follows its log to tell how far it
+// got. Failures are *PhaseError, tagged with a camerr kind like Failure's. A socket timeout once
+// frames were flowing makes ffmpeg finish the output and exit 0; that still fails, as a timeout
+// while recording, because the output is cut short.
+func RunRTSP(ctx context.Context, r Runner, caps Caps, opts RTSPOptions, args ...string) error {
+	pre := []string{"-hide_banner", "-loglevel", caps.RTSPLogLevel(os.Getenv(debugEnv) != "")}
+	if opts.OnProgress != nil {
+		pre = append(pre, ProgressArgs...)
+	}
+	args = append(pre, args...)
+	p, err := r.Start(ctx, nil, opts.Stdout, args...)
+	if err != nil {
+		return err
+	}
+	var log phaseLog
+	progress := progressParser{on: opts.OnProgress}
+	sc := bufio.NewScanner(p.Stderr())
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	sc.Split(ScanLogLines)
+	for sc.Scan() {
+		if opts.OnProgress != nil && progress.feed(sc.Text()) {
+			log.phase = PhaseRecording
+			continue
+		}
+		log.line(sc.Text())
+	}
+	if err := sc.Err(); err != nil {
+		_ = p.Wait()
+		return fmt.Errorf("read ffmpeg logs: %w", err)
+	}
+	if err := p.Wait(); err != nil {
+		return &PhaseError{Phase: log.phase, Err: Failure(ctx, args, err, log.String())}
+	}
+	if log.stalled {
+		err := fmt.Errorf("camera stopped sending; output is cut short\n%s", log.String())
+		return &PhaseError{Phase: PhaseRecording, Err: camerr.Wrap(camerr.ErrTimeout, err)}
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package exec
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func TestPhaseLog(t *testing.T) {
+	logs := "[tcp @ 0x1] [verbose] Successfully connected to 10.0.0.5 port 554\n" +
+		"[rtsp @ 0x2] [verbose] SDP:\n" +
+		"[info] frame=    1 fps=0.0 q=0.0 size=N/A time=00:00:00.06\r" +
+		"[info] frame=   12 fps= 12 q=0.0 size=N/A time=00:00:00.80\r\n" +
+		"[in#0/rtsp @ 0x3] [error] Error during demuxing: Operation timed out\n"
+	var l phaseLog
+	sc := bufio.NewScanner(strings.NewReader(logs))
+	sc.Split(ScanLogLines)
+	var phases []Phase
+	for sc.Scan() {
+		l.line(sc.Text())
+		phases = append(phases, l.phase)
+	}
+	want := []Phase{PhaseDescribe, PhaseFirstFrame, PhaseRecording, PhaseRecording, PhaseRecording}
+	if len(phases) != len(want) {
+		t.Fatalf("phases %v", phases)
+	}
+	for i := range want {
+		if phases[i] != want[i] {
+			t.Fatalf("line %d: %s, want %s", i, phases[i], want[i])
+		}
+	}
+	if !l.stalled || len(l.tail) != 3 || !strings.HasPrefix(l.tail[0], "frame=") {
+		t.Fatalf("stalled %v tail %q", l.stalled, l.tail)
+	}
+}
+
+func TestRTSPLogLevel(t *testing.T) {
+	for _, c := range []struct {
+		caps  Caps
+		debug bool
+		want  string
+	}{
+		{Caps{Major: 6, Minor: 1}, false, "level+verbose"},
+		{Caps{Major: 6, Minor: 1}, true, "level+debug"},
+		{Caps{}, false, "level+verbose"},
+		{Caps{Major: 3, Minor: 4}, false, "verbose"},
+		{Caps{Major: 3, Minor: 4}, true, "debug"},
+	} {
+		if got := c.caps.RTSPLogLevel(c.debug); got != c.want {
+			t.Fatalf("%d.%d debug=%v: %s, want %s", c.caps.Major, c.caps.Minor, c.debug, got, c.want)
+		}
+	}
+}
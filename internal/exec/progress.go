@@ -0,0 +1,68 @@
+package exec
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Progress is one report of ffmpeg -progress: what has been written so far.
+type Progress struct {
+	Frame       int64
+	FPS         float64
+	BitrateKbps float64
+	TotalSize   int64 // bytes
+	OutTime     time.Duration
+	DupFrames   int64
+	DropFrames  int64
+	Speed       float64 // 1 = realtime
+	End         bool    // the last report of the run
+}
+
+// ProgressArgs make ffmpeg write -progress reports to stderr, where RunRTSP picks them out of the
+// log, instead of its human stats line.
+var ProgressArgs = []string{"-progress", "pipe:2", "-nostats"}
+
+var progressLine = regexp.MustCompile(`^([a-z0-9_]+)=(\S*)$`)
+
+// progressParser collects -progress key=value lines into reports; a progress= line ends each.
+type progressParser struct {
+	cur Progress
+	on  func(Progress)
+}
+
+// feed consumes line if it is a -progress line and reports whether it was.
+func (p *progressParser) feed(line string) bool {
+	m := progressLine.FindStringSubmatch(strings.TrimSpace(line))
+	if m == nil {
+		return false
+	}
+	key, val := m[1], m[2]
+	switch key {
+	case "frame":
+		p.cur.Frame, _ = strconv.ParseInt(val, 10, 64)
+	case "fps":
+		p.cur.FPS, _ = strconv.ParseFloat(val, 64)
+	case "bitrate":
+		p.cur.BitrateKbps, _ = strconv.ParseFloat(strings.TrimSuffix(val, "kbits/s"), 64)
+	case "total_size":
+		p.cur.TotalSize, _ = strconv.ParseInt(val, 10, 64)
+	case "out_time_us":
+		if us, err := strconv.ParseInt(val, 10, 64); err == nil {
+			p.cur.OutTime = time.Duration(us) * time.Microsecond
+		}
+	case "dup_frames":
+		p.cur.DupFrames, _ = strconv.ParseInt(val, 10, 64)
+	case "drop_frames":
+		p.cur.DropFrames, _ = strconv.ParseInt(val, 10, 64)
+	case "speed":
+		p.cur.Speed, _ = strconv.ParseFloat(strings.TrimSuffix(val, "x"), 64)
+	case "progress":
+		p.cur.End = val == "end"
+		if p.on != nil {
+			p.on(p.cur)
+		}
+	}
+	return true
+}
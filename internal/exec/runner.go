@@ -0,0 +1,75 @@
+package exec
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+)
+
+// Runner runs ffmpeg. The CLI talks to media tools only through a Runner so tests can replace the
+// binary with a scripted fake (see exectest).
+type Runner interface {
+	// Available reports whether the named tool can be run.
+	Available(name string) bool
+	// Run runs ffmpeg to completion and returns its combined output; failures are tagged with a
+	// camerr kind (see Failure).
+	Run(ctx context.Context, args ...string) (string, error)
+	// Start launches ffmpeg reading stdin and writing its stdout to stdout (either may be nil) and
+	// returns a handle on its stderr.
+	Start(ctx context.Context, stdin io.Reader, stdout io.Writer, args ...string) (Process, error)
+	// Caps reports what the ffmpeg build supports (see Caps).
+	Caps(ctx context.Context) Caps
+}
+
+// Process is a running ffmpeg started by Runner.Start.
+type Process interface {
+	// Stderr streams ffmpeg's log output; read it to EOF before calling Wait.
+	Stderr() io.Reader
+	Wait() error
+}
+
+// FFmpeg runs the ffmpeg binary from PATH.
+type FFmpeg struct{}
+
+// Available implements Runner.
+func (FFmpeg) Available(name string) bool { return HasBinary(name) }
+
+// Run implements Runner.
+func (FFmpeg) Run(ctx context.Context, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return string(output), Failure(ctx, args, err, string(output))
+	}
+	return string(output), nil
+}
+
+// Start implements Runner.
+func (FFmpeg) Start(ctx context.Context, stdin io.Reader, stdout io.Writer, args ...string) (Process, error) {
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, fmt.Errorf("stderr pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start ffmpeg: %w", err)
+	}
+	return &process{cmd: cmd, stderr: stderr}, nil
+}
+
+type process struct {
+	cmd    *exec.Cmd
+	stderr io.Reader
+}
+
+func (p *process) Stderr() io.Reader { return p.stderr }
+func (p *process) Wait() error       { return p.cmd.Wait() }
+
+// Failure builds the error for a failed ffmpeg run: the args and output in the message, tagged with
+// the kind the output points at (a ctx deadline counts as a timeout).
+func Failure(ctx context.Context, args []string, err error, output string) error {
+	return ffmpegError(ctx, fmt.Errorf("ffmpeg %v failed: %w\n%s", args, err, output), output)
+}
@@ -0,0 +1,183 @@
+package fakecam
+
+// The test pattern is encoded without a codec library: keyframes are IDR slices made of I_PCM
+// macroblocks (raw samples, which every H264 decoder accepts) and the frames in between are P slices
+// that skip every macroblock, so the picture only changes on keyframes.
+
+// bitWriter writes an RBSP most significant bit first.
+type bitWriter struct {
+	buf  []byte
+	bits int // bits used in the last byte; 0 when byte aligned
+}
+
+func (w *bitWriter) u(n int, v uint32) {
+	for i := n - 1; i >= 0; i-- {
+		if w.bits == 0 {
+			w.buf = append(w.buf, 0)
+		}
+		if v>>uint(i)&1 == 1 {
+			w.buf[len(w.buf)-1] |= 0x80 >> uint(w.bits)
+		}
+		w.bits = (w.bits + 1) % 8
+	}
+}
+
+// ue writes an unsigned Exp-Golomb code.
+func (w *bitWriter) ue(v uint32) {
+	v++
+	n := 0
+	for t := v; t > 1; t >>= 1 {
+		n++
+	}
+	w.u(n, 0)
+	w.u(n+1, v)
+}
+
+// se writes a signed Exp-Golomb code.
+func (w *bitWriter) se(v int32) {
+	if v > 0 {
+		w.ue(uint32(2*v - 1))
+	} else {
+		w.ue(uint32(-2 * v))
+	}
+}
+
+func (w *bitWriter) align() {
+	for w.bits != 0 {
+		w.u(1, 0)
+	}
+}
+
+// trailing writes rbsp_trailing_bits.
+func (w *bitWriter) trailing() {
+	w.u(1, 1)
+	w.align()
+}
+
+// nalu prefixes the header byte and inserts emulation prevention bytes.
+func nalu(refIdc, typ byte, rbsp []byte) []byte {
+	out := make([]byte, 0, len(rbsp)+len(rbsp)/64+1)
+	out = append(out, refIdc<<5|typ)
+	zeros := 0
+	for _, b := range rbsp {
+		if zeros >= 2 && b <= 3 {
+			out = append(out, 3)
+			zeros = 0
+		}
+		out = append(out, b)
+		if b == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+	}
+	return out
+}
+
+const log2MaxFrameNum = 4
+
+// encoder produces constrained-baseline H264 access units for a w×h picture (multiples of 16).
+type encoder struct {
+	w, h     int
+	sps, pps []byte
+	frameNum uint32
+	idrID    uint32
+}
+
+func newEncoder(w, h int) *encoder {
+	e := &encoder{w: w, h: h}
+
+	var s bitWriter
+	s.u(8, 66)   // profile_idc: baseline
+	s.u(8, 0xc0) // constraint_set0/1: constrained baseline
+	s.u(8, 40)   // level_idc 4.0
+	s.ue(0)      // seq_parameter_set_id
+	s.ue(log2MaxFrameNum - 4)
+	s.ue(2) // pic_order_cnt_type: output order = decode order
+	s.ue(1) // max_num_ref_frames
+	s.u(1, 0)
+	s.ue(uint32(w/16 - 1))
+	s.ue(uint32(h/16 - 1))
+	s.u(1, 1) // frame_mbs_only_flag
+	s.u(1, 1) // direct_8x8_inference_flag
+	s.u(1, 0) // frame_cropping_flag
+	s.u(1, 0) // vui_parameters_present_flag
+	s.trailing()
+	e.sps = nalu(3, 7, s.buf)
+
+	var p bitWriter
+	p.ue(0)   // pic_parameter_set_id
+	p.ue(0)   // seq_parameter_set_id
+	p.u(1, 0) // entropy_coding_mode_flag: CAVLC
+	p.u(1, 0) // bottom_field_pic_order_in_frame_present_flag
+	p.ue(0)   // num_slice_groups_minus1
+	p.ue(0)   // num_ref_idx_l0_default_active_minus1
+	p.ue(0)   // num_ref_idx_l1_default_active_minus1
+	p.u(1, 0) // weighted_pred_flag
+	p.u(2, 0) // weighted_bipred_idc
+	p.se(0)   // pic_init_qp_minus26
+	p.se(0)   // pic_init_qs_minus26
+	p.se(0)   // chroma_qp_index_offset
+	p.u(1, 0) // deblocking_filter_control_present_flag
+	p.u(1, 0) // constrained_intra_pred_flag
+	p.u(1, 0) // redundant_pic_cnt_present_flag
+	p.trailing()
+	e.pps = nalu(3, 8, p.buf)
+	return e
+}
+
+// keyframe encodes pic (planar 4:2:0: w*h luma, then w*h/4 Cb and Cr) as SPS, PPS and an IDR slice.
+func (e *encoder) keyframe(pic []byte) [][]byte {
+	var s bitWriter
+	s.ue(0) // first_mb_in_slice
+	s.ue(7) // slice_type: I, all slices
+	s.ue(0) // pic_parameter_set_id
+	s.u(log2MaxFrameNum, 0)
+	s.ue(e.idrID % 2) // consecutive IDRs need different ids
+	s.u(1, 0)         // no_output_of_prior_pics_flag
+	s.u(1, 0)         // long_term_reference_flag
+	s.se(0)           // slice_qp_delta
+
+	cw, ch := e.w/2, e.h/2
+	cb := pic[e.w*e.h:]
+	cr := cb[cw*ch:]
+	for my := 0; my < e.h/16; my++ {
+		for mx := 0; mx < e.w/16; mx++ {
+			s.ue(25) // mb_type: I_PCM
+			s.align()
+			for y := 0; y < 16; y++ {
+				row := (my*16+y)*e.w + mx*16
+				s.buf = append(s.buf, pic[row:row+16]...)
+			}
+			for _, plane := range [][]byte{cb, cr} {
+				for y := 0; y < 8; y++ {
+					row := (my*8+y)*cw + mx*8
+					s.buf = append(s.buf, plane[row:row+8]...)
+				}
+			}
+		}
+	}
+	s.trailing()
+
+	e.idrID++
+	e.frameNum = 1
+	return [][]byte{e.sps, e.pps, nalu(3, 5, s.buf)}
+}
+
+// repeat encodes a P slice that skips every macroblock, showing the previous picture again.
+func (e *encoder) repeat() [][]byte {
+	var s bitWriter
+	s.ue(0) // first_mb_in_slice
+	s.ue(5) // slice_type: P, all slices
+	s.ue(0) // pic_parameter_set_id
+	s.u(log2MaxFrameNum, e.frameNum%(1<<log2MaxFrameNum))
+	s.u(1, 0) // num_ref_idx_active_override_flag
+	s.u(1, 0) // ref_pic_list_modification_flag_l0
+	s.u(1, 0) // adaptive_ref_pic_marking_mode_flag
+	s.se(0)   // slice_qp_delta
+	s.ue(uint32(e.w / 16 * e.h / 16))
+	s.trailing()
+
+	e.frameNum++
+	return [][]byte{nalu(2, 1, s.buf)}
+}
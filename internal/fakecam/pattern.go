@@ -0,0 +1,38 @@
+package fakecam
+
+// bars are the SMPTE colour bars as limited-range Y, Cb, Cr: white, yellow, cyan, green, magenta,
+// red, blue, black. Samples stay within 16-235 so a raw I_PCM slice never contains a start code.
+var bars = [][3]byte{
+	{180, 128, 128},
+	{168, 44, 136},
+	{145, 147, 44},
+	{133, 63, 52},
+	{63, 193, 204},
+	{51, 109, 212},
+	{28, 212, 120},
+	{16, 128, 128},
+}
+
+// colorBars renders the bars as a planar 4:2:0 picture, rotated right by shift pixels. The idle
+// stream shows shift 0; motion bursts move the bars so every frame differs from the last.
+func colorBars(w, h, shift int) []byte {
+	pic := make([]byte, w*h*3/2)
+	cw, ch := w/2, h/2
+	cb, cr := pic[w*h:w*h+cw*ch], pic[w*h+cw*ch:]
+	bar := func(x int) [3]byte {
+		x = ((x-shift)%w + w) % w
+		return bars[x*len(bars)/w]
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			pic[y*w+x] = bar(x)[0]
+		}
+	}
+	for y := 0; y < ch; y++ {
+		for x := 0; x < cw; x++ {
+			c := bar(2 * x)
+			cb[y*cw+x], cr[y*cw+x] = c[1], c[2]
+		}
+	}
+	return pic
+}
@@ -0,0 +1,497 @@
+// Package fakecam serves a synthetic H264 camera over RTSP, with the failure modes of real ones
+// (auth, transport restrictions, session limits, dropped connections) on demand.
+package fakecam
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bluenviron/gortsplib/v4"
+	"github.com/bluenviron/gortsplib/v4/pkg/auth"
+	"github.com/bluenviron/gortsplib/v4/pkg/base"
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
+	"github.com/bluenviron/gortsplib/v4/pkg/format"
+	"github.com/bluenviron/gortsplib/v4/pkg/liberrors"
+	"github.com/steipete/camsnap/internal/hostport"
+)
+
+// Config describes the camera to fake. Zero values give an open 320x240 10fps stream on /stream1.
+type Config struct {
+	Addr      string // RTSP listen address; port 0 picks a free one
+	UDPPort   int    // RTP port for UDP (RTCP is the next one); 0 picks a free pair
+	Path      string
+	Username  string // empty disables auth
+	Password  string
+	Auth      string // basic|digest|any
+	Transport string // tcp|udp|any
+
+	Width  int
+	Height int
+	FPS    int
+	GOP    int    // frames per keyframe
+	File   string // Annex-B H264 file to loop instead of the test pattern
+
+	MaxSessions int           // SETUPs beyond this get 453 Not Enough Bandwidth; 0 = unlimited
+	DropAfter   time.Duration // close every session this long after PLAY
+	MotionEvery time.Duration // start a motion burst this often
+	MotionFor   time.Duration // length of a burst
+
+	Logf func(format string, args ...any) // session events; nil discards them
+}
+
+// Server is a running fake camera.
+type Server struct {
+	cfg    Config
+	server *gortsplib.Server
+	stream *gortsplib.ServerStream
+	source frameSource
+	addr   net.Addr
+
+	mu       sync.Mutex
+	sessions map[*gortsplib.ServerSession]struct{}
+	done     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// Start validates cfg and starts serving.
+func Start(cfg Config) (*Server, error) {
+	if err := applyDefaults(&cfg); err != nil {
+		return nil, err
+	}
+	s := &Server{cfg: cfg, sessions: map[*gortsplib.ServerSession]struct{}{}, done: make(chan struct{})}
+
+	var err error
+	if cfg.File != "" {
+		s.source, err = newFileSource(cfg.File)
+	} else {
+		s.source = newPatternSource(cfg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.server = &gortsplib.Server{
+		Handler:     s,
+		RTSPAddress: cfg.Addr,
+		Listen: func(network, address string) (net.Listener, error) {
+			ln, err := net.Listen(network, address)
+			if err == nil {
+				s.addr = ln.Addr()
+			}
+			return ln, err
+		},
+	}
+	switch cfg.Auth {
+	case "basic":
+		s.server.AuthMethods = []auth.VerifyMethod{auth.VerifyMethodBasic}
+	case "digest":
+		s.server.AuthMethods = []auth.VerifyMethod{auth.VerifyMethodDigestMD5}
+	}
+	if cfg.Transport != "tcp" {
+		host, _, err := net.SplitHostPort(cfg.Addr)
+		if err != nil {
+			return nil, fmt.Errorf("listen address %q: %w", cfg.Addr, err)
+		}
+		rtpPort := cfg.UDPPort
+		if rtpPort == 0 {
+			if rtpPort, err = freeUDPPair(host); err != nil {
+				return nil, err
+			}
+		}
+		s.server.UDPRTPAddress = hostport.Join(host, rtpPort)
+		s.server.UDPRTCPAddress = hostport.Join(host, rtpPort+1)
+	}
+	if err := s.server.Start(); err != nil {
+		return nil, fmt.Errorf("start RTSP server: %w", err)
+	}
+
+	sps, pps := s.source.params()
+	s.stream = &gortsplib.ServerStream{
+		Server: s.server,
+		Desc: &description.Session{
+			Title: "camsnap fakecam",
+			Medias: []*description.Media{{
+				Type:    description.MediaTypeVideo,
+				Formats: []format.Format{&format.H264{PayloadTyp: 96, PacketizationMode: 1, SPS: sps, PPS: pps}},
+			}},
+		},
+	}
+	if err := s.stream.Initialize(); err != nil {
+		s.server.Close()
+		return nil, fmt.Errorf("init stream: %w", err)
+	}
+
+	s.wg.Add(1)
+	go s.run()
+	return s, nil
+}
+
+func applyDefaults(cfg *Config) error {
+	if cfg.Addr == "" {
+		cfg.Addr = "127.0.0.1:0"
+	}
+	if cfg.Path == "" {
+		cfg.Path = "/stream1"
+	}
+	if !strings.HasPrefix(cfg.Path, "/") {
+		cfg.Path = "/" + cfg.Path
+	}
+	cfg.Auth = strings.ToLower(cfg.Auth)
+	if cfg.Auth == "" {
+		cfg.Auth = "any"
+	}
+	if cfg.Auth != "basic" && cfg.Auth != "digest" && cfg.Auth != "any" {
+		return fmt.Errorf("invalid auth %q (use basic|digest|any)", cfg.Auth)
+	}
+	cfg.Transport = strings.ToLower(cfg.Transport)
+	if cfg.Transport == "" {
+		cfg.Transport = "any"
+	}
+	if cfg.Transport != "tcp" && cfg.Transport != "udp" && cfg.Transport != "any" {
+		return fmt.Errorf("invalid transport %q (use tcp|udp|any)", cfg.Transport)
+	}
+	if cfg.Width == 0 && cfg.Height == 0 {
+		cfg.Width, cfg.Height = 320, 240
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width%16 != 0 || cfg.Height%16 != 0 {
+		return fmt.Errorf("invalid size %dx%d (width and height must be positive multiples of 16)", cfg.Width, cfg.Height)
+	}
+	if cfg.FPS <= 0 {
+		cfg.FPS = 10
+	}
+	if cfg.GOP <= 0 {
+		cfg.GOP = cfg.FPS
+	}
+	if cfg.MotionEvery > 0 && cfg.MotionFor <= 0 {
+		cfg.MotionFor = time.Second
+	}
+	return nil
+}
+
+// freeUDPPair finds an even port whose successor is also free, as gortsplib needs for RTP/RTCP.
+func freeUDPPair(host string) (int, error) {
+	for i := 0; i < 50; i++ {
+		rtp, err := net.ListenPacket("udp", hostport.Join(host, 0))
+		if err != nil {
+			return 0, fmt.Errorf("find UDP port: %w", err)
+		}
+		port := rtp.LocalAddr().(*net.UDPAddr).Port
+		_ = rtp.Close()
+		if port%2 == 1 || port >= 65535 {
+			continue
+		}
+		rtp, err1 := net.ListenPacket("udp", hostport.Join(host, port))
+		rtcp, err2 := net.ListenPacket("udp", hostport.Join(host, port+1))
+		if rtp != nil {
+			_ = rtp.Close()
+		}
+		if rtcp != nil {
+			_ = rtcp.Close()
+		}
+		if err1 == nil && err2 == nil {
+			return port, nil
+		}
+	}
+	return 0, fmt.Errorf("no free UDP port pair")
+}
+
+// Port is the RTSP port the server listens on.
+func (s *Server) Port() int {
+	if a, ok := s.addr.(*net.TCPAddr); ok {
+		return a.Port
+	}
+	return 0
+}
+
+// URL is the stream URL including the credentials.
+func (s *Server) URL() string {
+	host, _, _ := net.SplitHostPort(s.cfg.Addr)
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+	u := url.URL{Scheme: "rtsp", Host: hostport.Join(host, s.Port()), Path: s.cfg.Path}
+	if s.cfg.Username != "" {
+		u.User = url.UserPassword(s.cfg.Username, s.cfg.Password)
+	}
+	return u.String()
+}
+
+// Sessions is the number of open RTSP sessions that have SETUP the stream.
+func (s *Server) Sessions() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.sessions)
+}
+
+// Close stops streaming and disconnects every client.
+func (s *Server) Close() {
+	close(s.done)
+	s.wg.Wait()
+	s.stream.Close()
+	s.server.Close()
+}
+
+func (s *Server) logf(format string, args ...any) {
+	if s.cfg.Logf != nil {
+		s.cfg.Logf(format, args...)
+	}
+}
+
+// run writes one access unit per frame interval until Close.
+func (s *Server) run() {
+	defer s.wg.Done()
+	medi := s.stream.Desc.Medias[0]
+	enc, err := medi.Formats[0].(*format.H264).CreateEncoder()
+	if err != nil {
+		s.logf("rtp encoder: %v", err)
+		return
+	}
+	interval := time.Second / time.Duration(s.cfg.FPS)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	start := time.Now()
+	for n := 0; ; n++ {
+		elapsed := time.Since(start)
+		au := s.source.next(n, s.inMotion(elapsed))
+		pkts, err := enc.Encode(au)
+		if err != nil {
+			s.logf("rtp encode: %v", err)
+			return
+		}
+		ts := uint32(int64(n) * 90000 / int64(s.cfg.FPS))
+		for _, pkt := range pkts {
+			pkt.Timestamp += ts
+			_ = s.stream.WritePacketRTP(medi, pkt)
+		}
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// inMotion reports whether a motion burst is running elapsed into the stream.
+func (s *Server) inMotion(elapsed time.Duration) bool {
+	return s.cfg.MotionEvery > 0 && elapsed >= s.cfg.MotionEvery && elapsed%s.cfg.MotionEvery < s.cfg.MotionFor
+}
+
+// authorize checks credentials and the path of a DESCRIBE or SETUP.
+func (s *Server) authorize(conn *gortsplib.ServerConn, req *base.Request, path string) (*base.Response, error) {
+	if s.cfg.Username != "" && !conn.VerifyCredentials(req, s.cfg.Username, s.cfg.Password) {
+		return &base.Response{StatusCode: base.StatusUnauthorized}, liberrors.ErrServerAuth{}
+	}
+	if path != s.cfg.Path {
+		return &base.Response{StatusCode: base.StatusNotFound}, fmt.Errorf("no stream at %s", path)
+	}
+	return nil, nil
+}
+
+// OnConnOpen implements gortsplib.ServerHandlerOnConnOpen.
+func (s *Server) OnConnOpen(ctx *gortsplib.ServerHandlerOnConnOpenCtx) {
+	s.logf("connection from %s", ctx.Conn.NetConn().RemoteAddr())
+}
+
+// OnDescribe implements gortsplib.ServerHandlerOnDescribe.
+func (s *Server) OnDescribe(ctx *gortsplib.ServerHandlerOnDescribeCtx) (*base.Response, *gortsplib.ServerStream, error) {
+	if res, err := s.authorize(ctx.Conn, ctx.Request, ctx.Path); res != nil {
+		return res, nil, err
+	}
+	return &base.Response{StatusCode: base.StatusOK}, s.stream, nil
+}
+
+// OnSetup implements gortsplib.ServerHandlerOnSetup.
+func (s *Server) OnSetup(ctx *gortsplib.ServerHandlerOnSetupCtx) (*base.Response, *gortsplib.ServerStream, error) {
+	if res, err := s.authorize(ctx.Conn, ctx.Request, ctx.Path); res != nil {
+		return res, nil, err
+	}
+	transport := "udp"
+	if ctx.Transport == gortsplib.TransportTCP {
+		transport = "tcp"
+	}
+	if s.cfg.Transport != "any" && transport != s.cfg.Transport {
+		s.logf("rejected %s SETUP (only %s)", transport, s.cfg.Transport)
+		return &base.Response{StatusCode: base.StatusUnsupportedTransport}, nil, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.sessions[ctx.Session]; !ok && s.cfg.MaxSessions > 0 && len(s.sessions) >= s.cfg.MaxSessions {
+		s.logf("rejected SETUP: %d of %d sessions in use", len(s.sessions), s.cfg.MaxSessions)
+		return &base.Response{StatusCode: base.StatusNotEnoughBandwidth}, nil, nil
+	}
+	s.sessions[ctx.Session] = struct{}{}
+	s.logf("session %s over %s", ctx.Path, transport)
+	return &base.Response{StatusCode: base.StatusOK}, s.stream, nil
+}
+
+// OnPlay implements gortsplib.ServerHandlerOnPlay.
+func (s *Server) OnPlay(ctx *gortsplib.ServerHandlerOnPlayCtx) (*base.Response, error) {
+	if d := s.cfg.DropAfter; d > 0 {
+		session := ctx.Session
+		time.AfterFunc(d, func() {
+			s.logf("dropping session after %s", d)
+			session.Close()
+		})
+	}
+	return &base.Response{StatusCode: base.StatusOK}, nil
+}
+
+// OnSessionClose implements gortsplib.ServerHandlerOnSessionClose.
+func (s *Server) OnSessionClose(ctx *gortsplib.ServerHandlerOnSessionCloseCtx) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, ctx.Session)
+}
+
+// frameSource yields the access unit for frame n.
+type frameSource interface {
+	params() (sps, pps []byte)
+	next(n int, motion bool) [][]byte
+}
+
+// patternSource encodes colour bars: a keyframe every GOP frames and skipped frames in between;
+// during motion bursts every frame is a keyframe with the bars moved by one bar width.
+type patternSource struct {
+	enc      *encoder
+	gop      int
+	still    []byte
+	shift    int
+	inMotion bool
+}
+
+func newPatternSource(cfg Config) *patternSource {
+	return &patternSource{enc: newEncoder(cfg.Width, cfg.Height), gop: cfg.GOP, still: colorBars(cfg.Width, cfg.Height, 0)}
+}
+
+func (p *patternSource) params() ([]byte, []byte) { return p.enc.sps, p.enc.pps }
+
+func (p *patternSource) next(n int, motion bool) [][]byte {
+	switch {
+	case motion:
+		p.inMotion = true
+		p.shift += p.enc.w / len(bars)
+		return p.enc.keyframe(colorBars(p.enc.w, p.enc.h, p.shift))
+	case p.inMotion, n%p.gop == 0:
+		p.inMotion = false
+		return p.enc.keyframe(p.still)
+	}
+	return p.enc.repeat()
+}
+
+// fileSource loops the access units of an Annex-B file.
+type fileSource struct {
+	aus      [][][]byte
+	sps, pps []byte
+}
+
+func newFileSource(path string) (*fileSource, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read sample: %w", err)
+	}
+	f := &fileSource{}
+	for _, au := range splitAccessUnits(data) {
+		for _, n := range au {
+			switch n[0] & 0x1f {
+			case 7:
+				if f.sps == nil {
+					f.sps = n
+				}
+			case 8:
+				if f.pps == nil {
+					f.pps = n
+				}
+			}
+		}
+		f.aus = append(f.aus, au)
+	}
+	if f.sps == nil || f.pps == nil {
+		return nil, fmt.Errorf("%s: not an Annex-B H264 stream with SPS and PPS", path)
+	}
+	return f, nil
+}
+
+func (f *fileSource) params() ([]byte, []byte) { return f.sps, f.pps }
+
+func (f *fileSource) next(n int, _ bool) [][]byte { return f.aus[n%len(f.aus)] }
+
+// splitAccessUnits cuts an Annex-B stream into NAL units and groups them into access units: a new
+// one starts at an access unit delimiter, SPS, or a slice whose first_mb_in_slice is 0.
+func splitAccessUnits(data []byte) [][][]byte {
+	var nalus [][]byte
+	start := -1
+	for i := 0; i+2 < len(data); i++ {
+		if data[i] != 0 || data[i+1] != 0 || data[i+2] != 1 {
+			continue
+		}
+		if start >= 0 {
+			nalus = append(nalus, trimZeros(data[start:i]))
+		}
+		start = i + 3
+		i += 2
+	}
+	if start >= 0 && start < len(data) {
+		nalus = append(nalus, data[start:])
+	}
+
+	var aus [][][]byte
+	var cur [][]byte
+	hasSlice := false
+	for _, n := range nalus {
+		if len(n) == 0 {
+			continue
+		}
+		typ := n[0] & 0x1f
+		firstSlice := (typ == 1 || typ == 5) && len(n) > 1 && n[1]&0x80 != 0
+		if len(cur) > 0 && hasSlice && (typ == 9 || typ == 7 || firstSlice) {
+			aus = append(aus, cur)
+			cur, hasSlice = nil, false
+		}
+		if typ == 9 {
+			continue
+		}
+		cur = append(cur, n)
+		hasSlice = hasSlice || typ == 1 || typ == 5
+	}
+	if hasSlice {
+		aus = append(aus, cur)
+	}
+	return aus
+}
+
+// trimZeros drops the trailing zero of a 4-byte start code (and any trailing_zero_8bits).
+func trimZeros(n []byte) []byte {
+	for len(n) > 0 && n[len(n)-1] == 0 {
+		n = n[:len(n)-1]
+	}
+	return n
+}
+
+// Describe summarizes the configuration for the startup banner.
+func (s *Server) Describe() string {
+	parts := []string{fmt.Sprintf("%dx%d@%dfps gop %d", s.cfg.Width, s.cfg.Height, s.cfg.FPS, s.cfg.GOP)}
+	if s.cfg.File != "" {
+		parts = []string{s.cfg.File + " @" + strconv.Itoa(s.cfg.FPS) + "fps"}
+	}
+	if s.cfg.Username != "" {
+		parts = append(parts, s.cfg.Auth+" auth")
+	}
+	parts = append(parts, "transport "+s.cfg.Transport)
+	if s.cfg.MaxSessions > 0 {
+		parts = append(parts, fmt.Sprintf("max %d sessions", s.cfg.MaxSessions))
+	}
+	if s.cfg.DropAfter > 0 {
+		parts = append(parts, "drop after "+s.cfg.DropAfter.String())
+	}
+	if s.cfg.MotionEvery > 0 {
+		parts = append(parts, fmt.Sprintf("motion %s every %s", s.cfg.MotionFor, s.cfg.MotionEvery))
+	}
+	return strings.Join(parts, ", ")
+}
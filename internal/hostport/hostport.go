@@ -0,0 +1,87 @@
+// Package hostport parses and joins camera host strings, including IPv6 literals and zone IDs.
+package hostport
+
+import (
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Split separates an optional port from host. It accepts "host", "host:port",
+// "[v6]", "[v6]:port" and bare IPv6 literals such as "fe80::1%eth0".
+// The port is 0 when none is present or it is not numeric.
+func Split(host string) (string, int) {
+	host = strings.TrimSpace(host)
+	if strings.HasPrefix(host, "[") {
+		end := strings.IndexByte(host, ']')
+		if end < 0 {
+			return host, 0
+		}
+		h := host[1:end]
+		rest := host[end+1:]
+		if strings.HasPrefix(rest, ":") {
+			return h, parsePort(rest[1:])
+		}
+		return h, 0
+	}
+	// more than one colon means a bare IPv6 literal without port
+	if strings.Count(host, ":") == 1 {
+		i := strings.IndexByte(host, ':')
+		if p := parsePort(host[i+1:]); p != 0 {
+			return host[:i], p
+		}
+	}
+	return host, 0
+}
+
+// Join returns host:port for dialing, bracketing IPv6 literals. A port embedded in host wins.
+func Join(host string, port int) string {
+	h, embedded := Split(host)
+	if embedded != 0 {
+		port = embedded
+	}
+	return net.JoinHostPort(h, strconv.Itoa(port))
+}
+
+// URLHost returns the authority host:port for a URL. IPv6 zones are escaped as %25 (RFC 6874).
+func URLHost(host string, port int) string {
+	h, embedded := Split(host)
+	if embedded != 0 {
+		port = embedded
+	}
+	if strings.Contains(h, ":") {
+		h = "[" + strings.Replace(h, "%", "%25", 1) + "]"
+	}
+	if port == 0 {
+		return h
+	}
+	return h + ":" + strconv.Itoa(port)
+}
+
+// SameHost reports whether two host strings name the same host, ignoring ports and brackets.
+func SameHost(a, b string) bool {
+	ha, _ := Split(a)
+	hb, _ := Split(b)
+	if ha == "" || hb == "" {
+		return false
+	}
+	if ipa, ipb := net.ParseIP(stripZone(ha)), net.ParseIP(stripZone(hb)); ipa != nil && ipb != nil {
+		return ipa.Equal(ipb)
+	}
+	return strings.EqualFold(ha, hb)
+}
+
+func stripZone(h string) string {
+	if i := strings.IndexByte(h, '%'); i >= 0 {
+		return h[:i]
+	}
+	return h
+}
+
+func parsePort(s string) int {
+	p, err := strconv.Atoi(s)
+	if err != nil || p <= 0 || p > 65535 {
+		return 0
+	}
+	return p
+}
@@ -0,0 +1,56 @@
+package hostport
+
+import "testing"
+
+func TestSplit(t *testing.T) {
+	cases := []struct {
+		in   string
+		host string
+		port int
+	}{
+		{"192.168.1.5", "192.168.1.5", 0},
+		{"192.168.1.5:8554", "192.168.1.5", 8554},
+		{"cam.local:554", "cam.local", 554},
+		{"fe80::1", "fe80::1", 0},
+		{"fe80::1%eth0", "fe80::1%eth0", 0},
+		{"2001:db8::10", "2001:db8::10", 0},
+		{"[2001:db8::10]", "2001:db8::10", 0},
+		{"[fe80::1%eth0]:554", "fe80::1%eth0", 554},
+	}
+	for _, c := range cases {
+		h, p := Split(c.in)
+		if h != c.host || p != c.port {
+			t.Fatalf("Split(%q) got (%q,%d) want (%q,%d)", c.in, h, p, c.host, c.port)
+		}
+	}
+}
+
+func TestJoinAndURLHost(t *testing.T) {
+	if got := Join("fe80::1%eth0", 554); got != "[fe80::1%eth0]:554" {
+		t.Fatalf("Join zone: %s", got)
+	}
+	if got := Join("10.0.0.1:8554", 554); got != "10.0.0.1:8554" {
+		t.Fatalf("Join embedded port: %s", got)
+	}
+	if got := URLHost("fe80::1%eth0", 554); got != "[fe80::1%25eth0]:554" {
+		t.Fatalf("URLHost zone: %s", got)
+	}
+	if got := URLHost("10.0.0.1", 554); got != "10.0.0.1:554" {
+		t.Fatalf("URLHost v4: %s", got)
+	}
+}
+
+func TestSameHost(t *testing.T) {
+	if !SameHost("2001:db8::10", "[2001:db8:0::10]:2020") {
+		t.Fatalf("expected equal IPv6 hosts")
+	}
+	if !SameHost("192.168.1.5", "192.168.1.5:2020") {
+		t.Fatalf("expected equal IPv4 hosts")
+	}
+	if SameHost("fe80::1", "fe80::1:2") {
+		t.Fatalf("prefix must not match")
+	}
+	if SameHost("192.168.1.5", "192.168.1.50:2020") {
+		t.Fatalf("prefix must not match")
+	}
+}
@@ -0,0 +1,362 @@
+// Package httpcam fetches JPEG snapshots and MJPEG streams from cameras over HTTP(S).
+package httpcam
+
+import (
+	"context"
+	"crypto/md5" //nolint:gosec
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/steipete/camsnap/internal/camerr"
+	"github.com/steipete/camsnap/internal/config"
+	"github.com/steipete/camsnap/internal/hostport"
+	"github.com/steipete/camsnap/internal/presets"
+)
+
+// Source types stored in config.Camera.Source.
+const (
+	SourceRTSP     = "rtsp"
+	SourceSnapshot = "snapshot"
+	SourceMJPEG    = "mjpeg"
+)
+
+// maxSnapshotBytes caps a single JPEG so a misbehaving endpoint cannot exhaust memory.
+const maxSnapshotBytes = 32 << 20
+
+// IsHTTPSource reports whether a camera is read over HTTP instead of RTSP.
+func IsHTTPSource(cam config.Camera) bool {
+	return cam.Source == SourceSnapshot || cam.Source == SourceMJPEG
+}
+
+// ParseSource validates a source type; empty means rtsp.
+func ParseSource(v string) (string, bool) {
+	switch strings.ToLower(v) {
+	case "", SourceRTSP:
+		return "", true
+	case SourceSnapshot, "jpeg":
+		return SourceSnapshot, true
+	case SourceMJPEG:
+		return SourceMJPEG, true
+	default:
+		return "", false
+	}
+}
+
+// BuildURL returns the HTTP URL for a snapshot or MJPEG camera. Credentials are not embedded;
+// they are sent via Basic or Digest auth by Fetch and Stream.
+func BuildURL(cam config.Camera) (string, error) {
+	if cam.Host == "" {
+		return "", fmt.Errorf("host is required")
+	}
+	proto := strings.ToLower(cam.Protocol)
+	switch proto {
+	case "", "rtsp":
+		proto = "http"
+	case "rtsps":
+		proto = "https"
+	case "http", "https":
+	default:
+		return "", fmt.Errorf("unsupported protocol %q for %s source", cam.Protocol, cam.Source)
+	}
+
+	var preset presets.Preset
+	if cam.Vendor != "" {
+		p, ok := presets.Lookup(cam.Vendor)
+		if !ok {
+			return "", fmt.Errorf("unknown vendor %q", cam.Vendor)
+		}
+		preset = p
+	}
+
+	port := cam.Port
+	if port == 0 {
+		port = preset.SnapshotPort
+	}
+	if port == 0 {
+		port = 80
+		if proto == "https" {
+			port = 443
+		}
+	}
+
+	path := cam.Path
+	if path == "" && cam.Source == SourceSnapshot {
+		path = preset.SnapshotRequestPath(cam.Channel)
+	}
+	if path == "" {
+		return "", fmt.Errorf("path is required for %s source (e.g., /cgi-bin/snapshot.cgi)", cam.Source)
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	if cam.Query != "" {
+		sep := "?"
+		if strings.Contains(path, "?") {
+			sep = "&"
+		}
+		path += sep + cam.Query
+	}
+	return proto + "://" + hostport.URLHost(cam.Host, port) + path, nil
+}
+
+// ParseURL splits an http(s) snapshot or MJPEG URL into camera fields (Source defaults to snapshot).
+func ParseURL(raw string) (config.Camera, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return config.Camera{}, fmt.Errorf("parse url: %w", err)
+	}
+	proto := strings.ToLower(u.Scheme)
+	if proto != "http" && proto != "https" {
+		return config.Camera{}, fmt.Errorf("unsupported protocol %q", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return config.Camera{}, fmt.Errorf("url %q has no host", raw)
+	}
+	cam := config.Camera{
+		Host:     u.Hostname(),
+		Protocol: proto,
+		Source:   SourceSnapshot,
+		Path:     u.EscapedPath(),
+		Query:    u.RawQuery,
+	}
+	if p := u.Port(); p != "" {
+		port, err := strconv.Atoi(p)
+		if err != nil || port <= 0 || port > 65535 {
+			return config.Camera{}, fmt.Errorf("invalid port %q", p)
+		}
+		cam.Port = port
+	}
+	if u.User != nil {
+		cam.Username = u.User.Username()
+		cam.Password, _ = u.User.Password()
+	}
+	return cam, nil
+}
+
+// DialAddr returns the host:port of an HTTP URL built by BuildURL.
+func DialAddr(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+// Client performs authenticated camera requests.
+type Client struct {
+	HTTP     *http.Client
+	Username string
+	Password string
+}
+
+// NewClient returns a client with a request timeout.
+func NewClient(user, pass string, timeout time.Duration) *Client {
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &Client{HTTP: &http.Client{Timeout: timeout}, Username: user, Password: pass}
+}
+
+// FetchSnapshot downloads a single JPEG.
+func (c *Client) FetchSnapshot(ctx context.Context, rawURL string) ([]byte, error) {
+	resp, err := c.Get(ctx, rawURL)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	ct := resp.Header.Get("Content-Type")
+	if strings.HasPrefix(ct, "multipart/") {
+		// an MJPEG endpoint configured as snapshot: take the first frame
+		var frame []byte
+		err := readMultipart(resp, func(b []byte) error {
+			frame = b
+			return errStop
+		})
+		if err != nil {
+			return nil, err
+		}
+		return frame, nil
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSnapshotBytes))
+	if err != nil {
+		return nil, fmt.Errorf("read snapshot: %w", err)
+	}
+	if len(data) < 2 || data[0] != 0xff || data[1] != 0xd8 {
+		return nil, fmt.Errorf("snapshot is not a JPEG (content-type %q)", ct)
+	}
+	return data, nil
+}
+
+// Get issues a GET, answering a Basic or Digest challenge if the camera sends one.
+func (c *Client) Get(ctx context.Context, rawURL string) (*http.Response, error) {
+	resp, err := c.do(ctx, rawURL, "")
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized && c.Username != "" {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+		_ = resp.Body.Close()
+		auth, err := c.authorization(challenge, rawURL)
+		if err != nil {
+			return nil, err
+		}
+		resp, err = c.do(ctx, rawURL, auth)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("auth failed (status %d): %w", resp.StatusCode, camerr.ErrAuth)
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+		_ = resp.Body.Close()
+		err := fmt.Errorf("http status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+		return nil, camerr.Wrap(camerr.FromStatus(resp.StatusCode), err)
+	}
+	return resp, nil
+}
+
+func (c *Client) do(ctx context.Context, rawURL, auth string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	httpClient := c.HTTP
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, camerr.Wrap(camerr.FromNet(err), err)
+	}
+	return resp, nil
+}
+
+func (c *Client) authorization(challenge, rawURL string) (string, error) {
+	scheme, params := parseChallenge(challenge)
+	switch scheme {
+	case "basic", "":
+		req := &http.Request{Header: http.Header{}}
+		req.SetBasicAuth(c.Username, c.Password)
+		return req.Header.Get("Authorization"), nil
+	case "digest":
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return "", err
+		}
+		return digestAuthorization(params, c.Username, c.Password, http.MethodGet, u.RequestURI())
+	default:
+		return "", fmt.Errorf("unsupported auth scheme %q", scheme)
+	}
+}
+
+// parseChallenge splits a WWW-Authenticate header into its scheme and parameters.
+func parseChallenge(h string) (string, map[string]string) {
+	h = strings.TrimSpace(h)
+	scheme, rest, _ := strings.Cut(h, " ")
+	params := map[string]string{}
+	for rest != "" {
+		rest = strings.TrimLeft(rest, " ,")
+		key, after, ok := strings.Cut(rest, "=")
+		if !ok {
+			break
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		var val string
+		if strings.HasPrefix(after, `"`) {
+			end := strings.IndexByte(after[1:], '"')
+			if end < 0 {
+				val, rest = after[1:], ""
+			} else {
+				val, rest = after[1:end+1], after[end+2:]
+			}
+		} else {
+			val, rest, _ = strings.Cut(after, ",")
+			val = strings.TrimSpace(val)
+		}
+		params[key] = val
+	}
+	return strings.ToLower(scheme), params
+}
+
+// digestAuthorization builds an RFC 7616 Digest response (MD5, MD5-sess or SHA-256; qop auth).
+func digestAuthorization(params map[string]string, user, pass, method, uri string) (string, error) {
+	realm, nonce := params["realm"], params["nonce"]
+	if nonce == "" {
+		return "", fmt.Errorf("digest challenge without nonce")
+	}
+	algorithm := params["algorithm"]
+	var newHash func() hash.Hash
+	switch strings.ToUpper(algorithm) {
+	case "", "MD5", "MD5-SESS":
+		newHash = md5.New
+	case "SHA-256", "SHA-256-SESS":
+		newHash = sha256.New
+	default:
+		return "", fmt.Errorf("unsupported digest algorithm %q", algorithm)
+	}
+	h := func(s string) string {
+		sum := newHash()
+		sum.Write([]byte(s))
+		return hex.EncodeToString(sum.Sum(nil))
+	}
+
+	cnonceRaw := make([]byte, 8)
+	_, _ = rand.Read(cnonceRaw)
+	cnonce := hex.EncodeToString(cnonceRaw)
+	nc := "00000001"
+
+	ha1 := h(user + ":" + realm + ":" + pass)
+	if strings.HasSuffix(strings.ToUpper(algorithm), "-SESS") {
+		ha1 = h(ha1 + ":" + nonce + ":" + cnonce)
+	}
+	ha2 := h(method + ":" + uri)
+
+	qop := ""
+	for _, q := range strings.Split(params["qop"], ",") {
+		if strings.TrimSpace(q) == "auth" {
+			qop = "auth"
+		}
+	}
+	var response string
+	if qop != "" {
+		response = h(strings.Join([]string{ha1, nonce, nc, cnonce, qop, ha2}, ":"))
+	} else {
+		response = h(ha1 + ":" + nonce + ":" + ha2)
+	}
+
+	parts := []string{
+		fmt.Sprintf(`username="%s"`, user),
+		fmt.Sprintf(`realm="%s"`, realm),
+		fmt.Sprintf(`nonce="%s"`, nonce),
+		fmt.Sprintf(`uri="%s"`, uri),
+		fmt.Sprintf(`response="%s"`, response),
+	}
+	if algorithm != "" {
+		parts = append(parts, "algorithm="+algorithm)
+	}
+	if qop != "" {
+		parts = append(parts, "qop="+qop, "nc="+nc, fmt.Sprintf(`cnonce="%s"`, cnonce))
+	}
+	if opaque := params["opaque"]; opaque != "" {
+		parts = append(parts, fmt.Sprintf(`opaque="%s"`, opaque))
+	}
+	return "Digest " + strings.Join(parts, ", "), nil
+}
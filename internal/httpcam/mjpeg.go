@@ -0,0 +1,93 @@
+package httpcam
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+)
+
+// errStop ends a multipart read early without reporting an error.
+var errStop = errors.New("stop")
+
+// Stream reads an MJPEG (multipart/x-mixed-replace) endpoint and calls fn for each JPEG frame
+// until ctx is canceled, the stream ends, or fn returns an error.
+func (c *Client) Stream(ctx context.Context, rawURL string, fn func(frame []byte) error) error {
+	resp, err := c.Get(ctx, rawURL)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	err = readMultipart(resp, fn)
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// StreamTo copies MJPEG frames back-to-back into w, the format ffmpeg reads with -f mjpeg.
+func (c *Client) StreamTo(ctx context.Context, rawURL string, w io.Writer) error {
+	return c.Stream(ctx, rawURL, func(frame []byte) error {
+		_, err := w.Write(frame)
+		return err
+	})
+}
+
+func readMultipart(resp *http.Response, fn func([]byte) error) error {
+	mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") {
+		return fmt.Errorf("not an MJPEG stream (content-type %q)", resp.Header.Get("Content-Type"))
+	}
+	// Cameras disagree on whether the advertised boundary includes its leading dashes,
+	// so take the delimiter from the first boundary line in the body instead.
+	br := bufio.NewReader(resp.Body)
+	first, err := br.ReadString('\n')
+	for err == nil && strings.TrimSpace(first) == "" {
+		first, err = br.ReadString('\n')
+	}
+	if err != nil {
+		return fmt.Errorf("read MJPEG boundary: %w", err)
+	}
+	first = strings.TrimSpace(first)
+	boundary := strings.TrimPrefix(first, "--")
+	if !strings.HasPrefix(first, "--") || boundary == "" {
+		boundary = strings.TrimPrefix(params["boundary"], "--")
+	}
+	if boundary == "" {
+		return fmt.Errorf("MJPEG stream without boundary")
+	}
+
+	mr := multipart.NewReader(io.MultiReader(strings.NewReader(first+"\r\n"), br), boundary)
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read MJPEG part: %w", err)
+		}
+		data, err := io.ReadAll(io.LimitReader(part, maxSnapshotBytes))
+		if err != nil {
+			return fmt.Errorf("read MJPEG frame: %w", err)
+		}
+		data = bytes.TrimLeft(data, "\r\n")
+		if len(data) < 2 || data[0] != 0xff || data[1] != 0xd8 {
+			// skip non-JPEG parts (some cameras interleave metadata)
+			continue
+		}
+		if err := fn(data); err != nil {
+			if errors.Is(err, errStop) {
+				return nil
+			}
+			return err
+		}
+	}
+}
@@ -0,0 +1,161 @@
+// Package presets maps camera vendors to RTSP paths, ports and known quirks.
+package presets
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Preset describes the URL layout and defaults of a camera vendor.
+// Path templates accept {ch} (1-based channel), {ch2} (zero-padded) and {ch0} (0-based).
+type Preset struct {
+	Name          string
+	Manufacturers []string // lowercase substrings matched against ONVIF Manufacturer
+	Port          int
+	MainPath      string
+	SubPath       string
+	SnapshotPath  string // HTTP path for a JPEG still, empty if unsupported
+	SnapshotPort  int
+	Transport     string // preferred RTSP transport (tcp|udp)
+	AudioCodec    string // audio codec that survives mp4 muxing
+	Notes         string
+}
+
+var registry = []Preset{
+	{
+		Name:          "tapo",
+		Manufacturers: []string{"tp-link", "tapo"},
+		Port:          554,
+		MainPath:      "/stream1",
+		SubPath:       "/stream2",
+		Transport:     "udp",
+		AudioCodec:    "aac",
+		Notes:         "enable Third-Party NVR/RTSP and create a camera account; PCMA audio needs transcoding for mp4",
+	},
+	{
+		Name:          "hikvision",
+		Manufacturers: []string{"hikvision"},
+		Port:          554,
+		MainPath:      "/Streaming/Channels/{ch}01",
+		SubPath:       "/Streaming/Channels/{ch}02",
+		SnapshotPath:  "/ISAPI/Streaming/channels/{ch}01/picture",
+		SnapshotPort:  80,
+		Transport:     "tcp",
+		AudioCodec:    "aac",
+		Notes:         "G.711 audio by default; snapshots need digest auth on recent firmware",
+	},
+	{
+		Name:          "dahua",
+		Manufacturers: []string{"dahua"},
+		Port:          554,
+		MainPath:      "/cam/realmonitor?channel={ch}&subtype=0",
+		SubPath:       "/cam/realmonitor?channel={ch}&subtype=1",
+		SnapshotPath:  "/cgi-bin/snapshot.cgi?channel={ch}",
+		SnapshotPort:  80,
+		Transport:     "tcp",
+		AudioCodec:    "aac",
+		Notes:         "uses digest auth for RTSP and HTTP",
+	},
+	{
+		Name:          "amcrest",
+		Manufacturers: []string{"amcrest"},
+		Port:          554,
+		MainPath:      "/cam/realmonitor?channel={ch}&subtype=0",
+		SubPath:       "/cam/realmonitor?channel={ch}&subtype=1",
+		SnapshotPath:  "/cgi-bin/snapshot.cgi?channel={ch}",
+		SnapshotPort:  80,
+		Transport:     "tcp",
+		AudioCodec:    "aac",
+		Notes:         "Dahua OEM; same URL layout",
+	},
+	{
+		Name:          "reolink",
+		Manufacturers: []string{"reolink"},
+		Port:          554,
+		MainPath:      "/h264Preview_{ch2}_main",
+		SubPath:       "/h264Preview_{ch2}_sub",
+		SnapshotPath:  "/cgi-bin/api.cgi?cmd=Snap&channel={ch0}&rs=camsnap",
+		SnapshotPort:  80,
+		Transport:     "tcp",
+		AudioCodec:    "aac",
+		Notes:         "H.265 models serve /h265Preview_01_main; UDP drops frames on most firmware",
+	},
+	{
+		Name:          "axis",
+		Manufacturers: []string{"axis"},
+		Port:          554,
+		MainPath:      "/axis-media/media.amp?camera={ch}",
+		SubPath:       "/axis-media/media.amp?camera={ch}&resolution=640x360",
+		SnapshotPath:  "/axis-cgi/jpg/image.cgi?camera={ch}",
+		SnapshotPort:  80,
+		Transport:     "tcp",
+		AudioCodec:    "aac",
+		Notes:         "RTSP requires digest auth unless anonymous viewing is enabled",
+	},
+}
+
+// Lookup returns the preset with the given name (case-insensitive).
+func Lookup(name string) (Preset, bool) {
+	n := strings.ToLower(strings.TrimSpace(name))
+	for _, p := range registry {
+		if p.Name == n {
+			return p, true
+		}
+	}
+	return Preset{}, false
+}
+
+// Names lists all preset names in alphabetical order.
+func Names() []string {
+	names := make([]string, 0, len(registry))
+	for _, p := range registry {
+		names = append(names, p.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ForManufacturer picks a preset from an ONVIF Manufacturer string.
+func ForManufacturer(manufacturer string) (Preset, bool) {
+	m := strings.ToLower(manufacturer)
+	if m == "" {
+		return Preset{}, false
+	}
+	for _, p := range registry {
+		for _, alias := range p.Manufacturers {
+			if strings.Contains(m, alias) {
+				return p, true
+			}
+		}
+	}
+	return Preset{}, false
+}
+
+// StreamPath renders the RTSP path for a channel (1-based; 0 means 1).
+func (p Preset) StreamPath(channel int, substream bool) string {
+	tmpl := p.MainPath
+	if substream {
+		tmpl = p.SubPath
+	}
+	return render(tmpl, channel)
+}
+
+// SnapshotRequestPath renders the HTTP snapshot path (with query) for a channel, or "" if unsupported.
+func (p Preset) SnapshotRequestPath(channel int) string {
+	if p.SnapshotPath == "" {
+		return ""
+	}
+	return render(p.SnapshotPath, channel)
+}
+
+func render(tmpl string, channel int) string {
+	if channel <= 0 {
+		channel = 1
+	}
+	return strings.NewReplacer(
+		"{ch0}", fmt.Sprintf("%d", channel-1),
+		"{ch2}", fmt.Sprintf("%02d", channel),
+		"{ch}", fmt.Sprintf("%d", channel),
+	).Replace(tmpl)
+}
@@ -0,0 +1,75 @@
+package presets
+
+import "testing"
+
+func TestStreamPath(t *testing.T) {
+	cases := []struct {
+		vendor    string
+		channel   int
+		substream bool
+		want      string
+	}{
+		{"hikvision", 1, false, "/Streaming/Channels/101"},
+		{"hikvision", 2, true, "/Streaming/Channels/202"},
+		{"dahua", 0, true, "/cam/realmonitor?channel=1&subtype=1"},
+		{"reolink", 3, false, "/h264Preview_03_main"},
+		{"axis", 1, false, "/axis-media/media.amp?camera=1"},
+		{"tapo", 1, true, "/stream2"},
+	}
+	for _, c := range cases {
+		p, ok := Lookup(c.vendor)
+		if !ok {
+			t.Fatalf("preset %s missing", c.vendor)
+		}
+		if got := p.StreamPath(c.channel, c.substream); got != c.want {
+			t.Fatalf("%s ch=%d sub=%v: got %s want %s", c.vendor, c.channel, c.substream, got, c.want)
+		}
+	}
+}
+
+func TestSnapshotRequestPath(t *testing.T) {
+	p, _ := Lookup("reolink")
+	got := p.SnapshotRequestPath(1)
+	want := "/cgi-bin/api.cgi?cmd=Snap&channel=0&rs=camsnap"
+	if got != want {
+		t.Fatalf("got %s want %s", got, want)
+	}
+	tapo, _ := Lookup("tapo")
+	if got := tapo.SnapshotRequestPath(1); got != "" {
+		t.Fatalf("expected no tapo snapshot path, got %s", got)
+	}
+}
+
+func TestForManufacturer(t *testing.T) {
+	cases := map[string]string{
+		"HIKVISION":                      "hikvision",
+		"Dahua Technology":               "dahua",
+		"TP-Link":                        "tapo",
+		"AXIS":                           "axis",
+		"Reolink":                        "reolink",
+		"Amcrest Technologies":           "amcrest",
+		"":                               "",
+		"Some White Label Camera Vendor": "",
+	}
+	for in, want := range cases {
+		p, ok := ForManufacturer(in)
+		if want == "" {
+			if ok {
+				t.Fatalf("ForManufacturer(%q) expected no match, got %s", in, p.Name)
+			}
+			continue
+		}
+		if !ok || p.Name != want {
+			t.Fatalf("ForManufacturer(%q) got %s want %s", in, p.Name, want)
+		}
+	}
+}
+
+func TestLookupCaseInsensitive(t *testing.T) {
+	if _, ok := Lookup("HikVision"); !ok {
+		t.Fatalf("expected case-insensitive lookup")
+	}
+	if _, ok := Lookup("nope"); ok {
+		t.Fatalf("expected unknown vendor to fail")
+	}
+}
@@ -3,14 +3,27 @@ package rtsp
 
 import (
 	"fmt"
-	"net"
 	"net/url"
+	"strconv"
 	"strings"
 
 	"github.com/steipete/camsnap/internal/config"
+	"github.com/steipete/camsnap/internal/hostport"
+	"github.com/steipete/camsnap/internal/presets"
 )
 
-const defaultPort = 554
+const (
+	defaultPort      = 554
+	defaultRTSPSPort = 322
+)
+
+// DefaultPort is the port an RTSP URL without one connects to.
+func DefaultPort(protocol string) int {
+	if strings.EqualFold(protocol, "rtsps") {
+		return defaultRTSPSPort
+	}
+	return defaultPort
+}
 
 // BuildURL returns an RTSP URL for a camera, including auth if available.
 func BuildURL(cam config.Camera) (string, error) {
@@ -19,11 +32,21 @@ func BuildURL(cam config.Camera) (string, error) {
 		return "", fmt.Errorf("host is required")
 	}
 
-	if !strings.Contains(host, ":") && cam.Port != 0 {
-		host = net.JoinHostPort(host, fmt.Sprintf("%d", cam.Port))
-	} else if !strings.Contains(host, ":") {
-		host = net.JoinHostPort(host, fmt.Sprintf("%d", defaultPort))
+	var preset presets.Preset
+	if cam.Vendor != "" {
+		p, ok := presets.Lookup(cam.Vendor)
+		if !ok {
+			return "", fmt.Errorf("unknown vendor %q (use %s)", cam.Vendor, strings.Join(presets.Names(), "|"))
+		}
+		preset = p
+	}
+
+	// no port leaves the scheme's default (554, or 322 for rtsps) implicit, as in the URL it came from
+	port := cam.Port
+	if port == 0 {
+		port = preset.Port
 	}
+	host = hostport.URLHost(host, port)
 
 	userInfo := ""
 	if cam.Username != "" {
@@ -51,6 +74,9 @@ func BuildURL(cam config.Camera) (string, error) {
 	}
 
 	path := cam.Path
+	if path == "" && cam.Vendor != "" {
+		path = preset.StreamPath(cam.Channel, cam.Substream)
+	}
 	if path == "" {
 		// Default to /stream1 as the main Tapo stream.
 		path = "/stream1"
@@ -59,5 +85,56 @@ func BuildURL(cam config.Camera) (string, error) {
 		path = "/" + path
 	}
 
+	if cam.Query != "" {
+		sep := "?"
+		if strings.Contains(path, "?") {
+			sep = "&"
+		}
+		path += sep + cam.Query
+	}
+
 	return fmt.Sprintf("%s://%s%s", proto, authority, path), nil
 }
+
+// ParseURL splits a full RTSP URL (as used in VLC) into camera fields.
+// Credentials are URL-decoded; path and query are kept in their escaped form and a missing port
+// stays 0, so BuildURL reproduces the URL exactly.
+func ParseURL(raw string) (config.Camera, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return config.Camera{}, fmt.Errorf("parse url: %w", err)
+	}
+	proto := strings.ToLower(u.Scheme)
+	switch proto {
+	case "rtsp", "rtsps":
+	case "":
+		return config.Camera{}, fmt.Errorf("url %q has no scheme (expected rtsp:// or rtsps://)", raw)
+	default:
+		return config.Camera{}, fmt.Errorf("unsupported protocol %q", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return config.Camera{}, fmt.Errorf("url %q has no host", raw)
+	}
+	if u.Fragment != "" {
+		return config.Camera{}, fmt.Errorf("url %q has a fragment; RTSP URLs do not use #", raw)
+	}
+
+	cam := config.Camera{
+		Host:     u.Hostname(),
+		Protocol: proto,
+		Path:     u.EscapedPath(),
+		Query:    u.RawQuery,
+	}
+	if p := u.Port(); p != "" {
+		port, err := strconv.Atoi(p)
+		if err != nil || port <= 0 || port > 65535 {
+			return config.Camera{}, fmt.Errorf("invalid port %q", p)
+		}
+		cam.Port = port
+	}
+	if u.User != nil {
+		cam.Username = u.User.Username()
+		cam.Password, _ = u.User.Password()
+	}
+	return cam, nil
+}
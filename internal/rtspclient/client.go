@@ -5,49 +5,64 @@ import (
 	"bytes"
 	"context"
 	"fmt"
-	"os/exec"
+	"io"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/bluenviron/gortsplib/v4"
 	"github.com/bluenviron/gortsplib/v4/pkg/base"
 	"github.com/bluenviron/gortsplib/v4/pkg/description"
 	"github.com/bluenviron/gortsplib/v4/pkg/format"
-	"github.com/bluenviron/gortsplib/v4/pkg/format/rtph264"
 	"github.com/bluenviron/mediacommon/v2/pkg/codecs/h264"
 	"github.com/pion/rtp"
+	"github.com/steipete/camsnap/internal/camerr"
+	iexec "github.com/steipete/camsnap/internal/exec"
 )
 
-// GrabFrameViaGort connects with gortsplib, reads until a random-access (IDR) frame, then pipes it to ffmpeg to save a JPEG.
-func GrabFrameViaGort(ctx context.Context, url, transport, outPath string, timeout time.Duration) error {
+// Supported video codecs, in the order they are preferred when no codec is requested.
+const (
+	CodecH264  = "h264"
+	CodecH265  = "h265"
+	CodecMJPEG = "mjpeg"
+)
+
+// ParseCodec normalizes a user-supplied codec name. Empty and "auto" map to "".
+func ParseCodec(v string) (string, bool) {
+	switch strings.ToLower(v) {
+	case "", "auto":
+		return "", true
+	case "h264", "avc":
+		return CodecH264, true
+	case "h265", "hevc":
+		return CodecH265, true
+	case "mjpeg", "jpeg":
+		return CodecMJPEG, true
+	default:
+		return "", false
+	}
+}
+
+// GrabFrameViaGort connects with gortsplib, reads until a random-access frame, then writes it as an image.
+// H264/H265 access units are piped to ffmpeg, run through r, for decoding and encoding as img
+// (resolved with exec.Image.Resolve); MJPEG frames are written as-is unless img asks for changes.
+// codec selects the track when the camera offers several ("" picks h264, then h265, then mjpeg).
+func GrabFrameViaGort(ctx context.Context, r iexec.Runner, url, transport, codec, outPath string, img iexec.Image, timeout time.Duration) error {
 	if transport == "" {
 		transport = "udp"
 	}
+	want, ok := ParseCodec(codec)
+	if !ok {
+		return fmt.Errorf("invalid codec %q (use h264|h265|mjpeg)", codec)
+	}
 
 	u, err := base.ParseURL(url)
 	if err != nil {
 		return fmt.Errorf("parse url: %w", err)
 	}
-
-	cl := &gortsplib.Client{
-		Scheme:       u.Scheme,
-		Host:         u.Host,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-	}
-
-	switch transport {
-	case "udp":
-		t := gortsplib.TransportUDP
-		cl.Transport = &t
-	case "tcp":
-		t := gortsplib.TransportTCP
-		cl.Transport = &t
-	default:
-		return fmt.Errorf("invalid transport %q", transport)
-	}
-
-	if err := cl.Start2(); err != nil {
-		return fmt.Errorf("start: %w", err)
+	cl, err := startClient(u, transport, false)
+	if err != nil {
+		return err
 	}
 	defer cl.Close()
 
@@ -56,12 +71,12 @@ func GrabFrameViaGort(ctx context.Context, url, transport, outPath string, timeo
 
 	desc, _, err := cl.Describe(u)
 	if err != nil {
-		return fmt.Errorf("describe: %w", err)
+		return classify(fmt.Errorf("describe: %w", err))
 	}
 
-	medi, fmtH264 := findH264(desc.Medias)
-	if medi == nil || fmtH264 == nil {
-		return fmt.Errorf("no H264 track found")
+	medi, forma, err := selectVideoTrack(desc.Medias, want)
+	if err != nil {
+		return err
 	}
 
 	// ensure auth propagated to setup/play
@@ -70,45 +85,31 @@ func GrabFrameViaGort(ctx context.Context, url, transport, outPath string, timeo
 	}
 
 	if _, err := cl.Setup(desc.BaseURL, medi, 0, 0); err != nil {
-		return fmt.Errorf("setup video: %w", err)
+		return classify(fmt.Errorf("setup video: %w", err))
 	}
 
-	dec, err := fmtH264.CreateDecoder()
+	frames, err := newFrameCollector(forma)
 	if err != nil {
-		return fmt.Errorf("decoder: %w", err)
+		return err
 	}
 
-	var sample bytes.Buffer
 	done := make(chan struct{})
 	errCh := make(chan error, 1)
 
-	cl.OnPacketRTP(medi, fmtH264, func(pkt *rtp.Packet) {
-		nalus, err := dec.Decode(pkt)
-		if err != nil {
-			if err != rtph264.ErrMorePacketsNeeded {
-				// ignore non-fatal decode errors
-				return
-			}
+	cl.OnPacketRTP(medi, forma, func(pkt *rtp.Packet) {
+		select {
+		case <-done:
 			return
+		default:
 		}
-		if len(nalus) == 0 {
-			return
-		}
-		for _, n := range nalus {
-			sample.Write([]byte{0x00, 0x00, 0x00, 0x01})
-			sample.Write(n)
-		}
-		if h264.IsRandomAccess(nalus) {
-			select {
-			case <-done:
-			default:
-				close(done)
-			}
+		// decode errors are non-fatal: cameras routinely start mid-fragment
+		if frames.decode(pkt) {
+			close(done)
 		}
 	})
 
 	if _, err := cl.Play(nil); err != nil {
-		return fmt.Errorf("play: %w", err)
+		return classify(fmt.Errorf("play: %w", err))
 	}
 
 	go func() {
@@ -120,27 +121,190 @@ func GrabFrameViaGort(ctx context.Context, url, transport, outPath string, timeo
 	select {
 	case <-done:
 	case err := <-errCh:
-		return fmt.Errorf("rtsp client: %w", err)
+		return classify(fmt.Errorf("rtsp client: %w", err))
 	case <-ctxTimeout.Done():
-		return fmt.Errorf("timeout waiting for frame")
-	}
-
-	// feed collected H264 to ffmpeg to produce jpeg
-	cmd := exec.CommandContext(ctx, "ffmpeg",
-		"-y",
-		"-f", "h264",
-		"-i", "pipe:0",
-		"-frames:v", "1",
-		outPath,
-	)
-	cmd.Stdin = bytes.NewReader(sample.Bytes())
-	out, err := cmd.CombinedOutput()
+		return fmt.Errorf("waiting for frame: %w", camerr.ErrTimeout)
+	}
+
+	return frames.write(ctx, r, outPath, img)
+}
+
+// startClient connects a gortsplib client for u over transport (tcp|udp).
+func startClient(u *base.URL, transport string, backChannels bool) (*gortsplib.Client, error) {
+	cl := &gortsplib.Client{
+		Scheme:              u.Scheme,
+		Host:                u.Host,
+		ReadTimeout:         15 * time.Second,
+		WriteTimeout:        15 * time.Second,
+		RequestBackChannels: backChannels,
+	}
+
+	switch transport {
+	case "udp":
+		t := gortsplib.TransportUDP
+		cl.Transport = &t
+	case "tcp":
+		t := gortsplib.TransportTCP
+		cl.Transport = &t
+	default:
+		return nil, fmt.Errorf("invalid transport %q", transport)
+	}
+
+	if err := cl.Start2(); err != nil {
+		return nil, classify(fmt.Errorf("start: %w", err))
+	}
+	return cl, nil
+}
+
+// frameCollector buffers RTP payloads until a self-contained frame is available.
+// decode reports true once sample holds a complete frame.
+type frameCollector struct {
+	codec  string
+	decode func(*rtp.Packet) bool
+	sample bytes.Buffer
+}
+
+func newFrameCollector(forma format.Format) (*frameCollector, error) {
+	fc := &frameCollector{}
+	switch f := forma.(type) {
+	case *format.H264:
+		dec, err := f.CreateDecoder()
+		if err != nil {
+			return nil, fmt.Errorf("decoder: %w", err)
+		}
+		fc.codec = CodecH264
+		fc.decode = func(pkt *rtp.Packet) bool {
+			au, err := dec.Decode(pkt)
+			if err != nil || len(au) == 0 || !h264.IsRandomAccess(au) {
+				return false
+			}
+			sps, pps := f.SafeParams()
+			writeAnnexB(&fc.sample, [][]byte{sps, pps})
+			writeAnnexB(&fc.sample, au)
+			return true
+		}
+	case *format.H265:
+		dec, err := f.CreateDecoder()
+		if err != nil {
+			return nil, fmt.Errorf("decoder: %w", err)
+		}
+		fc.codec = CodecH265
+		fc.decode = func(pkt *rtp.Packet) bool {
+			au, err := dec.Decode(pkt)
+			if err != nil || len(au) == 0 || !isH265IRAP(au) {
+				return false
+			}
+			vps, sps, pps := f.SafeParams()
+			writeAnnexB(&fc.sample, [][]byte{vps, sps, pps})
+			writeAnnexB(&fc.sample, au)
+			return true
+		}
+	case *format.MJPEG:
+		dec, err := f.CreateDecoder()
+		if err != nil {
+			return nil, fmt.Errorf("decoder: %w", err)
+		}
+		fc.codec = CodecMJPEG
+		fc.decode = func(pkt *rtp.Packet) bool {
+			img, err := dec.Decode(pkt)
+			if err != nil || len(img) == 0 {
+				return false
+			}
+			fc.sample.Write(img)
+			return true
+		}
+	default:
+		return nil, fmt.Errorf("video format %s: %w", forma.Codec(), camerr.ErrUnsupportedCodec)
+	}
+	return fc, nil
+}
+
+// write stores the buffered frame at outPath as img: a JPEG that needs no changes is written as
+// it is, everything else goes through ffmpeg.
+func (fc *frameCollector) write(ctx context.Context, r iexec.Runner, outPath string, img iexec.Image) error {
+	if fc.codec == CodecMJPEG && img.Passthrough() {
+		if err := os.WriteFile(outPath, fc.sample.Bytes(), 0o644); err != nil {
+			return fmt.Errorf("write frame: %w", err)
+		}
+		return nil
+	}
+
+	demuxer := map[string]string{CodecH264: "h264", CodecH265: "hevc", CodecMJPEG: "mjpeg"}[fc.codec]
+	args := append([]string{"-y", "-hide_banner", "-loglevel", "error", "-f", demuxer, "-i", "pipe:0"}, img.Args()...)
+	args = append(args, outPath)
+	p, err := r.Start(ctx, bytes.NewReader(fc.sample.Bytes()), nil, args...)
 	if err != nil {
-		return fmt.Errorf("ffmpeg write frame: %w\n%s", err, string(out))
+		return err
+	}
+	log, readErr := io.ReadAll(p.Stderr())
+	if err := p.Wait(); err != nil {
+		return iexec.Failure(ctx, args, err, string(log))
+	}
+	if readErr != nil {
+		return fmt.Errorf("read ffmpeg logs: %w", readErr)
 	}
 	return nil
 }
 
+func writeAnnexB(buf *bytes.Buffer, nalus [][]byte) {
+	for _, n := range nalus {
+		if len(n) == 0 {
+			continue
+		}
+		buf.Write([]byte{0x00, 0x00, 0x00, 0x01})
+		buf.Write(n)
+	}
+}
+
+// isH265IRAP reports whether an access unit contains an intra random access point
+// (BLA, IDR or CRA; NAL unit types 16-23).
+func isH265IRAP(au [][]byte) bool {
+	for _, n := range au {
+		if len(n) == 0 {
+			continue
+		}
+		typ := (n[0] >> 1) & 0x3f
+		if typ >= 16 && typ <= 23 {
+			return true
+		}
+	}
+	return false
+}
+
+// selectVideoTrack picks the media/format for the requested codec, or the first supported one.
+func selectVideoTrack(medias []*description.Media, codec string) (*description.Media, format.Format, error) {
+	if codec == "" || codec == CodecH264 {
+		if m, f := findH264(medias); m != nil {
+			return m, f, nil
+		}
+	}
+	if codec == "" || codec == CodecH265 {
+		if m, f := findH265(medias); m != nil {
+			return m, f, nil
+		}
+	}
+	if codec == "" || codec == CodecMJPEG {
+		if m, f := findMJPEG(medias); m != nil {
+			return m, f, nil
+		}
+	}
+
+	var offered []string
+	for _, m := range medias {
+		for _, f := range m.Formats {
+			offered = append(offered, f.Codec())
+		}
+	}
+	want := "H264/H265/MJPEG"
+	if codec != "" {
+		want = strings.ToUpper(codec)
+	}
+	if len(offered) == 0 {
+		return nil, nil, fmt.Errorf("no %s track found: %w", want, camerr.ErrUnsupportedCodec)
+	}
+	return nil, nil, fmt.Errorf("no %s track found (camera offers %s): %w", want, strings.Join(offered, ", "), camerr.ErrUnsupportedCodec)
+}
+
 func findH264(medias []*description.Media) (*description.Media, *format.H264) {
 	for _, m := range medias {
 		for _, f := range m.Formats {
@@ -151,3 +315,25 @@ func findH264(medias []*description.Media) (*description.Media, *format.H264) {
 	}
 	return nil, nil
 }
+
+func findH265(medias []*description.Media) (*description.Media, *format.H265) {
+	for _, m := range medias {
+		for _, f := range m.Formats {
+			if h, ok := f.(*format.H265); ok {
+				return m, h
+			}
+		}
+	}
+	return nil, nil
+}
+
+func findMJPEG(medias []*description.Media) (*description.Media, *format.MJPEG) {
+	for _, m := range medias {
+		for _, f := range m.Formats {
+			if j, ok := f.(*format.MJPEG); ok {
+				return m, j
+			}
+		}
+	}
+	return nil, nil
+}
@@ -2,11 +2,18 @@ package rtspclient
 
 import (
 	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/bluenviron/gortsplib/v4/pkg/description"
 	"github.com/bluenviron/gortsplib/v4/pkg/format"
+	"github.com/steipete/camsnap/internal/camerr"
+	iexec "github.com/steipete/camsnap/internal/exec"
+	"github.com/steipete/camsnap/internal/exec/exectest"
 )
 
 func TestFindH264(t *testing.T) {
@@ -39,7 +46,7 @@ func TestGrabFrameViaGortBadURL(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer cancel()
 
-	err := GrabFrameViaGort(ctx, "rtsp://127.0.0.1:0/stream1", "udp", t.TempDir()+"/out.jpg", 500*time.Millisecond)
+	err := GrabFrameViaGort(ctx, iexec.FFmpeg{}, "rtsp://127.0.0.1:0/stream1", "udp", "", t.TempDir()+"/out.jpg", iexec.Image{}, 500*time.Millisecond)
 	if err == nil {
 		t.Fatalf("expected error on invalid url/connection")
 	}
@@ -49,8 +56,113 @@ func TestGrabFrameViaGortInvalidTransport(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer cancel()
 
-	err := GrabFrameViaGort(ctx, "rtsp://127.0.0.1:0/stream1", "invalid", t.TempDir()+"/out.jpg", 500*time.Millisecond)
+	err := GrabFrameViaGort(ctx, iexec.FFmpeg{}, "rtsp://127.0.0.1:0/stream1", "invalid", "", t.TempDir()+"/out.jpg", iexec.Image{}, 500*time.Millisecond)
 	if err == nil {
 		t.Fatalf("expected error on invalid transport")
 	}
 }
+
+func TestGrabFrameViaGortInvalidCodec(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	err := GrabFrameViaGort(ctx, iexec.FFmpeg{}, "rtsp://127.0.0.1:0/stream1", "udp", "vp9", t.TempDir()+"/out.jpg", iexec.Image{}, 500*time.Millisecond)
+	if err == nil {
+		t.Fatalf("expected error on invalid codec")
+	}
+}
+
+func TestSelectVideoTrack(t *testing.T) {
+	medias := []*description.Media{
+		{
+			Type:    description.MediaTypeVideo,
+			Formats: []format.Format{&format.MJPEG{}},
+		},
+		{
+			Type:    description.MediaTypeVideo,
+			Formats: []format.Format{&format.H265{PayloadTyp: 97}},
+		},
+	}
+
+	_, f, err := selectVideoTrack(medias, "")
+	if err != nil {
+		t.Fatalf("auto select: %v", err)
+	}
+	if _, ok := f.(*format.H265); !ok {
+		t.Fatalf("expected h265 preferred over mjpeg, got %T", f)
+	}
+
+	_, f, err = selectVideoTrack(medias, CodecMJPEG)
+	if err != nil {
+		t.Fatalf("mjpeg select: %v", err)
+	}
+	if _, ok := f.(*format.MJPEG); !ok {
+		t.Fatalf("expected mjpeg, got %T", f)
+	}
+
+	_, _, err = selectVideoTrack(medias, CodecH264)
+	if err == nil || !strings.Contains(err.Error(), "camera offers") {
+		t.Fatalf("expected missing h264 error listing offered codecs, got %v", err)
+	}
+}
+
+func TestIsH265IRAP(t *testing.T) {
+	// NAL header: type in bits 1-6 of the first byte.
+	idr := []byte{19 << 1, 0x01}
+	cra := []byte{21 << 1, 0x01}
+	trail := []byte{1 << 1, 0x01}
+	if !isH265IRAP([][]byte{idr}) || !isH265IRAP([][]byte{trail, cra}) {
+		t.Fatalf("expected IDR/CRA to be IRAP")
+	}
+	if isH265IRAP([][]byte{trail}) {
+		t.Fatalf("expected TRAIL_R not to be IRAP")
+	}
+}
+
+func TestParseCodec(t *testing.T) {
+	cases := []struct {
+		in     string
+		ok     bool
+		expect string
+	}{
+		{"", true, ""},
+		{"auto", true, ""},
+		{"HEVC", true, CodecH265},
+		{"h264", true, CodecH264},
+		{"mjpeg", true, CodecMJPEG},
+		{"vp9", false, ""},
+	}
+	for _, c := range cases {
+		got, ok := ParseCodec(c.in)
+		if ok != c.ok || got != c.expect {
+			t.Fatalf("ParseCodec(%s) got (%s,%v) want (%s,%v)", c.in, got, ok, c.expect, c.ok)
+		}
+	}
+}
+
+func TestFrameCollectorWriteUsesRunner(t *testing.T) {
+	fake := exectest.NewFake()
+	fake.SetReplies(exectest.Reply{Output: []byte("JPEG")})
+	fc := &frameCollector{codec: CodecH264}
+	fc.sample.Write([]byte{0, 0, 0, 1, 0x65})
+	out := filepath.Join(t.TempDir(), "out.jpg")
+	img, err := (iexec.Image{}).Resolve(out, iexec.Caps{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fc.write(context.Background(), fake, out, img); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	calls := fake.Calls()
+	if len(calls) != 1 || !strings.Contains(strings.Join(calls[0], " "), "-f h264 -i pipe:0 -frames:v 1 -c:v mjpeg") {
+		t.Fatalf("args: %v", calls)
+	}
+	if data, _ := os.ReadFile(out); string(data) != "JPEG" {
+		t.Fatalf("output: %q", data)
+	}
+
+	fake.SetReplies(exectest.Reply{Stderr: "Decoder (codec h264) not found for input stream #0:0", Exit: 1})
+	if err := fc.write(context.Background(), fake, out, img); !errors.Is(err, camerr.ErrUnsupportedCodec) {
+		t.Fatalf("failure should be classified: %v", err)
+	}
+}
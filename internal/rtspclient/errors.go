@@ -0,0 +1,18 @@
+package rtspclient
+
+import (
+	"errors"
+
+	"github.com/bluenviron/gortsplib/v4/pkg/liberrors"
+	"github.com/steipete/camsnap/internal/camerr"
+)
+
+// classify tags a gortsplib error with its camerr kind: RTSP status codes first, then dial and
+// I/O failures.
+func classify(err error) error {
+	var status liberrors.ErrClientBadStatusCode
+	if errors.As(err, &status) {
+		return camerr.Wrap(camerr.FromStatus(int(status.Code)), err)
+	}
+	return camerr.Wrap(camerr.FromNet(err), err)
+}
@@ -0,0 +1,390 @@
+package rtspclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bluenviron/gortsplib/v4/pkg/base"
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
+	"github.com/bluenviron/gortsplib/v4/pkg/format"
+	"github.com/bluenviron/gortsplib/v4/pkg/liberrors"
+	"github.com/bluenviron/mediacommon/v2/pkg/codecs/h264"
+	"github.com/bluenviron/mediacommon/v2/pkg/codecs/h265"
+	"github.com/pion/rtp"
+	"github.com/steipete/camsnap/internal/camerr"
+)
+
+// ProbeReport describes a stream: what DESCRIBE announced and, when sampled, how the video track behaves.
+type ProbeReport struct {
+	SDP    string
+	Medias []MediaInfo
+	Video  *VideoStats // nil when no sampling window was requested
+}
+
+// MediaInfo is one m= section of the SDP.
+type MediaInfo struct {
+	Type        string // video|audio|application
+	Control     string
+	BackChannel bool // ONVIF audio backchannel (camera speaker)
+	Formats     []FormatInfo
+}
+
+// FormatInfo is one payload format with the parameters that matter for decoding.
+type FormatInfo struct {
+	PayloadType uint8
+	Codec       string
+	ClockRate   int
+	RTPMap      string
+	FMTP        map[string]string
+	Width       int    // from the SPS, video only
+	Height      int    // from the SPS, video only
+	Profile     string // e.g. High, Main 10
+	Level       string // e.g. 4.1
+	SampleRate  int    // audio only
+	Channels    int    // audio only
+}
+
+// VideoStats are measured on the selected video track over the sampling window.
+type VideoStats struct {
+	Codec          string
+	Window         time.Duration
+	TimeToKeyframe time.Duration // from PLAY to the first random-access frame; 0 if none arrived
+	Frames         int
+	Keyframes      int
+	GOPFrames      float64 // mean frames per GOP; 0 if fewer than two keyframes arrived
+	GOP            time.Duration
+	FPS            float64 // from RTP timestamps, i.e. the camera's clock
+	BitrateKbps    float64 // RTP payload bytes over wall time
+	Width          int     // from an in-band SPS when the SDP carried none
+	Height         int
+}
+
+// Probe runs DESCRIBE (asking for ONVIF backchannels) and, if window > 0, plays the video track for
+// window to measure time-to-first-keyframe, GOP length, frame rate and bitrate.
+func Probe(ctx context.Context, url, transport, codec string, window time.Duration) (ProbeReport, error) {
+	if transport == "" {
+		transport = "udp"
+	}
+	want, ok := ParseCodec(codec)
+	if !ok {
+		return ProbeReport{}, fmt.Errorf("invalid codec %q (use h264|h265|mjpeg)", codec)
+	}
+	u, err := base.ParseURL(url)
+	if err != nil {
+		return ProbeReport{}, fmt.Errorf("parse url: %w", err)
+	}
+	cl, err := startClient(u, transport, true)
+	if err != nil {
+		return ProbeReport{}, err
+	}
+	defer cl.Close()
+
+	desc, res, err := cl.Describe(u)
+	var bad liberrors.ErrClientBadStatusCode
+	if errors.As(err, &bad) && (bad.Code == base.StatusOptionNotSupported || bad.Code == base.StatusBadRequest) {
+		// cameras without backchannel support may reject the Require header
+		cl.RequestBackChannels = false
+		desc, res, err = cl.Describe(u)
+	}
+	if err != nil {
+		return ProbeReport{}, classify(fmt.Errorf("describe: %w", err))
+	}
+
+	report := ProbeReport{SDP: string(res.Body)}
+	for _, m := range desc.Medias {
+		mi := MediaInfo{Type: string(m.Type), Control: m.Control, BackChannel: m.IsBackChannel}
+		for _, f := range m.Formats {
+			mi.Formats = append(mi.Formats, describeFormat(m.Type, f))
+		}
+		report.Medias = append(report.Medias, mi)
+	}
+	if window <= 0 {
+		return report, nil
+	}
+
+	medi, forma, err := selectVideoTrack(desc.Medias, want)
+	if err != nil {
+		return report, err
+	}
+	if u.User != nil && desc.BaseURL != nil {
+		desc.BaseURL.User = u.User
+	}
+	if _, err := cl.Setup(desc.BaseURL, medi, 0, 0); err != nil {
+		return report, classify(fmt.Errorf("setup video: %w", err))
+	}
+	accessUnits, err := newAUDecoder(forma)
+	if err != nil {
+		return report, err
+	}
+
+	var mu sync.Mutex
+	stats := newStatsCollector(forma.Codec(), forma.ClockRate())
+	cl.OnPacketRTP(medi, forma, func(pkt *rtp.Packet) {
+		mu.Lock()
+		defer mu.Unlock()
+		stats.packet(len(pkt.Payload), time.Now())
+		if au, key, ok := accessUnits(pkt); ok {
+			stats.accessUnit(au, key, pkt.Timestamp, time.Now())
+		}
+	})
+
+	stats.play = time.Now()
+	if _, err := cl.Play(nil); err != nil {
+		return report, classify(fmt.Errorf("play: %w", err))
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- cl.Wait()
+	}()
+
+	timer := time.NewTimer(window)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case err := <-errCh:
+		return report, classify(fmt.Errorf("rtsp client: %w", err))
+	case <-ctx.Done():
+		// keep what was measured so far
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	v := stats.result(time.Since(stats.play))
+	report.Video = &v
+	return report, nil
+}
+
+// auDecoder turns RTP packets into access units, reporting whether each is a random-access frame.
+type auDecoder func(*rtp.Packet) ([][]byte, bool, bool)
+
+func newAUDecoder(forma format.Format) (auDecoder, error) {
+	switch f := forma.(type) {
+	case *format.H264:
+		dec, err := f.CreateDecoder()
+		if err != nil {
+			return nil, fmt.Errorf("decoder: %w", err)
+		}
+		return func(pkt *rtp.Packet) ([][]byte, bool, bool) {
+			au, err := dec.Decode(pkt)
+			if err != nil || len(au) == 0 {
+				return nil, false, false
+			}
+			return au, h264.IsRandomAccess(au), true
+		}, nil
+	case *format.H265:
+		dec, err := f.CreateDecoder()
+		if err != nil {
+			return nil, fmt.Errorf("decoder: %w", err)
+		}
+		return func(pkt *rtp.Packet) ([][]byte, bool, bool) {
+			au, err := dec.Decode(pkt)
+			if err != nil || len(au) == 0 {
+				return nil, false, false
+			}
+			return au, isH265IRAP(au), true
+		}, nil
+	case *format.MJPEG:
+		dec, err := f.CreateDecoder()
+		if err != nil {
+			return nil, fmt.Errorf("decoder: %w", err)
+		}
+		return func(pkt *rtp.Packet) ([][]byte, bool, bool) {
+			img, err := dec.Decode(pkt)
+			if err != nil || len(img) == 0 {
+				return nil, false, false
+			}
+			// every JPEG stands alone
+			return nil, true, true
+		}, nil
+	default:
+		return nil, fmt.Errorf("video format %s: %w", forma.Codec(), camerr.ErrUnsupportedCodec)
+	}
+}
+
+// statsCollector accumulates VideoStats; it is fed from the RTP callback.
+type statsCollector struct {
+	codec     string
+	clockRate int
+	play      time.Time
+
+	firstKey    time.Time
+	frames      int
+	keyframes   int
+	sinceKey    int // frames since the last keyframe
+	gopFrames   int // frames in completed GOPs
+	gops        int
+	firstTS     uint32
+	lastTS      uint32
+	tsFrames    int
+	bytes       int
+	firstPacket time.Time
+	lastPacket  time.Time
+	width       int
+	height      int
+}
+
+func newStatsCollector(codec string, clockRate int) *statsCollector {
+	return &statsCollector{codec: codec, clockRate: clockRate}
+}
+
+func (s *statsCollector) packet(n int, now time.Time) {
+	if s.firstPacket.IsZero() {
+		s.firstPacket = now
+	}
+	s.lastPacket = now
+	s.bytes += n
+}
+
+func (s *statsCollector) accessUnit(au [][]byte, key bool, ts uint32, now time.Time) {
+	if s.width == 0 {
+		s.width, s.height = spsSize(s.codec, au)
+	}
+	if key {
+		if s.keyframes == 0 {
+			s.firstKey = now
+		} else {
+			s.gopFrames += s.sinceKey
+			s.gops++
+		}
+		s.keyframes++
+		s.sinceKey = 0
+	}
+	s.sinceKey++
+	s.frames++
+	if s.tsFrames == 0 {
+		s.firstTS = ts
+	}
+	if s.tsFrames == 0 || ts != s.lastTS {
+		s.tsFrames++
+	}
+	s.lastTS = ts
+}
+
+func (s *statsCollector) result(window time.Duration) VideoStats {
+	v := VideoStats{
+		Codec:     s.codec,
+		Window:    window,
+		Frames:    s.frames,
+		Keyframes: s.keyframes,
+		Width:     s.width,
+		Height:    s.height,
+	}
+	if !s.firstKey.IsZero() {
+		v.TimeToKeyframe = s.firstKey.Sub(s.play)
+	}
+	// unsigned subtraction handles timestamp wrap-around
+	if span := s.lastTS - s.firstTS; span > 0 && s.clockRate > 0 && s.tsFrames > 1 {
+		v.FPS = float64(s.tsFrames-1) * float64(s.clockRate) / float64(span)
+	}
+	if s.gops > 0 {
+		v.GOPFrames = float64(s.gopFrames) / float64(s.gops)
+		if v.FPS > 0 {
+			v.GOP = time.Duration(v.GOPFrames / v.FPS * float64(time.Second))
+		}
+	}
+	if d := s.lastPacket.Sub(s.firstPacket); d > 0 {
+		v.BitrateKbps = float64(s.bytes) * 8 / d.Seconds() / 1000
+	}
+	return v
+}
+
+// describeFormat extracts codec parameters from the SDP; video sizes and profiles come from the SPS.
+func describeFormat(typ description.MediaType, f format.Format) FormatInfo {
+	fi := FormatInfo{
+		PayloadType: f.PayloadType(),
+		Codec:       f.Codec(),
+		ClockRate:   f.ClockRate(),
+		RTPMap:      f.RTPMap(),
+		FMTP:        f.FMTP(),
+	}
+	switch f := f.(type) {
+	case *format.H264:
+		sps, _ := f.SafeParams()
+		var s h264.SPS
+		if len(sps) > 0 && s.Unmarshal(sps) == nil {
+			fi.Width, fi.Height = s.Width(), s.Height()
+			fi.Profile = h264ProfileName(s.ProfileIdc)
+			fi.Level = strconv.FormatFloat(float64(s.LevelIdc)/10, 'f', 1, 64)
+		}
+	case *format.H265:
+		_, sps, _ := f.SafeParams()
+		var s h265.SPS
+		if len(sps) > 0 && s.Unmarshal(sps) == nil {
+			fi.Width, fi.Height = s.Width(), s.Height()
+			fi.Profile = h265ProfileName(s.ProfileTierLevel.GeneralProfileIdc)
+			fi.Level = strconv.FormatFloat(float64(s.ProfileTierLevel.GeneralLevelIdc)/30, 'f', 1, 64)
+		}
+	}
+	// audio rtpmap is encoding/rate[/channels]
+	if parts := strings.Split(fi.RTPMap, "/"); len(parts) >= 2 && typ == description.MediaTypeAudio {
+		fi.SampleRate, _ = strconv.Atoi(parts[1])
+		fi.Channels = 1
+		if len(parts) == 3 {
+			fi.Channels, _ = strconv.Atoi(parts[2])
+		}
+	}
+	return fi
+}
+
+// spsSize reads the picture size from an in-band SPS, if the access unit carries one.
+func spsSize(codec string, au [][]byte) (int, int) {
+	for _, n := range au {
+		if len(n) == 0 {
+			continue
+		}
+		switch codec {
+		case "H264":
+			var s h264.SPS
+			if h264.NALUType(n[0]&0x1f) == h264.NALUTypeSPS && s.Unmarshal(n) == nil {
+				return s.Width(), s.Height()
+			}
+		case "H265":
+			var s h265.SPS
+			if h265.NALUType((n[0]>>1)&0x3f) == h265.NALUType_SPS_NUT && s.Unmarshal(n) == nil {
+				return s.Width(), s.Height()
+			}
+		}
+	}
+	return 0, 0
+}
+
+func h264ProfileName(idc uint8) string {
+	switch idc {
+	case 66:
+		return "Baseline"
+	case 77:
+		return "Main"
+	case 88:
+		return "Extended"
+	case 100:
+		return "High"
+	case 110:
+		return "High 10"
+	case 122:
+		return "High 4:2:2"
+	case 244:
+		return "High 4:4:4"
+	default:
+		return "profile " + strconv.Itoa(int(idc))
+	}
+}
+
+func h265ProfileName(idc uint8) string {
+	switch idc {
+	case 1:
+		return "Main"
+	case 2:
+		return "Main 10"
+	case 3:
+		return "Main Still Picture"
+	case 4:
+		return "Range Extensions"
+	default:
+		return "profile " + strconv.Itoa(int(idc))
+	}
+}
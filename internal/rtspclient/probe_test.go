@@ -0,0 +1,86 @@
+package rtspclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/bluenviron/gortsplib/v4/pkg/base"
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
+	"github.com/bluenviron/gortsplib/v4/pkg/format"
+	"github.com/bluenviron/gortsplib/v4/pkg/liberrors"
+	"github.com/steipete/camsnap/internal/camerr"
+)
+
+// 1280x720 High@3.1
+var testSPS = []byte{
+	0x67, 0x64, 0x00, 0x1f, 0xac, 0xd9, 0x40, 0x50,
+	0x05, 0xbb, 0x01, 0x6c, 0x80, 0x00, 0x00, 0x03,
+	0x00, 0x80, 0x00, 0x00, 0x1e, 0x07, 0x8c, 0x18,
+	0xcb,
+}
+
+func TestDescribeFormat(t *testing.T) {
+	v := describeFormat(description.MediaTypeVideo, &format.H264{PayloadTyp: 96, SPS: testSPS, PPS: []byte{0x68, 0xeb, 0xe3, 0xcb}, PacketizationMode: 1})
+	if v.Codec != "H264" || v.Width != 1280 || v.Height != 720 || v.Profile != "High" || v.Level != "3.1" || v.ClockRate != 90000 {
+		t.Fatalf("unexpected video format: %+v", v)
+	}
+
+	a := describeFormat(description.MediaTypeAudio, &format.G711{PayloadTyp: 8, SampleRate: 8000, ChannelCount: 1})
+	if a.Codec != "G711" || a.SampleRate != 8000 || a.Channels != 1 || a.RTPMap != "PCMA/8000" {
+		t.Fatalf("unexpected audio format: %+v", a)
+	}
+}
+
+func TestStatsCollector(t *testing.T) {
+	s := newStatsCollector("H264", 90000)
+	start := time.Unix(1000, 0)
+	s.play = start
+	// 25 fps, a keyframe every 10 frames, 1000 bytes per frame; the stream starts mid-GOP
+	for i := 0; i < 33; i++ {
+		now := start.Add(200*time.Millisecond + time.Duration(i)*40*time.Millisecond)
+		s.packet(1000, now)
+		au := [][]byte{{0x41}}
+		if i == 0 {
+			au = [][]byte{testSPS, {0x41}}
+		}
+		s.accessUnit(au, i%10 == 3, uint32(i*3600), now)
+	}
+	v := s.result(2 * time.Second)
+	if v.Frames != 33 || v.Keyframes != 3 || v.Width != 1280 || v.Height != 720 {
+		t.Fatalf("unexpected counts: %+v", v)
+	}
+	if v.TimeToKeyframe != 320*time.Millisecond {
+		t.Fatalf("time to keyframe %v", v.TimeToKeyframe)
+	}
+	if math.Abs(v.FPS-25) > 0.01 || v.GOPFrames != 10 || v.GOP != 400*time.Millisecond {
+		t.Fatalf("fps %v gop %v/%v", v.FPS, v.GOPFrames, v.GOP)
+	}
+	// 33 kB over 1.28s
+	if math.Abs(v.BitrateKbps-206.25) > 0.01 {
+		t.Fatalf("bitrate %v", v.BitrateKbps)
+	}
+}
+
+func TestProbeInvalidTransport(t *testing.T) {
+	if _, err := Probe(context.Background(), "rtsp://127.0.0.1:1/stream", "sctp", "", 0); err == nil {
+		t.Fatal("expected error for invalid transport")
+	}
+}
+
+func TestClassify(t *testing.T) {
+	err := classify(fmt.Errorf("describe: %w", liberrors.ErrClientBadStatusCode{Code: base.StatusUnauthorized, Message: "Unauthorized"}))
+	if !errors.Is(err, camerr.ErrAuth) {
+		t.Fatalf("401 not classified as auth: %v", err)
+	}
+	err = classify(fmt.Errorf("setup video: %w", liberrors.ErrClientBadStatusCode{Code: base.StatusNotEnoughBandwidth}))
+	if camerr.Class(err) != "session-limit" {
+		t.Fatalf("453 classified as %s", camerr.Class(err))
+	}
+	if _, err := Probe(context.Background(), "rtsp://127.0.0.1:1/stream", "tcp", "", 0); camerr.Class(err) != "unreachable" {
+		t.Fatalf("closed port classified as %s: %v", camerr.Class(err), err)
+	}
+}